@@ -0,0 +1,104 @@
+package gosf
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+/************************************/
+/********* AGGREGATE QUERY **********/
+/************************************/
+
+// AggregateResult is the result of an aggregate query.
+//
+// For a COUNT() query, TotalSize is the count of records matched and there
+// are no Records. Otherwise TotalSize is the number of Records, each of
+// which is a group with the group fields and the aggregates.
+type AggregateResult struct {
+	TotalSize int
+	Records   []AggregateRecord
+}
+
+// AggregateRecord is a row of an aggregate query. Aggregates without alias
+// are keyed by their positions, like expr0, expr1...; group fields are
+// keyed by their field names, Owner.Name is keyed by Name.
+type AggregateRecord map[string]interface{}
+
+// QueryAggregate runs the aggregate query op. See also OpQuery.SelectAggregate.
+func (c *Client) QueryAggregate(op *OpQuery) (result *AggregateResult, err error) {
+	if err = c.do(op); err != nil {
+		return
+	}
+	return op.result.Aggregate()
+}
+
+// Aggregate converts the result of an aggregate query to AggregateResult.
+func (r *QueryResult) Aggregate() (*AggregateResult, error) {
+	result := &AggregateResult{
+		TotalSize: r.TotalSize,
+		Records:   make([]AggregateRecord, 0, len(r.Records)),
+	}
+	for i, record := range r.Records {
+		fields, ok := record.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("aggregate record %d is %T, not an object", i, record)
+		}
+		row := make(AggregateRecord, len(fields))
+		for key, value := range fields {
+			if key != "attributes" {
+				row[key] = value
+			}
+		}
+		result.Records = append(result.Records, row)
+	}
+	return result, nil
+}
+
+// Count returns the count of a COUNT() query.
+func (r *AggregateResult) Count() int {
+	return r.TotalSize
+}
+
+// Parse parses the records into targets, which should be a pointer to a
+// slice of structs with fields tagged by the aliases or exprN keys.
+func (r *AggregateResult) Parse(targets interface{}) error {
+	byts, err := json.Marshal(r.Records)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(byts, targets)
+}
+
+// Expr returns the i-th aggregate without alias, the value of key expr<i>.
+func (r AggregateRecord) Expr(i int) interface{} {
+	return r["expr"+strconv.Itoa(i)]
+}
+
+// Float64 returns the value of key as float64, ok is false if the value is
+// null or not a number.
+func (r AggregateRecord) Float64(key string) (f float64, ok bool) {
+	switch v := r[key].(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// Int returns the value of key as int, ok is false if the value is null or
+// not a number.
+func (r AggregateRecord) Int(key string) (n int, ok bool) {
+	f, ok := r.Float64(key)
+	return int(f), ok
+}
+
+// String returns the value of key as string, ok is false if the value is
+// null or not a string.
+func (r AggregateRecord) String(key string) (s string, ok bool) {
+	s, ok = r[key].(string)
+	return
+}
@@ -1,6 +1,7 @@
 package gosf
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -27,6 +28,8 @@ func (c *Client) CreaetSobject(sobjectName string, sobject interface{}) (id stri
 }
 
 // UpdateSobject updates sobject by given sobject name, id and entity contains changes.
+// Only the fields encoded are changed, use a Patch or Nullable fields to send
+// exactly the changes and clear fields by null.
 func (c *Client) UpdateSobject(sobjectName, sobjectID string, sobject interface{}) error {
 	return c.do(&opUpdate{
 		sobjectName: sobjectName,
@@ -44,17 +47,36 @@ func (c *Client) DeleteSobject(sobjectName, sobjectID string) error {
 }
 
 // GetSobject get sobject by given sobject name and id, use target to receive the result.
+// All fields are retrieved, use GetSobjectFields to retrieve some of them.
 func (c *Client) GetSobject(sobjectName, sobjectID string, target interface{}) error {
-	op := &opGet{
+	return c.do(&opGet{
 		sobjectName: sobjectName,
 		sobjectID:   sobjectID,
-	}
-	if err := c.do(op); err != nil {
-		return err
-	}
+		target:      target,
+	})
+}
 
-	byts, _ := json.Marshal(op.target)
-	return json.Unmarshal(byts, &target)
+// GetSobjectFields is like GetSobject, only the given fields are retrieved.
+func (c *Client) GetSobjectFields(sobjectName, sobjectID string, target interface{}, fields ...string) error {
+	return c.do(&opGet{
+		sobjectName: sobjectName,
+		sobjectID:   sobjectID,
+		fields:      fields,
+		target:      target,
+	})
+}
+
+// GetSobjectByExternalID get sobject by the value of an external id field,
+// all fields are retrieved if fields is empty. If the value matches more
+// than one record, salesforce responds 300 with their URLs as the error.
+func (c *Client) GetSobjectByExternalID(sobjectName, externalField, value string, target interface{}, fields ...string) error {
+	return c.do(&opGet{
+		sobjectName:   sobjectName,
+		sobjectID:     value,
+		externalField: externalField,
+		fields:        fields,
+		target:        target,
+	})
 }
 
 // QuerySobject query sobject or sobjects by given op OpQuery.
@@ -67,6 +89,17 @@ func (c *Client) QuerySobject(op *OpQuery) (result *QueryResult, err error) {
 	return
 }
 
+// ExplainQuery returns the query plans of op without running it, to check
+// whether the filters are selective.
+func (c *Client) ExplainQuery(op *OpQuery) (result *ExplainResult, err error) {
+	explain := &opExplain{query: op}
+	if err = c.do(explain); err != nil {
+		return
+	}
+	result = explain.result
+	return
+}
+
 /************************************/
 /****** OTHER COMMON RESOURCES ******/
 /************************************/
@@ -80,7 +113,8 @@ type Version struct {
 
 // Versions shows all availabel ssalesforce rest api versions.
 func (c *Client) Versions() (versions []*Version, err error) {
-	req, err := http.NewRequest(http.MethodGet, c.requestCtx.BaseURL(), nil)
+	ctx := withOperation(context.Background(), Operation{Name: "versions"})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.newRequestCtx().BaseURL(), nil)
 	if err != nil {
 		return
 	}
@@ -94,9 +128,42 @@ func (c *Client) Versions() (versions []*Version, err error) {
 	return
 }
 
+// NegotiateAPIVersion picks the newest version salesforce supports within
+// [min, max] and makes the Client use it. A zero bound means unbounded.
+// It should be called before the Client is shared by goroutines.
+func (c *Client) NegotiateAPIVersion(min, max APIVersion) (version APIVersion, err error) {
+	versions, err := c.Versions()
+	if err != nil {
+		return
+	}
+
+	for _, v := range versions {
+		parsed, perr := ParseAPIVersion(v.Version)
+		if perr != nil {
+			continue
+		}
+		if (min > 0 && parsed < min) || (max > 0 && parsed > max) {
+			continue
+		}
+		if parsed > version {
+			version = parsed
+		}
+	}
+	if version == 0 {
+		err = fmt.Errorf("no api version available in range [%s, %s]", min, max)
+		return
+	}
+
+	c.mu.Lock()
+	c.requestCtx.version = version
+	c.mu.Unlock()
+	return
+}
+
 // Resources shows resources under current version.
 func (c *Client) Resources() (resources map[string]string, err error) {
-	req, err := http.NewRequest(http.MethodGet, c.requestCtx.VersionURL(), nil)
+	ctx := withOperation(context.Background(), Operation{Name: "resources"})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.newRequestCtx().VersionURL(), nil)
 	if err != nil {
 		return
 	}
@@ -111,8 +178,10 @@ func (c *Client) Resources() (resources map[string]string, err error) {
 }
 
 // SobjectInfo shows the basic information of given sobject name.
+// See DescribeGlobal for the typed result.
 func (c *Client) SobjectInfo() (info map[string]interface{}, err error) {
-	req, err := http.NewRequest(http.MethodGet, c.requestCtx.SobjectURL(), nil)
+	ctx := withOperation(context.Background(), Operation{Name: "sobjects"})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.newRequestCtx().SobjectURL(), nil)
 	if err != nil {
 		return
 	}
@@ -0,0 +1,695 @@
+package gosf_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sidebiequ/gosf"
+	"github.com/sidebiequ/gosf/gosftest"
+)
+
+type account struct {
+	ID       string `json:"Id,omitempty"`
+	Name     string `json:"Name,omitempty"`
+	Industry string `json:"Industry,omitempty"`
+}
+
+// missingID is a valid id no record has.
+const missingID = "001000000000999AAA"
+
+func TestSobjectCRUD(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	client := srv.Client()
+
+	id, err := client.CreaetSobject("Account", account{Name: "Acme", Industry: "Energy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got account
+	if err = client.GetSobject("Account", id, &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := (account{ID: id, Name: "Acme", Industry: "Energy"}); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	if err = client.UpdateSobject("Account", id, account{Industry: "Media"}); err != nil {
+		t.Fatal(err)
+	}
+	got = account{}
+	if err = client.GetSobjectFields("Account", id, &got, "Industry"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (account{ID: id, Industry: "Media"}); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	if err = client.DeleteSobject("Account", id); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := srv.Get("Account", id); ok {
+		t.Fatal("record is not deleted")
+	}
+}
+
+func TestSobjectErrors(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	deleted := srv.Insert("Account", gosftest.Record{"Name": "Gone"})
+	live := srv.Insert("Account", gosftest.Record{"Name": "Acme"})
+	client := srv.Client()
+	if err := client.DeleteSobject("Account", deleted); err != nil {
+		t.Fatal(err)
+	}
+
+	var target map[string]interface{}
+	tests := []struct {
+		name string
+		call func() error
+		want string
+	}{
+		{"get deleted", func() error { return client.GetSobject("Account", deleted, &target) }, "ENTITY_IS_DELETED"},
+		{"update deleted", func() error { return client.UpdateSobject("Account", deleted, account{Name: "X"}) }, "ENTITY_IS_DELETED"},
+		{"delete deleted", func() error { return client.DeleteSobject("Account", deleted) }, "ENTITY_IS_DELETED"},
+		{"get missing", func() error { return client.GetSobject("Account", missingID, &target) }, "404"},
+		{"update missing", func() error { return client.UpdateSobject("Account", missingID, account{Name: "X"}) }, "NOT_FOUND"},
+		{"get unknown field", func() error { return client.GetSobjectFields("Account", live, &target, "Nope") }, "INVALID_FIELD"},
+		{"get invalid id", func() error { return client.GetSobject("Account", "001", &target) }, "invalid id"},
+		{"describe unknown", func() error { _, err := client.Describe("Nope"); return err }, "NOT_FOUND"},
+		{"query unknown relationship", func() error {
+			_, err := client.QuerySobject(gosf.NewOpQuery("Account").Select("Id").SelectSubquery(gosf.NewOpQuery("Nopes").Select("Id")))
+			return err
+		}, "MALFORMED_QUERY"},
+		{"search without term", func() error { _, err := client.Search(gosf.NewOpSearch("")); return err }, "term"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v, want error of %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetSobjectByExternalID(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	acme := srv.Insert("Account", gosftest.Record{"Name": "Acme", "Code__c": "A/1"})
+	dup1 := srv.Insert("Account", gosftest.Record{"Name": "Globex", "Code__c": "G-1"})
+	dup2 := srv.Insert("Account", gosftest.Record{"Name": "Globex Asia", "Code__c": "G-1"})
+	gone := srv.Insert("Account", gosftest.Record{"Name": "Gone", "Code__c": "A/1"})
+	client := srv.Client()
+	if err := client.DeleteSobject("Account", gone); err != nil {
+		t.Fatal(err)
+	}
+
+	var got account
+	if err := client.GetSobjectByExternalID("Account", "Code__c", "A/1", &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := (account{ID: acme, Name: "Acme"}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	got = account{}
+	if err := client.GetSobjectByExternalID("Account", "Code__c", "A/1", &got, "Name"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (account{ID: acme, Name: "Acme"}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	err := client.GetSobjectByExternalID("Account", "Code__c", "G-1", &got)
+	if err == nil {
+		t.Fatal("got no error of a duplicate match")
+	}
+	for _, want := range []string{"300 Multiple Choices", "MULTIPLE_CHOICES", "/sobjects/Account/" + dup1, "/sobjects/Account/" + dup2} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("got %v, want %s", err, want)
+		}
+	}
+
+	for value, want := range map[string]string{"Z-9": "NOT_FOUND", "": "missing value"} {
+		if err = client.GetSobjectByExternalID("Account", "Code__c", value, &got); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("got %v of %q, want %s", err, value, want)
+		}
+	}
+	if err = client.GetSobjectByExternalID("Account", "Nope__c", "A/1", &got); err == nil || !strings.Contains(err.Error(), "INVALID_FIELD") {
+		t.Errorf("got %v, want INVALID_FIELD", err)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	srv.BatchSize = 2
+	for _, name := range []string{"Acme", "Globex", "Initech", "Umbrella", "Hooli"} {
+		srv.Insert("Account", gosftest.Record{"Name": name, "Industry": "Energy"})
+	}
+	deleted := srv.Insert("Account", gosftest.Record{"Name": "Gone", "Industry": "Energy"})
+	client := srv.Client()
+	if err := client.DeleteSobject("Account", deleted); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		op   *gosf.OpQuery
+		want []string
+	}{
+		{"where", gosf.NewOpQuery("Account").Select("Name").Where("Name", "Acme"), []string{"Acme"}},
+		{"in order", gosf.NewOpQuery("Account").Select("Name").WhereCompare("Name", ">", "B").OrderAsc("Name"),
+			[]string{"Globex", "Hooli", "Initech", "Umbrella"}},
+		{"limit", gosf.NewOpQuery("Account").Select("Name").OrderDesc("Name").Limit(1), []string{"Umbrella"}},
+		{"all with deleted", gosf.NewOpQuery("Account").Select("Name").Where("Industry", "Energy").OrderAsc("Name").QueryAll(),
+			[]string{"Acme", "Globex", "Gone", "Hooli", "Initech", "Umbrella"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			it := gosf.QueryIter[account](context.Background(), client, tt.op)
+			for it.Next() {
+				names = append(names, it.Record().Name)
+			}
+			if err := it.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", names, tt.want)
+			}
+		})
+	}
+
+	result, err := client.QuerySobject(gosf.NewOpQuery("Account").Select("Id", "Name").OrderAsc("Name"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalSize != 5 || result.Done || result.NextRecordsURL == "" {
+		t.Errorf("got totalSize %d, done %v, next %q, want the first page of 5", result.TotalSize, result.Done, result.NextRecordsURL)
+	}
+	var page []*account
+	if err = result.Parse(&page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].Name != "Acme" || page[1].Name != "Globex" {
+		t.Errorf("got page %+v", page)
+	}
+}
+
+func TestQueryAggregate(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	for _, opp := range []gosftest.Record{
+		{"Name": "A", "StageName": "Won", "Amount": 100, "Type": "New"},
+		{"Name": "B", "StageName": "Won", "Amount": 250, "Type": "Renewal"},
+		{"Name": "C", "StageName": "Lost", "Amount": 50, "Type": "New"},
+		{"Name": "D", "StageName": "Open", "Amount": nil, "Type": "New"},
+	} {
+		srv.Insert("Opportunity", opp)
+	}
+	client := srv.Client()
+
+	result, err := client.QueryAggregate(gosf.NewOpQuery("Opportunity").SelectCount().Where("Type", "New"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Count() != 3 || len(result.Records) != 0 {
+		t.Errorf("got count %d of %d records, want 3 of none", result.Count(), len(result.Records))
+	}
+
+	result, err = client.QueryAggregate(gosf.NewOpQuery("Opportunity").Select("StageName").
+		SelectAggregate(gosf.AggregateSum, "Amount", "").SelectAggregate(gosf.AggregateCount, "Id", "").
+		SelectAggregate(gosf.AggregateMax, "Amount", "most").
+		GroupBy("StageName").Having("COUNT(Id)", ">=", 1).OrderAsc("StageName"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type row struct {
+		stage string
+		sum   float64
+		count int
+		most  int
+	}
+	var got []row
+	for _, r := range result.Records {
+		stage, _ := r.String("StageName")
+		sum, _ := r.Float64("expr0")
+		most, _ := r.Int("most")
+		got = append(got, row{stage, sum, int(r.Expr(1).(float64)), most})
+	}
+	want := []row{{"Lost", 50, 1, 50}, {"Open", 0, 1, 0}, {"Won", 350, 2, 250}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if _, ok := result.Records[1].Float64("expr0"); ok {
+		t.Error("got a sum of no amounts, want null")
+	}
+
+	result, err = client.QueryAggregate(gosf.NewOpQuery("Opportunity").Select("StageName").
+		SelectAggregate(gosf.AggregateCount, "Id", "").GroupBy("StageName").Having("COUNT(Id)", ">", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Records) != 1 || result.Records[0]["StageName"] != "Won" {
+		t.Errorf("got %v, want the group of Won", result.Records)
+	}
+
+	result, err = client.QueryAggregate(gosf.NewOpQuery("Opportunity").Select("StageName", "Type").
+		SelectAggregate(gosf.AggregateSum, "Amount", "").GroupByRollup("StageName", "Type").Where("StageName", "Won"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rollup []string
+	for _, r := range result.Records {
+		stage, _ := r.String("StageName")
+		typ, _ := r.String("Type")
+		sum, _ := r.Int("expr0")
+		rollup = append(rollup, fmt.Sprintf("%s/%s=%d", stage, typ, sum))
+	}
+	if want := "Won/New=100,Won/Renewal=250,Won/=350,/=350"; strings.Join(rollup, ",") != want {
+		t.Errorf("got rollup %v, want %s", rollup, want)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	srv.Insert("Account", gosftest.Record{"Name": "Acme", "NumberOfEmployees": 10})
+	client := srv.Client()
+
+	desc, err := client.Describe("Account")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc.Name != "Account" {
+		t.Errorf("got name %s", desc.Name)
+	}
+	types := make(map[string]gosf.FieldType)
+	for _, field := range desc.Fields {
+		types[field.Name] = field.Type
+	}
+	for field, want := range map[string]gosf.FieldType{
+		"Id":                gosf.FieldTypeID,
+		"Name":              gosf.FieldTypeString,
+		"NumberOfEmployees": gosf.FieldTypeInt,
+		"IsDeleted":         gosf.FieldTypeBoolean,
+	} {
+		if types[field] != want {
+			t.Errorf("got type %s of %s, want %s", types[field], field, want)
+		}
+	}
+}
+
+// describeSpy records the If-Modified-Since headers and the statuses of
+// describe requests.
+type describeSpy struct {
+	mu       sync.Mutex
+	since    []string
+	statuses []int
+}
+
+func (d *describeSpy) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err == nil && strings.Contains(req.URL.Path, "/sobjects") {
+		d.mu.Lock()
+		d.since = append(d.since, req.Header.Get("If-Modified-Since"))
+		d.statuses = append(d.statuses, resp.StatusCode)
+		d.mu.Unlock()
+	}
+	return resp, err
+}
+
+func (d *describeSpy) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.since, d.statuses = nil, nil
+}
+
+func TestDescribeCache(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	srv.Insert("Account", gosftest.Record{"Name": "Acme"})
+	newClient := func(cache *gosf.DescribeCache) (*gosf.Client, *describeSpy) {
+		spy := &describeSpy{}
+		config := srv.Config()
+		config.Transport = spy
+		config.DescribeCache = cache
+		return gosf.NewClient(config, discardLogger{}), spy
+	}
+	fields := func(desc *gosf.SobjectDescription) map[string]bool {
+		names := make(map[string]bool)
+		for _, field := range desc.Fields {
+			names[field.Name] = true
+		}
+		return names
+	}
+
+	t.Run("fresh entry skips the request", func(t *testing.T) {
+		client, spy := newClient(&gosf.DescribeCache{TTL: time.Hour})
+		for i := 0; i < 3; i++ {
+			desc, err := client.Describe("Account")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !fields(desc)["Name"] {
+				t.Fatalf("got fields %v of call %d, want Name", fields(desc), i)
+			}
+			if _, err = client.DescribeGlobal(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if want := []int{200, 200}; !reflect.DeepEqual(spy.statuses, want) {
+			t.Errorf("got responses %v, want %v", spy.statuses, want)
+		}
+	})
+
+	t.Run("stale entry revalidated", func(t *testing.T) {
+		client, spy := newClient(&gosf.DescribeCache{})
+		first, err := client.Describe("Account")
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := client.Describe("Account")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []int{200, 304}; !reflect.DeepEqual(spy.statuses, want) {
+			t.Fatalf("got responses %v, want %v", spy.statuses, want)
+		}
+		if spy.since[0] != "" || spy.since[1] == "" {
+			t.Errorf("got If-Modified-Since %q, want it on the second request only", spy.since)
+		}
+		if !reflect.DeepEqual(first, second) {
+			t.Errorf("got %+v from the cached body, want %+v", second, first)
+		}
+	})
+
+	t.Run("200 replaces the entry", func(t *testing.T) {
+		client, spy := newClient(&gosf.DescribeCache{})
+		if _, err := client.Describe("Account"); err != nil {
+			t.Fatal(err)
+		}
+		srv.Insert("Account", gosftest.Record{"Name": "Globex", "Rating": "Hot"})
+		desc, err := client.Describe("Account")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fields(desc)["Rating"] {
+			t.Errorf("got fields %v, want the new Rating", fields(desc))
+		}
+		again, err := client.Describe("Account")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(again, desc) {
+			t.Errorf("got %+v, want the replaced entry", again)
+		}
+		if want := []int{200, 200, 304}; !reflect.DeepEqual(spy.statuses, want) {
+			t.Errorf("got responses %v, want %v", spy.statuses, want)
+		}
+		if spy.since[1] == spy.since[2] {
+			t.Errorf("got If-Modified-Since %q, want the Last-Modified of the new entry", spy.since)
+		}
+	})
+
+	t.Run("file store survives a new client", func(t *testing.T) {
+		dir := t.TempDir()
+		client, spy := newClient(&gosf.DescribeCache{TTL: time.Hour, Store: gosf.NewFileDescribeStore(dir)})
+		want, err := client.Describe("Account")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(spy.statuses) != 1 {
+			t.Fatalf("got responses %v, want one", spy.statuses)
+		}
+
+		client, spy = newClient(&gosf.DescribeCache{TTL: time.Hour, Store: gosf.NewFileDescribeStore(dir)})
+		got, err := client.Describe("Account")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(spy.statuses) != 0 {
+			t.Errorf("got responses %v, want none", spy.statuses)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+
+		client, spy = newClient(&gosf.DescribeCache{Store: gosf.NewFileDescribeStore(dir)})
+		if _, err = client.Describe("Account"); err != nil {
+			t.Fatal(err)
+		}
+		if want := []int{304}; !reflect.DeepEqual(spy.statuses, want) {
+			t.Errorf("got responses %v, want %v", spy.statuses, want)
+		}
+	})
+}
+
+func TestSearch(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	srv.Insert("Account", gosftest.Record{"Name": "Acme Energy"})
+	srv.Insert("Account", gosftest.Record{"Name": "Globex"})
+	srv.Insert("Contact", gosftest.Record{"LastName": "Acme"})
+	client := srv.Client()
+
+	tests := []struct {
+		name     string
+		op       *gosf.OpSearch
+		accounts []string
+		contacts int
+	}{
+		{"one sobject", gosf.NewOpSearch("Acme").Returning(gosf.NewOpQuery("Account").Select("Name")), []string{"Acme Energy"}, 0},
+		{"two sobjects", gosf.NewOpSearch("Acme").Returning(
+			gosf.NewOpQuery("Account").Select("Name"),
+			gosf.NewOpQuery("Contact").Select("LastName"),
+		), []string{"Acme Energy"}, 1},
+		{"no match", gosf.NewOpSearch("Initech").Returning(gosf.NewOpQuery("Account").Select("Name")), nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := client.Search(tt.op)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var accounts []*account
+			if err = result.Parse("Account", &accounts); err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, a := range accounts {
+				names = append(names, a.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.accounts, ",") {
+				t.Errorf("got accounts %v, want %v", names, tt.accounts)
+			}
+			if got := len(result.Groups["Contact"]); got != tt.contacts {
+				t.Errorf("got %d contacts, want %d", got, tt.contacts)
+			}
+		})
+	}
+}
+
+func TestParameterizedSearch(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	srv.Insert("Account", gosftest.Record{"Name": "Acme Energy", "Industry": "Energy"})
+	srv.Insert("Account", gosftest.Record{"Name": "Acme Media", "Industry": "Media"})
+	srv.Insert("Account", gosftest.Record{"Name": "Globex", "Industry": "Acme"})
+	srv.Insert("Contact", gosftest.Record{"LastName": "Acme", "Email": "info@acme.com"})
+	client := srv.Client()
+
+	tests := []struct {
+		name     string
+		op       *gosf.OpSearch
+		accounts []string
+		contacts int
+	}{
+		{"all fields", gosf.NewOpSearch("Acme").Returning(
+			gosf.NewOpQuery("Account").Select("Name").OrderAsc("Name"),
+			gosf.NewOpQuery("Contact"),
+		), []string{"Acme Energy", "Acme Media", "Globex"}, 1},
+		{"sobject filter", gosf.NewOpSearch("Acme").Returning(
+			gosf.NewOpQuery("Account").Select("Name").Where("Industry", "Media"),
+		), []string{"Acme Media"}, 0},
+		{"sobject order and limit", gosf.NewOpSearch("Acme").Returning(
+			gosf.NewOpQuery("Account").Select("Name").OrderDesc("Name").Limit(2),
+		), []string{"Globex", "Acme Media"}, 0},
+		{"in name fields", gosf.NewOpSearch("Acme").In(gosf.SearchNameFields).Returning(
+			gosf.NewOpQuery("Account").Select("Name").OrderAsc("Name"),
+			gosf.NewOpQuery("Contact"),
+		), []string{"Acme Energy", "Acme Media"}, 1},
+		{"in email fields", gosf.NewOpSearch("info@acme.com").In(gosf.SearchEmailFields).Returning(
+			gosf.NewOpQuery("Account").Select("Name"),
+			gosf.NewOpQuery("Contact"),
+		), nil, 1},
+		{"overall limit", gosf.NewOpSearch("Acme").Returning(
+			gosf.NewOpQuery("Account").Select("Name").OrderAsc("Name"),
+			gosf.NewOpQuery("Contact"),
+		).Limit(1), []string{"Acme Energy"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := client.ParameterizedSearch(tt.op)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var accounts []*account
+			if err = result.Parse("Account", &accounts); err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, a := range accounts {
+				if a.Industry != "" {
+					t.Errorf("got industry of %s, which is not selected", a.Name)
+				}
+				names = append(names, a.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.accounts, ",") {
+				t.Errorf("got accounts %v, want %v", names, tt.accounts)
+			}
+			if got := len(result.Groups["Contact"]); got != tt.contacts {
+				t.Errorf("got %d contacts, want %d", got, tt.contacts)
+			}
+		})
+	}
+}
+
+func TestSearchEscaped(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	srv.Insert("Account", gosftest.Record{"Name": "Acme-Co (West)"})
+	srv.Insert("Account", gosftest.Record{"Name": "AcmeXCo"})
+	client := srv.Client()
+
+	result, err := client.Search(gosf.NewOpSearch("Acme-Co (West)").Returning(gosf.NewOpQuery("Account").Select("Name")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var accounts []*account
+	if err = result.Parse("Account", &accounts); err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || accounts[0].Name != "Acme-Co (West)" {
+		t.Errorf("got %+v, want Acme-Co (West)", accounts)
+	}
+
+	tests := []struct {
+		name string
+		op   *gosf.OpSearch
+		want int
+	}{
+		{"wildcard", gosf.NewOpSearchExpr("Acme?Co"), 2},
+		{"wildcard escaped", gosf.NewOpSearch("Acme?Co"), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := client.Search(tt.op.Returning(gosf.NewOpQuery("Account").Select("Name")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(result.Records); got != tt.want {
+				t.Errorf("got %d records, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchSuggestions(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	for _, name := range []string{"Acme Energy", "Acme Media", "Big Acme", "Globex"} {
+		srv.Insert("Account", gosftest.Record{"Name": name})
+	}
+	client := srv.Client()
+
+	result, err := client.SearchSuggestions("acme", "Account", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var accounts []*account
+	if err = result.Parse(&accounts); err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 2 || accounts[0].Name != "Acme Energy" || accounts[1].Name != "Acme Media" || accounts[0].ID == "" {
+		t.Errorf("got %+v, want the first 2 of Acme", accounts)
+	}
+	if !result.HasMoreResults {
+		t.Error("got no more results, want Big Acme")
+	}
+
+	if result, err = client.SearchSuggestions("acme", "Account", 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Records) != 3 || result.HasMoreResults {
+		t.Errorf("got %d records, more %v, want all 3", len(result.Records), result.HasMoreResults)
+	}
+
+	if _, err = client.SearchSuggestions(" ", "Account", 0); err == nil {
+		t.Error("got no error of an empty term")
+	}
+}
+
+func TestSearchScopeOrder(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	srv.Insert("Contact", gosftest.Record{"LastName": "Acme"})
+	srv.Insert("Account", gosftest.Record{"Name": "Acme"})
+	client := srv.Client()
+
+	scopes, err := client.SearchScopeOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, scope := range scopes {
+		got = append(got, scope.Type)
+		if !strings.HasSuffix(scope.URL, "/sobjects/"+scope.Type) {
+			t.Errorf("got url %s of %s", scope.URL, scope.Type)
+		}
+	}
+	if want := []string{"Account", "Contact"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestExplainQuery(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	id := srv.Insert("Account", gosftest.Record{"Name": "Acme"})
+	srv.Insert("Account", gosftest.Record{"Name": "Globex"})
+	client := srv.Client()
+
+	tests := []struct {
+		name        string
+		op          *gosf.OpQuery
+		leading     string
+		cardinality int
+	}{
+		{"by id", gosf.NewOpQuery("Account").Select("Name").Where("Id", id), "Index", 1},
+		{"by name", gosf.NewOpQuery("Account").Select("Name").Where("Name", "Globex"), "TableScan", 1},
+		{"all", gosf.NewOpQuery("Account").Select("Name"), "TableScan", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := client.ExplainQuery(tt.op)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Plans) != 1 {
+				t.Fatalf("got %d plans, want 1", len(result.Plans))
+			}
+			plan := result.Plans[0]
+			if plan.LeadingOperationType != tt.leading || plan.Cardinality != tt.cardinality || plan.SobjectCardinality != 2 {
+				t.Errorf("got plan %+v, want %s of cardinality %d", plan, tt.leading, tt.cardinality)
+			}
+		})
+	}
+}
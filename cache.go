@@ -0,0 +1,191 @@
+package gosf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sidebiequ/gosf/internal/jsonfile"
+)
+
+/************************************/
+/********** DESCRIBE CACHE **********/
+/************************************/
+
+// DescribeCache caches the results of DescribeGlobal and Describe, set it
+// as Config.DescribeCache:
+//
+//	config.DescribeCache = &gosf.DescribeCache{
+//		TTL:   time.Hour,
+//		Store: gosf.NewFileDescribeStore("/var/cache/gosf"),
+//	}
+//
+// A cached result is used without asking salesforce until TTL passes.
+// After that it's revalidated by If-Modified-Since, salesforce responds
+// 304 if the metadata is unchanged and the cached result is used again for
+// another TTL. A zero TTL revalidates on every call.
+type DescribeCache struct {
+	TTL time.Duration
+	// Store keeps the cached results, a memory store is used if it's nil.
+	Store DescribeStore
+
+	once sync.Once
+}
+
+// DescribeEntry is a describe result cached in DescribeStore.
+type DescribeEntry struct {
+	// Body is the response body of salesforce.
+	Body json.RawMessage `json:"body"`
+	// LastModified is the Last-Modified header of the response.
+	LastModified string `json:"lastModified"`
+	// CachedAt is when the entry is fetched or revalidated.
+	CachedAt time.Time `json:"cachedAt"`
+}
+
+// DescribeStore stores DescribeEntry by key, the key is the URL of the
+// describe request. It must be safe for concurrent use.
+type DescribeStore interface {
+	// Load returns the entry of key, nil if it's not cached.
+	Load(key string) (*DescribeEntry, error)
+	// Save caches the entry of key.
+	Save(key string, entry *DescribeEntry) error
+}
+
+func (c *DescribeCache) store() DescribeStore {
+	c.once.Do(func() {
+		if c.Store == nil {
+			c.Store = NewMemoryDescribeStore()
+		}
+	})
+	return c.Store
+}
+
+// fresh returns true if entry can be used without revalidation.
+func (c *DescribeCache) fresh(entry *DescribeEntry) bool {
+	return time.Since(entry.CachedAt) < c.TTL
+}
+
+type memoryDescribeStore struct {
+	mu      sync.RWMutex
+	entries map[string]*DescribeEntry
+}
+
+// NewMemoryDescribeStore returns a DescribeStore keeps entries in memory.
+func NewMemoryDescribeStore() DescribeStore {
+	return &memoryDescribeStore{entries: make(map[string]*DescribeEntry)}
+}
+
+func (s *memoryDescribeStore) Load(key string) (*DescribeEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.entries[key], nil
+}
+
+func (s *memoryDescribeStore) Save(key string, entry *DescribeEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = entry
+	return nil
+}
+
+type fileDescribeStore struct {
+	dir string
+}
+
+// NewFileDescribeStore returns a DescribeStore keeps each entry in a json
+// file under dir, so the cache survives restarts and can be shared by
+// processes. dir is created when the first entry is saved.
+func NewFileDescribeStore(dir string) DescribeStore {
+	return &fileDescribeStore{dir: dir}
+}
+
+func (s *fileDescribeStore) path(key string) string {
+	return filepath.Join(s.dir, url.QueryEscape(key)+".json")
+}
+
+func (s *fileDescribeStore) Load(key string) (entry *DescribeEntry, err error) {
+	_, err = jsonfile.Read(s.path(key), &entry)
+	return
+}
+
+func (s *fileDescribeStore) Save(key string, entry *DescribeEntry) error {
+	return jsonfile.Write(s.path(key), entry)
+}
+
+// conditional makes describe requests conditional on the cached entry and
+// keeps the response as the entry to cache.
+type conditional struct {
+	cached *DescribeEntry
+	entry  *DescribeEntry
+}
+
+func (c *conditional) request(urlStr string) *Request {
+	req := NewRequest(http.MethodGet, urlStr, nil)
+	if c.cached != nil && c.cached.LastModified != "" {
+		req.SetHeader("If-Modified-Since", c.cached.LastModified)
+	}
+	return req
+}
+
+func (c *conditional) handle(resp *http.Response, name string) error {
+	switch {
+	case resp.StatusCode == http.StatusNotModified && c.cached != nil:
+		c.entry = &DescribeEntry{Body: c.cached.Body, LastModified: c.cached.LastModified}
+	case resp.StatusCode == http.StatusOK:
+		byts, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		c.entry = &DescribeEntry{Body: byts, LastModified: resp.Header.Get("Last-Modified")}
+	default:
+		return fmt.Errorf("%s operator can't handle response with code %d, expect %d", name, resp.StatusCode, http.StatusOK)
+	}
+	return nil
+}
+
+// describeOperator is an Operator of describe results can be cached.
+type describeOperator interface {
+	Operator
+	conditional() *conditional
+	decode(body []byte) error
+}
+
+// describe does op through the describe cache of c, if any.
+func (c *Client) describe(op describeOperator) (err error) {
+	cache := c.describeCache
+	if cache == nil {
+		return c.do(op)
+	}
+
+	req, err := op.Make(c.newRequestCtx())
+	if err != nil {
+		return
+	}
+	key := req.urlStr
+	store := cache.store()
+
+	cached, err := store.Load(key)
+	if err != nil {
+		c.logger.Printf("[describe cache] failed to load %s: %v", key, err)
+		cached = nil
+	}
+	if cached != nil && cache.fresh(cached) {
+		return op.decode(cached.Body)
+	}
+
+	op.conditional().cached = cached
+	if err = c.do(op); err != nil {
+		return
+	}
+	entry := op.conditional().entry
+	entry.CachedAt = time.Now()
+	if serr := store.Save(key, entry); serr != nil {
+		c.logger.Printf("[describe cache] failed to save %s: %v", key, serr)
+	}
+	return
+}
@@ -1,10 +1,14 @@
 package gosf
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -23,10 +27,52 @@ type Config struct {
 	ExpiresIn    int    `json:"expires_in"`
 
 	// api
-	APIVersion int `json:"api_version"`
+	APIVersion APIVersion `json:"api_version"`
+
+	// NegotiateAPIVersion makes NewClient ask salesforce for the versions
+	// it supports and use the newest one in [MinAPIVersion, MaxAPIVersion].
+	// A zero bound means unbounded.
+	NegotiateAPIVersion bool       `json:"negotiate_api_version"`
+	MinAPIVersion       APIVersion `json:"min_api_version"`
+	MaxAPIVersion       APIVersion `json:"max_api_version"`
 
 	// proxy url
 	ProxyURL string `json:"proxy_url"`
+
+	// Transport sends the requests to salesforce, including the token
+	// exchange. ProxyURL is ignored if it is set. Use it to instrument or
+	// record the traffic.
+	Transport http.RoundTripper `json:"-"`
+
+	// DescribeCache caches describe results if it is set.
+	DescribeCache *DescribeCache `json:"-"`
+}
+
+// String returns the config with secrets redacted, so it is safe to print.
+func (c Config) String() string {
+	type config Config // avoid recursion
+	c.ClientSecret, c.Password = redactSecret(c.ClientSecret), redactSecret(c.Password)
+	return fmt.Sprintf("%+v", config(c))
+}
+
+// LogValue implements slog.LogValuer with secrets redacted.
+func (c Config) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("host", c.Host),
+		slog.String("client_id", c.ClientID),
+		slog.String("client_secret", redactSecret(c.ClientSecret)),
+		slog.String("username", c.Username),
+		slog.String("password", redactSecret(c.Password)),
+		slog.String("api_version", c.APIVersion.String()),
+		slog.String("proxy_url", c.ProxyURL),
+	)
+}
+
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
 }
 
 /***********************************/
@@ -41,6 +87,14 @@ type token struct {
 	ExpiresAt   time.Time `json:"-"`
 }
 
+// LogValue implements slog.LogValuer without exposing the token.
+func (t *token) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("token_type", t.TokenType),
+		slog.Time("expires_at", t.ExpiresAt),
+	)
+}
+
 // IsExpired returns true if token has expired.
 func (t *token) IsExpired() bool {
 	return time.Now().After(t.ExpiresAt)
@@ -55,18 +109,23 @@ type oAuth struct {
 	*Config
 	*token
 	transport http.RoundTripper
+	mu        sync.Mutex
 }
 
 func newOAuth(config *Config) *oAuth {
-	var proxy *url.URL
-	if config.ProxyURL != "" {
-		proxy, _ = url.Parse(config.ProxyURL)
+	transport := config.Transport
+	if transport == nil {
+		var proxy *url.URL
+		if config.ProxyURL != "" {
+			proxy, _ = url.Parse(config.ProxyURL)
+		}
+		transport = &http.Transport{
+			Proxy: http.ProxyURL(proxy),
+		}
 	}
 	return &oAuth{
-		Config: config,
-		transport: &http.Transport{
-			Proxy: http.ProxyURL(proxy),
-		},
+		Config:    config,
+		transport: transport,
 	}
 }
 
@@ -76,7 +135,7 @@ func newOAuth(config *Config) *oAuth {
 //          "error": "ERROR_TYPE",
 //          "error_description": "ERROR_DESCRIPTION"
 //      }
-func (o *oAuth) exchangeToken() (t *token, err error) {
+func (o *oAuth) exchangeToken(ctx context.Context) (t *token, err error) {
 	u := o.Host + "/services/oauth2/token"
 	form := url.Values{
 		"grant_type":    {"password"},
@@ -86,10 +145,19 @@ func (o *oAuth) exchangeToken() (t *token, err error) {
 		"password":      {o.Password},
 	}
 
-	resp, err := http.PostForm(u, form)
+	ctx = withOperation(ctx, Operation{Name: "token", Attempt: 1})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := (&http.Client{Transport: o.transport}).Do(req)
 	if err != nil {
 		return
 	}
+	defer resp.Body.Close()
+
 	decoder := json.NewDecoder(resp.Body)
 	if resp.StatusCode != 200 {
 		var authErrResp struct {
@@ -115,15 +183,57 @@ func (o *oAuth) exchangeToken() (t *token, err error) {
 	return
 }
 
-func (o *oAuth) RoundTrip(req *http.Request) (res *http.Response, err error) {
-	if o.token == nil || o.token.IsExpired() {
-		o.token, err = o.exchangeToken()
-		if err != nil {
+// validToken returns the current token, exchanges a new one if it is
+// missing, expired or equal to the rejected one.
+func (o *oAuth) validToken(ctx context.Context, rejected *token) (t *token, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.token == nil || o.token.IsExpired() || o.token == rejected {
+		if o.token, err = o.exchangeToken(ctx); err != nil {
 			return
 		}
 	}
-	req.Header.Add("Authorization", o.TokenType+" "+o.AccessToken)
-	return o.transport.RoundTrip(req)
+	return o.token, nil
+}
+
+// RoundTrip sends req with the access token. If salesforce rejects the token
+// before it expires (e.g. session revoked), a new token is exchanged and the
+// request is retried once. A request whose body can't be read again, which
+// has no GetBody, is not retried and the 401 response is returned; the
+// requests of Client always have one.
+func (o *oAuth) RoundTrip(req *http.Request) (res *http.Response, err error) {
+	t, err := o.validToken(req.Context(), nil)
+	if err != nil {
+		return
+	}
+	if res, err = o.transport.RoundTrip(o.authorize(req, t, 1)); err != nil {
+		return
+	}
+	if res.StatusCode != http.StatusUnauthorized || (req.Body != nil && req.GetBody == nil) {
+		return
+	}
+
+	res.Body.Close()
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	if t, err = o.validToken(req.Context(), t); err != nil {
+		return nil, err
+	}
+	return o.transport.RoundTrip(o.authorize(retry, t, 2))
+}
+
+// authorize returns a copy of req with authorization header and attempt set.
+func (o *oAuth) authorize(req *http.Request, t *token, attempt int) *http.Request {
+	op, _ := OperationFromContext(req.Context())
+	op.Attempt = attempt
+	req = req.Clone(withOperation(req.Context(), op))
+	req.Header.Set("Authorization", t.TokenType+" "+t.AccessToken)
+	return req
 }
 
 /************************************/
@@ -132,9 +242,12 @@ func (o *oAuth) RoundTrip(req *http.Request) (res *http.Response, err error) {
 
 // Client Type
 type Client struct {
-	client     *http.Client
-	requestCtx *RequestCtx
-	logger     Logger
+	client *http.Client
+	// mu guards requestCtx.version, which NegotiateAPIVersion changes.
+	mu            sync.RWMutex
+	requestCtx    *RequestCtx
+	logger        Logger
+	describeCache *DescribeCache
 }
 
 // NewClient returns a Client instance.
@@ -143,8 +256,6 @@ func NewClient(config *Config, logger Logger) *Client {
 	if logger == nil {
 		logger = defaultLogger
 		logger.Print("[logger] argument 'logger' is nil, use defaultLogger instead")
-	} else {
-		defaultLogger = logger
 	}
 
 	// set config.ExpiresIn default value if it's invalid
@@ -154,30 +265,62 @@ func NewClient(config *Config, logger Logger) *Client {
 	}
 	logger.Printf("[expired time] token expired after %ds and auto-refresh", config.ExpiresIn)
 
-	// set requestCtx.version to maxVersion if it is out of bound
+	// set requestCtx.version to defaultAPIVersion if it is unset or too old
 	requestCtx := &RequestCtx{
 		host:    config.Host,
 		version: config.APIVersion,
 	}
 	if !requestCtx.isVersionValid() {
-		requestCtx.version = maxAPIVersion
-		logger.Printf("[api version] config.APIVersion is out of bound [%d, %d], set to %d instead", minAPIVersion, maxAPIVersion, requestCtx.version)
+		requestCtx.version = defaultAPIVersion
+		if !config.NegotiateAPIVersion {
+			logger.Printf("[api version] config.APIVersion is lower than %s, set to %s instead", minAPIVersion, requestCtx.version)
+		}
 	}
 
-	return &Client{
+	c := &Client{
 		client: &http.Client{
 			Transport: newOAuth(config),
 		},
-		requestCtx: requestCtx,
-		logger:     logger,
+		requestCtx:    requestCtx,
+		logger:        logger,
+		describeCache: config.DescribeCache,
 	}
+
+	// negotiate api version with salesforce, keep the configured one if fail
+	if config.NegotiateAPIVersion {
+		version, err := c.NegotiateAPIVersion(config.MinAPIVersion, config.MaxAPIVersion)
+		if err != nil {
+			logger.Printf("[api version] negotiation failed, use %s instead: %v", requestCtx.version, err)
+		} else {
+			logger.Printf("[api version] negotiated version %s", version)
+		}
+	}
+	return c
+}
+
+func (c *Client) do(op Operator) error {
+	return c.doContext(context.Background(), op)
 }
 
-func (c *Client) do(op Operator) (err error) {
-	req, err := op.Make(&RequestCtx{
+// newRequestCtx returns a copy of the request context of c, so the api
+// version negotiated concurrently doesn't change it.
+func (c *Client) newRequestCtx() *RequestCtx {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return &RequestCtx{
 		host:    c.requestCtx.host,
 		version: c.requestCtx.version,
-	})
+		logger:  c.logger,
+	}
+}
+
+// APIVersion returns the api version requests are made with.
+func (c *Client) APIVersion() APIVersion {
+	return c.newRequestCtx().version
+}
+
+func (c *Client) doContext(ctx context.Context, op Operator) (err error) {
+	req, err := op.Make(c.newRequestCtx())
 	if err != nil {
 		return
 	}
@@ -187,21 +330,47 @@ func (c *Client) do(op Operator) (err error) {
 		return
 	}
 
-	return c.doWithHTTPRequest(httpReq, op.Handle)
+	operation := Operation{Name: "do"}
+	if describer, ok := op.(OperationDescriber); ok {
+		operation = describer.Operation()
+	}
+	return c.doWithHTTPRequest(httpReq.WithContext(withOperation(ctx, operation)), op.Handle)
 }
 
 func (c *Client) doWithHTTPRequest(httpReq *http.Request, handler func(*http.Response) error) (err error) {
 	httpReq.Header.Set("Content-Type", "application/json")
+	start := time.Now()
 	resp, err := c.client.Do(httpReq)
-	fmt.Println("resp is %s", resp)
 	if err != nil {
+		logFields(c.logger, levelError, "[request] failed",
+			"method", httpReq.Method,
+			"url", httpReq.URL.String(),
+			"duration", time.Since(start),
+			"error", err.Error(),
+		)
 		return
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode/100 == 2 {
+	fields := []interface{}{
+		"method", httpReq.Method,
+		"url", httpReq.URL.String(),
+		"status", resp.StatusCode,
+		"duration", time.Since(start),
+	}
+	if usage, ok := ParseAPIUsage(resp.Header); ok {
+		fields = append(fields, "api_usage", usage.String())
+	}
+	if id := requestID(resp.Header); id != "" {
+		fields = append(fields, "request_id", id)
+	}
+
+	// 304 answers a conditional request, the Operator sent it handles it
+	if resp.StatusCode/100 == 2 || resp.StatusCode == http.StatusNotModified {
+		logFields(c.logger, levelDebug, "[request] done", fields...)
 		err = handler(resp)
 	} else {
+		logFields(c.logger, levelWarn, "[request] error response", fields...)
 		err = parseErrResponse(resp)
 	}
 	return
@@ -212,3 +381,9 @@ func (c *Client) doWithHTTPRequest(httpReq *http.Request, handler func(*http.Res
 func (c *Client) Do(op Operator) error {
 	return c.do(op)
 }
+
+// DoContext is like Do, the request is sent with ctx, which carries
+// cancellation and the trace span.
+func (c *Client) DoContext(ctx context.Context, op Operator) error {
+	return c.doContext(ctx, op)
+}
@@ -0,0 +1,159 @@
+package gosf_test
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sidebiequ/gosf"
+	"github.com/sidebiequ/gosf/gosftest"
+)
+
+func TestAPIVersion(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	srv.Versions = append(srv.Versions, 67, 68)
+
+	tests := []struct {
+		name      string
+		version   gosf.APIVersion
+		negotiate bool
+		want      gosf.APIVersion
+	}{
+		{"unset uses default", 0, false, 66},
+		{"too old uses default", 7, false, 66},
+		{"newer than default is kept", 67, false, 67},
+		{"negotiated above default", 0, true, 68},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := srv.Config()
+			config.APIVersion = tt.version
+			config.NegotiateAPIVersion = tt.negotiate
+			client := gosf.NewClient(config, discardLogger{})
+			if got := client.APIVersion(); got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+			if _, err := client.Resources(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestNegotiateAPIVersionConcurrently(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	srv.Insert("Account", gosftest.Record{"Name": "Acme"})
+	client := srv.Client()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := client.NegotiateAPIVersion(60, 0); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := client.QuerySobject(gosf.NewOpQuery("Account").Select("Name")); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := client.APIVersion(); got != srv.LatestVersion() {
+		t.Errorf("got %s, want %s", got, srv.LatestVersion())
+	}
+}
+
+// discardLogger is a gosf.Logger prints nothing.
+type discardLogger struct{}
+
+func (discardLogger) Print(v ...interface{})                 {}
+func (discardLogger) Printf(format string, v ...interface{}) {}
+
+// revoking rejects the first revoke api requests as if the session was
+// revoked, by replacing their token, and counts the requests.
+type revoking struct {
+	mu     sync.Mutex
+	revoke int
+	tokens int
+	calls  int
+}
+
+func (r *revoking) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	if strings.HasSuffix(req.URL.Path, "/oauth2/token") {
+		r.tokens++
+	} else {
+		r.calls++
+		if r.revoke > 0 {
+			r.revoke--
+			req = req.Clone(req.Context())
+			req.Header.Set("Authorization", "Bearer revoked")
+		}
+	}
+	r.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestRetryOnRevokedToken(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	id := srv.Insert("Account", gosftest.Record{"Name": "Acme"})
+	newClient := func(revoke int) (*gosf.Client, *revoking) {
+		transport := &revoking{revoke: revoke}
+		config := srv.Config()
+		config.Transport = transport
+		client := gosf.NewClient(config, discardLogger{})
+		// exchange the first token
+		if _, err := client.Resources(); err != nil {
+			t.Fatal(err)
+		}
+		return client, transport
+	}
+
+	t.Run("get retried with a new token", func(t *testing.T) {
+		client, transport := newClient(0)
+		transport.revoke = 1
+		var account struct{ Name string }
+		if err := client.GetSobject("Account", id, &account); err != nil {
+			t.Fatal(err)
+		}
+		if account.Name != "Acme" || transport.tokens != 2 || transport.calls != 3 {
+			t.Errorf("got %+v, %d tokens, %d calls, want Acme, 2 tokens, 3 calls", account, transport.tokens, transport.calls)
+		}
+	})
+
+	t.Run("body sent again", func(t *testing.T) {
+		client, transport := newClient(0)
+		transport.revoke = 1
+		created, err := client.CreaetSobject("Account", map[string]interface{}{"Name": "Globex"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec, ok := srv.Get("Account", created); !ok || rec["Name"] != "Globex" {
+			t.Errorf("got %v, want the record created by the retry", rec)
+		}
+		if n := len(srv.Records("Account")); n != 2 {
+			t.Errorf("got %d accounts, want 2", n)
+		}
+	})
+
+	t.Run("retried once", func(t *testing.T) {
+		client, transport := newClient(0)
+		transport.revoke = 2
+		var account struct{ Name string }
+		err := client.GetSobject("Account", id, &account)
+		if err == nil || !strings.Contains(err.Error(), "INVALID_SESSION_ID") {
+			t.Fatalf("got %v, want INVALID_SESSION_ID", err)
+		}
+		if transport.tokens != 2 || transport.calls != 3 {
+			t.Errorf("got %d tokens, %d calls, want 2 tokens, 3 calls", transport.tokens, transport.calls)
+		}
+	})
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/sidebiequ/gosf"
+)
+
+// generator renders Go structs of sobject descriptions.
+type generator struct {
+	// types are the Go type names of the sobjects generated, keyed by
+	// sobject name.
+	types map[string]string
+	// picklistTypes are the Go type names of the picklist fields, keyed by
+	// sobject name and field name joined by a dot.
+	picklistTypes map[string]string
+	// used are the names declared in the package, so types and constants
+	// never collide.
+	used map[string]bool
+	buf  bytes.Buffer
+	// usesGosf is set if the generated code refers to package gosf.
+	usesGosf bool
+}
+
+// generate returns the formatted Go source of the structs of descriptions
+// in package pkg.
+func generate(pkg string, descriptions []*gosf.SobjectDescription) ([]byte, error) {
+	g := &generator{
+		types:         make(map[string]string),
+		picklistTypes: make(map[string]string),
+		used:          make(map[string]bool),
+	}
+	for _, d := range descriptions {
+		g.types[d.Name] = uniqueName(goName(d.Name, false), g.used)
+	}
+	// picklist types are named after all the sobject types, which keep
+	// their names, like AccountType of the sobject AccountType__c
+	for _, d := range descriptions {
+		for _, f := range d.Fields {
+			if f.Type == gosf.FieldTypePicklist && len(f.PicklistValues) > 0 {
+				g.picklistTypes[d.Name+"."+f.Name] = uniqueName(g.types[d.Name]+goName(f.Name, false), g.used)
+			}
+		}
+	}
+
+	var body bytes.Buffer
+	for _, d := range descriptions {
+		g.buf.Reset()
+		g.sobject(d)
+		body.Write(g.buf.Bytes())
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by gosf-gen. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	if g.usesGosf {
+		src.WriteString("import \"github.com/sidebiequ/gosf\"\n\n")
+	}
+	src.Write(body.Bytes())
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+	return formatted, nil
+}
+
+// sobject renders the struct of d, its picklist types and SobjectName
+// method.
+func (g *generator) sobject(d *gosf.SobjectDescription) {
+	typeName := g.types[d.Name]
+	var picklists []*gosf.FieldDescription
+	used := map[string]bool{"SobjectName": true}
+
+	fmt.Fprintf(&g.buf, "// %s is the sobject %s", typeName, d.Name)
+	if d.Label != "" && d.Label != d.Name {
+		fmt.Fprintf(&g.buf, " (%s)", d.Label)
+	}
+	fmt.Fprintf(&g.buf, ".\ntype %s struct {\n", typeName)
+	for _, f := range d.Fields {
+		goType, ok := g.fieldType(d.Name, f)
+		if !ok {
+			continue
+		}
+		if f.Type == gosf.FieldTypePicklist && len(f.PicklistValues) > 0 {
+			picklists = append(picklists, f)
+		}
+		name := uniqueName(goName(f.Name, f.Type == gosf.FieldTypeReference), used)
+		fmt.Fprintf(&g.buf, "%s %s `json:\"%s%s\" sf:\"%s\"`\n", name, goType, f.Name, omit(goType), f.Name)
+	}
+
+	// relationships are not selected by SelectStruct, their structs refer
+	// to each other, select them by SelectParent and SelectSubquery
+	for _, f := range d.Fields {
+		if f.RelationshipName == "" || len(f.ReferenceTo) != 1 {
+			continue
+		}
+		if parent, ok := g.types[f.ReferenceTo[0]]; ok {
+			name := uniqueName(goName(f.RelationshipName, false), used)
+			fmt.Fprintf(&g.buf, "%s *%s `json:\"%s,omitempty\" sf:\"-\"`\n", name, parent, f.RelationshipName)
+		}
+	}
+	for _, child := range d.ChildRelationships {
+		if child.RelationshipName == "" {
+			continue
+		}
+		if childType, ok := g.types[child.ChildSobject]; ok {
+			name := uniqueName(goName(child.RelationshipName, false), used)
+			fmt.Fprintf(&g.buf, "%s []*%s `json:\"%s,omitempty\" sf:\"-\"`\n", name, childType, child.RelationshipName)
+		}
+	}
+	g.buf.WriteString("}\n\n")
+
+	fmt.Fprintf(&g.buf, "// SobjectName implements gosf.SobjectNamer.\nfunc (%s) SobjectName() string { return %q }\n\n", typeName, d.Name)
+
+	for _, f := range picklists {
+		g.picklist(d.Name, typeName, f)
+	}
+}
+
+// picklist renders the type of picklist field f of the sobject
+// sobjectName and its values.
+func (g *generator) picklist(sobjectName, typeName string, f *gosf.FieldDescription) {
+	picklistType := g.picklistTypes[sobjectName+"."+f.Name]
+	fmt.Fprintf(&g.buf, "// %s is a value of %s.%s.\ntype %s string\n\n", picklistType, typeName, f.Name, picklistType)
+
+	fmt.Fprintf(&g.buf, "// Values of %s.%s.\nconst (\n", typeName, f.Name)
+	for _, value := range f.PicklistValues {
+		if !value.Active {
+			continue
+		}
+		name := uniqueName(picklistType+goName(value.Value, false), g.used)
+		fmt.Fprintf(&g.buf, "%s %s = %s\n", name, picklistType, strconv.Quote(value.Value))
+	}
+	g.buf.WriteString(")\n\n")
+}
+
+// fieldType returns the Go type of field f of the sobject sobjectName, ok
+// is false if the field is not generated.
+func (g *generator) fieldType(sobjectName string, f *gosf.FieldDescription) (goType string, ok bool) {
+	switch f.Type {
+	case gosf.FieldTypeID, gosf.FieldTypeReference:
+		goType, g.usesGosf = "gosf.ID", true
+	case gosf.FieldTypeString, gosf.FieldTypeTextArea,
+		gosf.FieldTypeEncryptedString, gosf.FieldTypeEmail, gosf.FieldTypePhone, gosf.FieldTypeURL,
+		gosf.FieldTypeCombobox, gosf.FieldTypeMultiPicklist, gosf.FieldTypeBase64, gosf.FieldTypeTime:
+		goType = "string"
+	case gosf.FieldTypePicklist:
+		goType = "string"
+		if len(f.PicklistValues) > 0 {
+			goType = g.picklistTypes[sobjectName+"."+f.Name]
+		}
+	case gosf.FieldTypeBoolean:
+		// booleans are never null
+		return "bool", true
+	case gosf.FieldTypeInt:
+		goType = "int"
+	case gosf.FieldTypeLong:
+		goType = "int64"
+	case gosf.FieldTypeDouble, gosf.FieldTypePercent:
+		goType = "float64"
+	case gosf.FieldTypeCurrency:
+		goType, g.usesGosf = "gosf.Currency", true
+	case gosf.FieldTypeDate:
+		goType, g.usesGosf = "gosf.Date", true
+	case gosf.FieldTypeDateTime:
+		goType, g.usesGosf = "gosf.DateTime", true
+	case gosf.FieldTypeAddress, gosf.FieldTypeLocation, gosf.FieldTypeAnyType:
+		return "interface{}", true
+	default:
+		return "", false
+	}
+	if f.Nillable {
+		goType = "*" + goType
+	}
+	return goType, true
+}
+
+// omit returns the json option of a field of goType. Pointers, interfaces
+// and strings omit empty values, dates omit zero values. Numbers and
+// booleans have no option, so their zero values can be written.
+func omit(goType string) string {
+	switch goType {
+	case "bool", "int", "int64", "float64", "gosf.Currency":
+		return ""
+	case "gosf.Date", "gosf.DateTime":
+		return ",omitzero"
+	default:
+		return ",omitempty"
+	}
+}
+
+// goName converts an api name to an exported Go name, like AccountID for
+// AccountId, DueDate for Due_Date__c and ParentID for the reference field
+// Parent__c. Namespace prefixes are kept, ns__Field__c is NsField.
+func goName(apiName string, reference bool) string {
+	name := apiName
+	custom := false
+	for _, suffix := range []string{"__c", "__r", "__mdt", "__e", "__x", "__b"} {
+		if strings.HasSuffix(name, suffix) {
+			name, custom = strings.TrimSuffix(name, suffix), true
+			break
+		}
+	}
+
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if upper {
+				r = unicode.ToUpper(r)
+			}
+			b.WriteRune(r)
+			upper = false
+		default:
+			upper = true
+		}
+	}
+	goName := b.String()
+	switch {
+	case goName == "":
+		goName = "X"
+	case unicode.IsDigit(rune(goName[0])):
+		goName = "X" + goName
+	}
+
+	switch {
+	case goName == "Id":
+		return "ID"
+	case strings.HasSuffix(goName, "Id"):
+		return strings.TrimSuffix(goName, "Id") + "ID"
+	case custom && reference:
+		return goName + "ID"
+	}
+	return goName
+}
+
+// uniqueName returns name, or name with a number suffix if it's used.
+func uniqueName(name string, used map[string]bool) string {
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	used[unique] = true
+	return unique
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/sidebiequ/gosf"
+)
+
+func TestGenerateOmit(t *testing.T) {
+	d := &gosf.SobjectDescription{
+		SobjectMetadata: gosf.SobjectMetadata{Name: "Account"},
+		Fields: []*gosf.FieldDescription{
+			{Name: "Id", Type: gosf.FieldTypeID},
+			{Name: "Name", Type: gosf.FieldTypeString},
+			{Name: "Description", Type: gosf.FieldTypeTextArea, Nillable: true},
+			{Name: "IsActive__c", Type: gosf.FieldTypeBoolean, Nillable: true},
+			{Name: "NumberOfEmployees", Type: gosf.FieldTypeInt},
+			{Name: "Score__c", Type: gosf.FieldTypeDouble},
+			{Name: "AnnualRevenue", Type: gosf.FieldTypeCurrency},
+			{Name: "Rank__c", Type: gosf.FieldTypeInt, Nillable: true},
+			{Name: "Since__c", Type: gosf.FieldTypeDate},
+		},
+	}
+	src, err := generate("sobjects", []*gosf.SobjectDescription{d})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"ID gosf.ID `json:\"Id,omitempty\" sf:\"Id\"`",
+		"Name string `json:\"Name,omitempty\" sf:\"Name\"`",
+		"Description *string `json:\"Description,omitempty\" sf:\"Description\"`",
+		"IsActive bool `json:\"IsActive__c\" sf:\"IsActive__c\"`",
+		"NumberOfEmployees int `json:\"NumberOfEmployees\" sf:\"NumberOfEmployees\"`",
+		"Score float64 `json:\"Score__c\" sf:\"Score__c\"`",
+		"AnnualRevenue gosf.Currency `json:\"AnnualRevenue\" sf:\"AnnualRevenue\"`",
+		"Rank *int `json:\"Rank__c,omitempty\" sf:\"Rank__c\"`",
+		"Since gosf.Date `json:\"Since__c,omitzero\" sf:\"Since__c\"`",
+	} {
+		if !strings.Contains(strings.Join(strings.Fields(string(src)), " "), want) {
+			t.Errorf("missing %s in\n%s", want, src)
+		}
+	}
+}
+
+// describeSales returns descriptions of accounts and contacts refer to
+// each other, and a custom sobject named like the picklist type of
+// Account.Type.
+func describeSales() []*gosf.SobjectDescription {
+	return []*gosf.SobjectDescription{
+		{
+			SobjectMetadata: gosf.SobjectMetadata{Name: "Account", Label: "Account"},
+			Fields: []*gosf.FieldDescription{
+				{Name: "Id", Type: gosf.FieldTypeID},
+				{Name: "Name", Type: gosf.FieldTypeString},
+				{Name: "Type", Type: gosf.FieldTypePicklist, Nillable: true, PicklistValues: []*gosf.PicklistValue{
+					{Value: "Customer - Direct", Active: true},
+					{Value: "Customer Direct", Active: true},
+					{Value: "Prospect", Active: true},
+					{Value: "Retired", Active: false},
+				}},
+				{Name: "Rating", Type: gosf.FieldTypePicklist, PicklistValues: []*gosf.PicklistValue{
+					{Value: "Hot", Active: true},
+				}},
+				{Name: "Industry", Type: gosf.FieldTypePicklist},
+				{Name: "ParentId", Type: gosf.FieldTypeReference, Nillable: true, ReferenceTo: []string{"Account"}, RelationshipName: "Parent"},
+				{Name: "OwnerId", Type: gosf.FieldTypeReference, ReferenceTo: []string{"User"}, RelationshipName: "Owner"},
+				{Name: "WhatId", Type: gosf.FieldTypeReference, ReferenceTo: []string{"Account", "Contact"}, RelationshipName: "What"},
+			},
+			ChildRelationships: []*gosf.ChildRelationship{
+				{ChildSobject: "Contact", Field: "AccountId", RelationshipName: "Contacts"},
+				{ChildSobject: "Account", Field: "ParentId", RelationshipName: "ChildAccounts"},
+				{ChildSobject: "Task", Field: "WhatId", RelationshipName: "Tasks"},
+				{ChildSobject: "Contact", Field: "ReportsToId"},
+			},
+		},
+		{
+			SobjectMetadata: gosf.SobjectMetadata{Name: "Contact", Label: "Contact"},
+			Fields: []*gosf.FieldDescription{
+				{Name: "Id", Type: gosf.FieldTypeID},
+				{Name: "AccountId", Type: gosf.FieldTypeReference, Nillable: true, ReferenceTo: []string{"Account"}, RelationshipName: "Account"},
+				{Name: "Primary_Account__c", Type: gosf.FieldTypeReference, Nillable: true, ReferenceTo: []string{"Account"}, RelationshipName: "Primary_Account__r"},
+			},
+		},
+		{
+			SobjectMetadata: gosf.SobjectMetadata{Name: "AccountType__c", Label: "Account Type"},
+			Fields: []*gosf.FieldDescription{
+				{Name: "Id", Type: gosf.FieldTypeID},
+				{Name: "Name", Type: gosf.FieldTypeString},
+			},
+		},
+	}
+}
+
+// normalized joins the fields of src by single spaces.
+func normalized(src []byte) string {
+	return strings.Join(strings.Fields(string(src)), " ")
+}
+
+func TestGeneratePicklists(t *testing.T) {
+	src, err := generate("sobjects", describeSales())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := normalized(src)
+	for _, want := range []string{
+		"type AccountType struct",
+		"func (AccountType) SobjectName() string { return \"AccountType__c\" }",
+		"// AccountType2 is a value of Account.Type. type AccountType2 string",
+		"Type *AccountType2 `json:\"Type,omitempty\" sf:\"Type\"`",
+		"AccountType2CustomerDirect AccountType2 = \"Customer - Direct\"",
+		"AccountType2CustomerDirect2 AccountType2 = \"Customer Direct\"",
+		"AccountType2Prospect AccountType2 = \"Prospect\"",
+		"Rating AccountRating `json:\"Rating,omitempty\" sf:\"Rating\"`",
+		"AccountRatingHot AccountRating = \"Hot\"",
+		"Industry string `json:\"Industry,omitempty\" sf:\"Industry\"`",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %s in\n%s", want, src)
+		}
+	}
+	if strings.Contains(got, "Retired") {
+		t.Errorf("got the inactive value Retired in\n%s", src)
+	}
+	if strings.Contains(got, "type AccountType__c struct") {
+		t.Errorf("got the sobject type named AccountType__c in\n%s", src)
+	}
+}
+
+func TestGenerateRelationships(t *testing.T) {
+	src, err := generate("sobjects", describeSales())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := normalized(src)
+	for _, want := range []string{
+		"ParentID *gosf.ID `json:\"ParentId,omitempty\" sf:\"ParentId\"`",
+		"Parent *Account `json:\"Parent,omitempty\" sf:\"-\"`",
+		"Contacts []*Contact `json:\"Contacts,omitempty\" sf:\"-\"`",
+		"ChildAccounts []*Account `json:\"ChildAccounts,omitempty\" sf:\"-\"`",
+		"AccountID *gosf.ID `json:\"AccountId,omitempty\" sf:\"AccountId\"`",
+		"Account *Account `json:\"Account,omitempty\" sf:\"-\"`",
+		"PrimaryAccountID *gosf.ID `json:\"Primary_Account__c,omitempty\" sf:\"Primary_Account__c\"`",
+		"PrimaryAccount *Account `json:\"Primary_Account__r,omitempty\" sf:\"-\"`",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %s in\n%s", want, src)
+		}
+	}
+	// User and Task are not generated, What is polymorphic
+	for _, unwanted := range []string{"Owner *", "Tasks []", "What *"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("got %s in\n%s", unwanted, src)
+		}
+	}
+}
+
+func TestGenerateCompiles(t *testing.T) {
+	src, err := generate("sobjects", describeSales())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "sobjects.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err = conf.Check("sobjects", fset, []*ast.File{file}, nil); err != nil {
+		t.Errorf("%v in\n%s", err, src)
+	}
+}
+
+func TestGoName(t *testing.T) {
+	tests := []struct {
+		apiName   string
+		reference bool
+		want      string
+	}{
+		{"Name", false, "Name"},
+		{"Id", false, "ID"},
+		{"Id", true, "ID"},
+		{"AccountId", true, "AccountID"},
+		{"Due_Date__c", false, "DueDate"},
+		{"Parent__c", true, "ParentID"},
+		{"Parent__c", false, "Parent"},
+		{"Parent__r", false, "Parent"},
+		{"External_Id__c", false, "ExternalID"},
+		{"ns__Field__c", false, "NsField"},
+		{"ns__Owner__c", true, "NsOwnerID"},
+		{"Event__e", false, "Event"},
+		{"Setting__mdt", false, "Setting"},
+		{"lower_case", false, "LowerCase"},
+		{"3D_Model__c", false, "X3DModel"},
+		{"__c", false, "X"},
+	}
+	for _, tt := range tests {
+		if got := goName(tt.apiName, tt.reference); got != tt.want {
+			t.Errorf("goName(%q, %v) = %q, want %q", tt.apiName, tt.reference, got, tt.want)
+		}
+	}
+}
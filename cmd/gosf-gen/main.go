@@ -0,0 +1,88 @@
+// Command gosf-gen generates Go structs of sobjects from their describe
+// metadata:
+//
+//	gosf-gen -config gosf.json -package sobjects -out sobjects.go Account Contact Invoice__c
+//
+// The config file is a gosf.Config in json. Each sobject gets a struct with
+// json and sf tags, so it works with OpQuery.SelectStruct and gosf.Query,
+// and a SobjectName method. Fields are typed by their salesforce types:
+// ids and references are gosf.ID, dates are gosf.Date and gosf.DateTime,
+// currencies gosf.Currency, nillable fields pointers, and picklists get
+// their own string types with constants of the active values. Names taken
+// by sobject types or other picklists get a number suffix. Parent and
+// child relationships between the generated sobjects are fields tagged
+// sf:"-", SelectStruct leaves them out and they are decoded if selected by
+// SelectParent or SelectSubquery.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/sidebiequ/gosf"
+)
+
+func main() {
+	configPath := flag.String("config", "gosf.json", "path of the gosf.Config json file")
+	pkg := flag.String("package", "sobjects", "package name of the generated code")
+	out := flag.String("out", "", "output file, stdout if empty")
+	cacheDir := flag.String("cache", "", "directory to cache describe results in")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: gosf-gen [flags] sobject...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*configPath, *pkg, *out, *cacheDir, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "gosf-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath, pkg, out, cacheDir string, sobjects []string) error {
+	if len(sobjects) == 0 {
+		return fmt.Errorf("no sobjects given")
+	}
+
+	byts, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	config := &gosf.Config{}
+	if err = json.Unmarshal(byts, config); err != nil {
+		return fmt.Errorf("parse config %s: %w", configPath, err)
+	}
+	if cacheDir != "" {
+		config.DescribeCache = &gosf.DescribeCache{Store: gosf.NewFileDescribeStore(cacheDir)}
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	client := gosf.NewClient(config, gosf.NewSlogLogger(logger))
+
+	descriptions := make([]*gosf.SobjectDescription, 0, len(sobjects))
+	for _, arg := range sobjects {
+		for _, name := range strings.Split(arg, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			description, err := client.Describe(name)
+			if err != nil {
+				return fmt.Errorf("describe %s: %w", name, err)
+			}
+			descriptions = append(descriptions, description)
+		}
+	}
+
+	src, err := generate(pkg, descriptions)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
@@ -0,0 +1,291 @@
+package gosf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sidebiequ/gosf/soql"
+)
+
+/************************************/
+/************** DATES ***************/
+/************************************/
+
+const (
+	// DateLayout is the format of salesforce dates.
+	DateLayout = "2006-01-02"
+	// DateTimeLayout is the format salesforce returns datetimes in.
+	DateTimeLayout = "2006-01-02T15:04:05.000-0700"
+	// soqlDateTimeLayout is the format of datetimes in SOQL.
+	soqlDateTimeLayout = "2006-01-02T15:04:05Z"
+)
+
+// dateTimeLayouts are the formats DateTime accepts when decoding.
+var dateTimeLayouts = []string{
+	DateTimeLayout,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05.999999999Z0700",
+}
+
+var jsonNull = []byte("null")
+
+// Date is a salesforce date without time and time zone, like 2024-01-31.
+// The zero Date is encoded as null in json and as empty text.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// NewDate returns the Date of year, month and day.
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{Year: year, Month: month, Day: day}
+}
+
+// DateOf returns the Date of t in its location.
+func DateOf(t time.Time) Date {
+	year, month, day := t.Date()
+	return NewDate(year, month, day)
+}
+
+// ParseDate parses a date in the format of 2006-01-02.
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return Date{}, err
+	}
+	return DateOf(t), nil
+}
+
+// String returns the date in the format of 2006-01-02.
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// IsZero returns true if d is the zero Date.
+func (d Date) IsZero() bool {
+	return d == Date{}
+}
+
+// In returns the time of the start of d in loc.
+func (d Date) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// MarshalText implements encoding.TextMarshaler, the zero Date is empty.
+func (d Date) MarshalText() ([]byte, error) {
+	if d.IsZero() {
+		return []byte{}, nil
+	}
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, empty text is the
+// zero Date.
+func (d *Date) UnmarshalText(text []byte) (err error) {
+	if len(text) == 0 {
+		*d = Date{}
+		return nil
+	}
+	*d, err = ParseDate(string(text))
+	return
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return jsonNull, nil
+	}
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, jsonNull) {
+		*d = Date{}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// DateTime is a salesforce datetime, it's encoded in the format salesforce
+// returns, like 2024-01-31T08:30:00.000+0000. The zero DateTime is encoded
+// as null in json and as empty text.
+type DateTime struct {
+	time.Time
+}
+
+// ParseDateTime parses a datetime in the formats salesforce uses.
+func ParseDateTime(s string) (DateTime, error) {
+	var err error
+	for _, layout := range dateTimeLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			return DateTime{Time: t}, nil
+		}
+	}
+	return DateTime{}, err
+}
+
+// String returns the datetime in the format salesforce returns.
+func (dt DateTime) String() string {
+	return dt.Time.Format(DateTimeLayout)
+}
+
+// MarshalText implements encoding.TextMarshaler, the zero DateTime is
+// empty.
+func (dt DateTime) MarshalText() ([]byte, error) {
+	if dt.IsZero() {
+		return []byte{}, nil
+	}
+	return []byte(dt.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, empty text is the
+// zero DateTime.
+func (dt *DateTime) UnmarshalText(text []byte) (err error) {
+	if len(text) == 0 {
+		*dt = DateTime{}
+		return nil
+	}
+	*dt, err = ParseDateTime(string(text))
+	return
+}
+
+// MarshalJSON implements json.Marshaler.
+func (dt DateTime) MarshalJSON() ([]byte, error) {
+	if dt.IsZero() {
+		return jsonNull, nil
+	}
+	return json.Marshal(dt.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (dt *DateTime) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, jsonNull) {
+		*dt = DateTime{}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return dt.UnmarshalText([]byte(s))
+}
+
+/************************************/
+/********** DATE LITERALS ***********/
+/************************************/
+
+// DateLiteral is a SOQL date literal, a range of dates relative to the
+// current day, like TODAY or LAST_N_DAYS:30. Comparing a field with it by
+// = matches the dates in the range, < and > match the dates before and
+// after the range.
+type DateLiteral string
+
+// IsValid returns true if l is a known date literal, n of the N forms
+// like LAST_N_DAYS:n must not be negative.
+func (l DateLiteral) IsValid() bool {
+	return soql.IsDateLiteral(string(l))
+}
+
+// Date literals of SOQL.
+const (
+	Yesterday         DateLiteral = "YESTERDAY"
+	Today             DateLiteral = "TODAY"
+	Tomorrow          DateLiteral = "TOMORROW"
+	LastWeek          DateLiteral = "LAST_WEEK"
+	ThisWeek          DateLiteral = "THIS_WEEK"
+	NextWeek          DateLiteral = "NEXT_WEEK"
+	LastMonth         DateLiteral = "LAST_MONTH"
+	ThisMonth         DateLiteral = "THIS_MONTH"
+	NextMonth         DateLiteral = "NEXT_MONTH"
+	Last90Days        DateLiteral = "LAST_90_DAYS"
+	Next90Days        DateLiteral = "NEXT_90_DAYS"
+	LastQuarter       DateLiteral = "LAST_QUARTER"
+	ThisQuarter       DateLiteral = "THIS_QUARTER"
+	NextQuarter       DateLiteral = "NEXT_QUARTER"
+	LastYear          DateLiteral = "LAST_YEAR"
+	ThisYear          DateLiteral = "THIS_YEAR"
+	NextYear          DateLiteral = "NEXT_YEAR"
+	LastFiscalQuarter DateLiteral = "LAST_FISCAL_QUARTER"
+	ThisFiscalQuarter DateLiteral = "THIS_FISCAL_QUARTER"
+	NextFiscalQuarter DateLiteral = "NEXT_FISCAL_QUARTER"
+	LastFiscalYear    DateLiteral = "LAST_FISCAL_YEAR"
+	ThisFiscalYear    DateLiteral = "THIS_FISCAL_YEAR"
+	NextFiscalYear    DateLiteral = "NEXT_FISCAL_YEAR"
+)
+
+func nDateLiteral(name string, n int) DateLiteral {
+	return DateLiteral(fmt.Sprintf("%s:%d", name, n))
+}
+
+// LastNDays is LAST_N_DAYS:n, today and the n days before.
+func LastNDays(n int) DateLiteral { return nDateLiteral("LAST_N_DAYS", n) }
+
+// NextNDays is NEXT_N_DAYS:n, the n days after today.
+func NextNDays(n int) DateLiteral { return nDateLiteral("NEXT_N_DAYS", n) }
+
+// NDaysAgo is N_DAYS_AGO:n, the day n days before today.
+func NDaysAgo(n int) DateLiteral { return nDateLiteral("N_DAYS_AGO", n) }
+
+// LastNWeeks is LAST_N_WEEKS:n, the n weeks before this week.
+func LastNWeeks(n int) DateLiteral { return nDateLiteral("LAST_N_WEEKS", n) }
+
+// NextNWeeks is NEXT_N_WEEKS:n, the n weeks after this week.
+func NextNWeeks(n int) DateLiteral { return nDateLiteral("NEXT_N_WEEKS", n) }
+
+// NWeeksAgo is N_WEEKS_AGO:n, the week n weeks before this week.
+func NWeeksAgo(n int) DateLiteral { return nDateLiteral("N_WEEKS_AGO", n) }
+
+// LastNMonths is LAST_N_MONTHS:n, the n months before this month.
+func LastNMonths(n int) DateLiteral { return nDateLiteral("LAST_N_MONTHS", n) }
+
+// NextNMonths is NEXT_N_MONTHS:n, the n months after this month.
+func NextNMonths(n int) DateLiteral { return nDateLiteral("NEXT_N_MONTHS", n) }
+
+// NMonthsAgo is N_MONTHS_AGO:n, the month n months before this month.
+func NMonthsAgo(n int) DateLiteral { return nDateLiteral("N_MONTHS_AGO", n) }
+
+// LastNQuarters is LAST_N_QUARTERS:n, the n quarters before this quarter.
+func LastNQuarters(n int) DateLiteral { return nDateLiteral("LAST_N_QUARTERS", n) }
+
+// NextNQuarters is NEXT_N_QUARTERS:n, the n quarters after this quarter.
+func NextNQuarters(n int) DateLiteral { return nDateLiteral("NEXT_N_QUARTERS", n) }
+
+// NQuartersAgo is N_QUARTERS_AGO:n, the quarter n quarters before this quarter.
+func NQuartersAgo(n int) DateLiteral { return nDateLiteral("N_QUARTERS_AGO", n) }
+
+// LastNYears is LAST_N_YEARS:n, the n years before this year.
+func LastNYears(n int) DateLiteral { return nDateLiteral("LAST_N_YEARS", n) }
+
+// NextNYears is NEXT_N_YEARS:n, the n years after this year.
+func NextNYears(n int) DateLiteral { return nDateLiteral("NEXT_N_YEARS", n) }
+
+// NYearsAgo is N_YEARS_AGO:n, the year n years before this year.
+func NYearsAgo(n int) DateLiteral { return nDateLiteral("N_YEARS_AGO", n) }
+
+// LastNFiscalQuarters is LAST_N_FISCAL_QUARTERS:n.
+func LastNFiscalQuarters(n int) DateLiteral { return nDateLiteral("LAST_N_FISCAL_QUARTERS", n) }
+
+// NextNFiscalQuarters is NEXT_N_FISCAL_QUARTERS:n.
+func NextNFiscalQuarters(n int) DateLiteral { return nDateLiteral("NEXT_N_FISCAL_QUARTERS", n) }
+
+// NFiscalQuartersAgo is N_FISCAL_QUARTERS_AGO:n.
+func NFiscalQuartersAgo(n int) DateLiteral { return nDateLiteral("N_FISCAL_QUARTERS_AGO", n) }
+
+// LastNFiscalYears is LAST_N_FISCAL_YEARS:n.
+func LastNFiscalYears(n int) DateLiteral { return nDateLiteral("LAST_N_FISCAL_YEARS", n) }
+
+// NextNFiscalYears is NEXT_N_FISCAL_YEARS:n.
+func NextNFiscalYears(n int) DateLiteral { return nDateLiteral("NEXT_N_FISCAL_YEARS", n) }
+
+// NFiscalYearsAgo is N_FISCAL_YEARS_AGO:n.
+func NFiscalYearsAgo(n int) DateLiteral { return nDateLiteral("N_FISCAL_YEARS_AGO", n) }
@@ -0,0 +1,82 @@
+package gosf_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/sidebiequ/gosf"
+)
+
+func TestDateEncoding(t *testing.T) {
+	type dates struct {
+		Date     gosf.Date     `json:"date"`
+		DateTime gosf.DateTime `json:"datetime"`
+	}
+	tests := []struct {
+		name     string
+		date     gosf.Date
+		dateTime gosf.DateTime
+		text     string
+		json     string
+	}{
+		{"zero", gosf.Date{}, gosf.DateTime{}, "", `{"date":null,"datetime":null}`},
+		{
+			"date",
+			gosf.NewDate(2024, time.January, 31),
+			gosf.DateTime{Time: time.Date(2024, time.January, 31, 8, 30, 0, 0, time.UTC)},
+			"2024-01-31",
+			`{"date":"2024-01-31","datetime":"2024-01-31T08:30:00.000+0000"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := tt.date.MarshalText()
+			if err != nil || string(text) != tt.text {
+				t.Fatalf("got text %q, %v, want %q", text, err, tt.text)
+			}
+			var date gosf.Date
+			if err = date.UnmarshalText(text); err != nil || date != tt.date {
+				t.Errorf("got %v, %v, want %v", date, err, tt.date)
+			}
+			text, err = tt.dateTime.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var dateTime gosf.DateTime
+			if err = dateTime.UnmarshalText(text); err != nil || !dateTime.Equal(tt.dateTime.Time) {
+				t.Errorf("got %v, %v, want %v", dateTime, err, tt.dateTime)
+			}
+
+			byts, err := json.Marshal(dates{tt.date, tt.dateTime})
+			if err != nil || string(byts) != tt.json {
+				t.Fatalf("got json %s, %v, want %s", byts, err, tt.json)
+			}
+			var decoded dates
+			if err = json.Unmarshal(byts, &decoded); err != nil || decoded.Date != tt.date || !decoded.DateTime.Equal(tt.dateTime.Time) {
+				t.Errorf("got %+v, %v", decoded, err)
+			}
+		})
+	}
+}
+
+func TestDateLiteralIsValid(t *testing.T) {
+	tests := []struct {
+		literal gosf.DateLiteral
+		want    bool
+	}{
+		{gosf.Today, true},
+		{gosf.ThisFiscalQuarter, true},
+		{gosf.LastNDays(30), true},
+		{gosf.NFiscalYearsAgo(2), true},
+		{gosf.LastNDays(-1), false},
+		{"LAST_N_DAYS", false},
+		{"TODAY:1", false},
+		{"TODAY OR Name != null", false},
+	}
+	for _, tt := range tests {
+		if got := tt.literal.IsValid(); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.literal, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,609 @@
+package gosf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+/************************************/
+/********* RECORD DECODING **********/
+/************************************/
+
+// relationships are the child relationships of records by lower-cased
+// name, each with the child relationships of its records. They tell the
+// child relationship results from the other objects of records decoded
+// into maps, records decoded into structs don't need them.
+type relationships map[string]relationships
+
+// relationships returns the child relationships of the records op queries.
+func (op *OpQuery) relationships() relationships {
+	if len(op.subqueries) == 0 {
+		return nil
+	}
+	rels := make(relationships, len(op.subqueries))
+	for _, sub := range op.subqueries {
+		rels[strings.ToLower(sub.sobjectName)] = sub.relationships()
+	}
+	return rels
+}
+
+// recordDecoder decodes records from a json stream into Go values, each
+// json value is read once:
+//   - the attributes envelope is skipped, unless it's decoded into a
+//     struct field of it, or keepAttributes for maps
+//   - child relationship results
+//     {"totalSize": 1, "done": true, "records": [...]}
+//     are decoded as their records into slices and relationships of maps
+//   - other values are decoded by encoding/json rules
+type recordDecoder struct {
+	// keepAttributes keeps the attributes of records decoded into maps.
+	keepAttributes bool
+	// deleted receives IsDeleted of the next record decoded, if it's set.
+	deleted *bool
+	// registry decodes Polymorphic fields, they decode themselves by
+	// DefaultTypeRegistry if it's nil.
+	registry *TypeRegistry
+}
+
+// decodeRecord decodes a record or records in json into target, the
+// records have the child relationships rels.
+func decodeRecord(raw []byte, target interface{}, rels relationships) error {
+	return (&recordDecoder{}).unmarshal(raw, target, rels)
+}
+
+// unmarshal decodes raw into target like json.Unmarshal.
+func (d *recordDecoder) unmarshal(raw []byte, target interface{}, rels relationships) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return &json.InvalidUnmarshalError{Type: reflect.TypeOf(target)}
+	}
+	return d.decode(json.NewDecoder(bytes.NewReader(raw)), v.Elem(), rels)
+}
+
+// decode decodes the next json value of dec into v, which is addressable.
+func (d *recordDecoder) decode(dec *json.Decoder, v reflect.Value, rels relationships) error {
+	if d.registry != nil && (v.Type() == polymorphicType || v.Type() == reflect.PointerTo(polymorphicType)) {
+		return d.decodePolymorphic(dec, v)
+	}
+	if decodesItself(v.Type()) {
+		if d.deleted != nil {
+			return d.decodeItself(dec, v)
+		}
+		return dec.Decode(v.Addr().Interface())
+	}
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	return d.decodeToken(dec, tok, v, rels)
+}
+
+// decodeItself decodes a record into v by encoding/json and reports its
+// IsDeleted.
+func (d *recordDecoder) decodeItself(dec *json.Decoder, v reflect.Value) error {
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, v.Addr().Interface()); err != nil {
+		return err
+	}
+	deleted := d.takeDeleted()
+	if firstByte(raw) != '{' {
+		return nil
+	}
+	var flags struct {
+		IsDeleted bool
+	}
+	if err := json.Unmarshal(raw, &flags); err != nil {
+		return err
+	}
+	*deleted = flags.IsDeleted
+	return nil
+}
+
+// decodePolymorphic decodes a related record into v, a Polymorphic or a
+// pointer to it, by the registry of d.
+func (d *recordDecoder) decodePolymorphic(dec *json.Decoder, v reflect.Value) error {
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	if v.Kind() == reflect.Ptr {
+		if firstByte(raw) != '{' {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(polymorphicType))
+		}
+		v = v.Elem()
+	}
+	return v.Addr().Interface().(*Polymorphic).decode(raw, d.registry)
+}
+
+// decodeToken decodes the json value started by tok, which is read from
+// dec, into v.
+func (d *recordDecoder) decodeToken(dec *json.Decoder, tok json.Token, v reflect.Value, rels relationships) error {
+	t := v.Type()
+	if _, delim := tok.(json.Delim); !delim && decodesItself(t) {
+		// a literal read ahead, encode it back for encoding/json
+		byts, err := json.Marshal(tok)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(byts, v.Addr().Interface())
+	}
+	if tok == nil {
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(t))
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return d.decodeToken(dec, tok, v.Elem(), rels)
+	case reflect.Interface:
+		value, err := d.decodeAny(dec, tok, rels)
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			v.Set(reflect.Zero(t))
+		} else {
+			v.Set(reflect.ValueOf(value))
+		}
+		return nil
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		switch v.Kind() {
+		case reflect.Struct:
+			return d.decodeStruct(dec, v, rels)
+		case reflect.Map:
+			return d.decodeMap(dec, v, rels)
+		case reflect.Slice:
+			return d.decodeChildResult(dec, v, rels)
+		}
+	case json.Delim('['):
+		if v.Kind() == reflect.Slice {
+			return d.decodeSlice(dec, v, rels)
+		}
+	}
+	return &json.UnmarshalTypeError{Value: tokenKind(tok), Type: t, Offset: dec.InputOffset()}
+}
+
+// decodeAny decodes the json value started by tok like encoding/json
+// decodes it into interface{}.
+func (d *recordDecoder) decodeAny(dec *json.Decoder, tok json.Token, rels relationships) (interface{}, error) {
+	switch tok {
+	case json.Delim('{'):
+		fields := make(map[string]interface{})
+		if err := d.decodeMap(dec, reflect.ValueOf(fields), rels); err != nil {
+			return nil, err
+		}
+		return fields, nil
+	case json.Delim('['):
+		var elems []interface{}
+		if err := d.decodeSlice(dec, reflect.ValueOf(&elems).Elem(), rels); err != nil {
+			return nil, err
+		}
+		return elems, nil
+	default:
+		return tok, nil
+	}
+}
+
+// decodeStruct decodes the fields of an object, whose '{' is read, into
+// struct v.
+func (d *recordDecoder) decodeStruct(dec *json.Decoder, v reflect.Value, rels relationships) error {
+	deleted := d.takeDeleted()
+	fields := fieldsOf(v.Type())
+	for dec.More() {
+		key, err := objectKey(dec)
+		if err != nil {
+			return err
+		}
+		field, ok := fields.lookup(key).value(v)
+		switch {
+		case deleted != nil && strings.EqualFold(key, "IsDeleted"):
+			err = d.decodeDeleted(dec, field, ok, deleted)
+		case !ok:
+			err = skipValue(dec)
+		default:
+			err = d.decodeField(dec, field, key, rels)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// decodeMap decodes the fields of an object, whose '{' is read, into map
+// v with string keys.
+func (d *recordDecoder) decodeMap(dec *json.Decoder, v reflect.Value, rels relationships) error {
+	deleted := d.takeDeleted()
+	t := v.Type()
+	if t.Key().Kind() != reflect.String {
+		return &json.UnmarshalTypeError{Value: "object", Type: t, Offset: dec.InputOffset()}
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(t))
+	}
+	for dec.More() {
+		key, err := objectKey(dec)
+		if err != nil {
+			return err
+		}
+		if key == "attributes" && !d.keepAttributes {
+			if err = skipValue(dec); err != nil {
+				return err
+			}
+			continue
+		}
+
+		elem := reflect.New(t.Elem()).Elem()
+		if deleted != nil && strings.EqualFold(key, "IsDeleted") {
+			err = d.decodeDeleted(dec, elem, true, deleted)
+		} else {
+			err = d.decodeField(dec, elem, key, rels)
+		}
+		if err != nil {
+			return err
+		}
+		v.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), elem)
+	}
+	return expectDelim(dec, '}')
+}
+
+// decodeField decodes the value of field key of a record into v, it's a
+// child relationship result if key is one of rels.
+func (d *recordDecoder) decodeField(dec *json.Decoder, v reflect.Value, key string, rels relationships) error {
+	children, isChild := rels[strings.ToLower(key)]
+	if !isChild || v.Kind() != reflect.Interface || v.NumMethod() > 0 {
+		// parent relationships are records without child relationships,
+		// slices tell child relationship results by themselves
+		return d.decode(dec, v, children)
+	}
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == json.Delim('{') {
+		return d.decodeChildResult(dec, v, children)
+	}
+	return d.decodeToken(dec, tok, v, children)
+}
+
+// decodeChildResult decodes the records of a child relationship result,
+// whose '{' is read, into v.
+func (d *recordDecoder) decodeChildResult(dec *json.Decoder, v reflect.Value, rels relationships) error {
+	for dec.More() {
+		key, err := objectKey(dec)
+		if err != nil {
+			return err
+		}
+		if key == "records" {
+			err = d.decode(dec, v, rels)
+		} else {
+			err = skipValue(dec)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// decodeSlice decodes the elements of an array, whose '[' is read, into
+// slice v.
+func (d *recordDecoder) decodeSlice(dec *json.Decoder, v reflect.Value, rels relationships) error {
+	i := 0
+	for ; dec.More(); i++ {
+		if i >= v.Len() {
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+		}
+		if err := d.decode(dec, v.Index(i), rels); err != nil {
+			return err
+		}
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+	v.SetLen(i)
+	return expectDelim(dec, ']')
+}
+
+// decodeDeleted decodes IsDeleted of a record into v if ok, and reports it
+// to deleted.
+func (d *recordDecoder) decodeDeleted(dec *json.Decoder, v reflect.Value, ok bool, deleted *bool) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	*deleted, _ = tok.(bool)
+	if !ok {
+		return nil
+	}
+	return d.decodeToken(dec, tok, v, nil)
+}
+
+// takeDeleted returns where to report IsDeleted of the record being
+// decoded, the records in it don't report.
+func (d *recordDecoder) takeDeleted() *bool {
+	deleted := d.deleted
+	d.deleted = nil
+	return deleted
+}
+
+/************************************/
+/*********** RECORD FIELDS **********/
+/************************************/
+
+var (
+	polymorphicType = reflect.TypeOf(Polymorphic{})
+	// decodesItselfCache caches decodesItself by type.
+	decodesItselfCache sync.Map
+	// fieldsCache caches fieldsOf by type.
+	fieldsCache sync.Map
+)
+
+// decodesItself returns true if values of t hold no records, so they're
+// decoded by encoding/json as they're.
+func decodesItself(t reflect.Type) bool {
+	if itself, ok := decodesItselfCache.Load(t); ok {
+		return itself.(bool)
+	}
+	itself := typeDecodesItself(t, make(map[reflect.Type]bool))
+	decodesItselfCache.Store(t, itself)
+	return itself
+}
+
+func typeDecodesItself(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if visiting[t] {
+		// a recursive type holds records by the other kinds
+		return false
+	}
+	visiting[t] = true
+
+	pt := reflect.PointerTo(t)
+	if pt.Implements(jsonUnmarshalerType) || pt.Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		return false
+	case reflect.Interface:
+		return t.NumMethod() > 0
+	case reflect.Ptr, reflect.Slice:
+		return typeDecodesItself(t.Elem(), visiting)
+	case reflect.Map:
+		return t.Key().Kind() != reflect.String || typeDecodesItself(t.Elem(), visiting)
+	default:
+		return true
+	}
+}
+
+// recordField is a field of a struct records decode into.
+type recordField struct {
+	name  string
+	index []int
+}
+
+// recordFields are the fields of a struct by the names they're decoded
+// from: the names in `sf` tags, or the ones of encoding/json rules if there
+// are none. Embedded structs are followed like encoding/json does.
+type recordFields struct {
+	byName map[string]*recordField
+	list   []*recordField
+}
+
+// lookup returns the field of key, matched exactly or case insensitively.
+func (fs *recordFields) lookup(key string) *recordField {
+	if f, ok := fs.byName[key]; ok {
+		return f
+	}
+	for _, f := range fs.list {
+		if strings.EqualFold(f.name, key) {
+			return f
+		}
+	}
+	return nil
+}
+
+// value returns the field of struct v, embedded pointers on the way are
+// allocated. ok is false if f is nil or the field can't be set.
+func (f *recordField) value(v reflect.Value) (field reflect.Value, ok bool) {
+	if f == nil {
+		return
+	}
+	for i, x := range f.index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, v.CanSet()
+}
+
+// fieldsOf returns the recordFields of struct type t.
+func fieldsOf(t reflect.Type) *recordFields {
+	if fields, ok := fieldsCache.Load(t); ok {
+		return fields.(*recordFields)
+	}
+
+	type candidate struct {
+		recordField
+		depth  int
+		tagged bool
+	}
+	byName := make(map[string][]*candidate)
+	var names []string
+	var walk func(t reflect.Type, index []int, path map[reflect.Type]bool)
+	walk = func(t reflect.Type, index []int, path map[reflect.Type]bool) {
+		if path[t] {
+			return
+		}
+		path[t] = true
+		defer delete(path, t)
+
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := tagName(f)
+			if name == "" || name == "-" {
+				tag := f.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, _, _ = strings.Cut(tag, ",")
+			}
+			fieldIndex := append(index[:len(index):len(index)], i)
+
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				walk(ft, fieldIndex, path)
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+
+			c := &candidate{depth: len(index), tagged: name != ""}
+			if name == "" {
+				name = f.Name
+			}
+			c.recordField = recordField{name: name, index: fieldIndex}
+			if byName[name] == nil {
+				names = append(names, name)
+			}
+			byName[name] = append(byName[name], c)
+		}
+	}
+	walk(t, nil, make(map[reflect.Type]bool))
+
+	fields := &recordFields{byName: make(map[string]*recordField)}
+	for _, name := range names {
+		// the shallowest field wins, then the tagged one, others are ambiguous
+		var dominant []*candidate
+		for _, c := range byName[name] {
+			switch {
+			case len(dominant) == 0 || c.depth < dominant[0].depth:
+				dominant = []*candidate{c}
+			case c.depth == dominant[0].depth:
+				dominant = append(dominant, c)
+			}
+		}
+		if len(dominant) > 1 {
+			var tagged []*candidate
+			for _, c := range dominant {
+				if c.tagged {
+					tagged = append(tagged, c)
+				}
+			}
+			dominant = tagged
+		}
+		if len(dominant) == 1 {
+			f := &dominant[0].recordField
+			fields.byName[name] = f
+			fields.list = append(fields.list, f)
+		}
+	}
+	actual, _ := fieldsCache.LoadOrStore(t, fields)
+	return actual.(*recordFields)
+}
+
+/************************************/
+/************ JSON TOKENS ***********/
+/************************************/
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	t, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if t != delim {
+		return fmt.Errorf("unexpected json token %v, expect %v", t, delim)
+	}
+	return nil
+}
+
+// objectKey reads the next key of an object.
+func objectKey(dec *json.Decoder) (string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected json token %v, expect object key", tok)
+	}
+	return key, nil
+}
+
+// skipValue reads the next json value of dec and drops it.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// tokenKind returns the kind of json value tok starts, in the words of
+// json.UnmarshalTypeError.
+func tokenKind(tok json.Token) string {
+	switch tok.(type) {
+	case json.Delim:
+		if tok == json.Delim('[') {
+			return "array"
+		}
+		return "object"
+	case bool:
+		return "bool"
+	case float64, json.Number:
+		return "number"
+	case string:
+		return "string"
+	default:
+		return "null"
+	}
+}
+
+func firstByte(raw json.RawMessage) byte {
+	trimmed := bytes.TrimLeft(raw, " \t\r\n")
+	if len(trimmed) == 0 {
+		return 0
+	}
+	return trimmed[0]
+}
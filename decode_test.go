@@ -0,0 +1,172 @@
+package gosf
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type decodeOwner struct {
+	Name string
+}
+
+type decodeContact struct {
+	Email string
+}
+
+type decodeBase struct {
+	ID string `json:"Id"`
+}
+
+type decodeAccount struct {
+	decodeBase
+	Attributes *Attributes `json:"attributes"`
+	Name       string
+	Owner      *decodeOwner
+	Contacts   []decodeContact
+	Revenue    json.Number
+	Internal   string `json:"-"`
+}
+
+func TestDecodeRecord(t *testing.T) {
+	contacts := relationships{"contacts": nil}
+	tests := []struct {
+		name   string
+		raw    string
+		target interface{}
+		rels   relationships
+		want   interface{}
+	}{
+		{
+			"struct",
+			`{"attributes":{"type":"Account","url":"/a/001"},"Id":"001","name":"Acme","Internal":"x",
+				"Owner":{"attributes":{"type":"User"},"Name":"Boss"},
+				"Contacts":{"totalSize":1,"done":true,"records":[{"attributes":{"type":"Contact"},"Email":"a@b.c"}]},
+				"Revenue":12345678901234567890}`,
+			&decodeAccount{},
+			nil,
+			&decodeAccount{
+				decodeBase: decodeBase{ID: "001"},
+				Attributes: &Attributes{Type: "Account", URL: "/a/001"},
+				Name:       "Acme",
+				Owner:      &decodeOwner{Name: "Boss"},
+				Contacts:   []decodeContact{{Email: "a@b.c"}},
+				Revenue:    "12345678901234567890",
+			},
+		},
+		{
+			"struct without children",
+			`{"Name":"Acme","Owner":null,"Contacts":null}`,
+			&decodeAccount{},
+			nil,
+			&decodeAccount{Name: "Acme"},
+		},
+		{
+			"records into slice",
+			`[{"attributes":{"type":"User"},"Name":"A"},{"Name":"B"}]`,
+			&[]*decodeOwner{},
+			nil,
+			&[]*decodeOwner{{Name: "A"}, {Name: "B"}},
+		},
+		{
+			"map of relationships",
+			`{"attributes":{"type":"Account"},"Name":"Acme",
+				"Owner":{"attributes":{"type":"User"},"Name":"Boss"},
+				"Contacts":{"totalSize":1,"done":true,"records":[{"attributes":{"type":"Contact"},"Email":"a@b.c"}]},
+				"Stats":{"totalSize":1,"done":true,"records":[]}}`,
+			&map[string]interface{}{},
+			contacts,
+			&map[string]interface{}{
+				"Name":     "Acme",
+				"Owner":    map[string]interface{}{"Name": "Boss"},
+				"Contacts": []interface{}{map[string]interface{}{"Email": "a@b.c"}},
+				"Stats":    map[string]interface{}{"totalSize": 1.0, "done": true, "records": []interface{}{}},
+			},
+		},
+		{
+			"map without relationships",
+			`{"Name":"Acme","Contacts":{"totalSize":0,"done":true,"records":[]}}`,
+			&map[string]interface{}{},
+			nil,
+			&map[string]interface{}{
+				"Name":     "Acme",
+				"Contacts": map[string]interface{}{"totalSize": 0.0, "done": true, "records": []interface{}{}},
+			},
+		},
+		{
+			"interface",
+			`[{"attributes":{"type":"Account"},"Contacts":null}]`,
+			new(interface{}),
+			contacts,
+			func() *interface{} {
+				var v interface{} = []interface{}{map[string]interface{}{"Contacts": nil}}
+				return &v
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := decodeRecord([]byte(tt.raw), tt.target, tt.rels); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tt.target, tt.want) {
+				t.Errorf("got  %#v\nwant %#v", tt.target, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeRecordErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		target interface{}
+		want   string
+	}{
+		{"not a pointer", `{}`, decodeAccount{}, "non-pointer"},
+		{"object into string", `{"Name":{}}`, &decodeAccount{}, "cannot unmarshal object"},
+		{"string into struct", `{"Owner":"Boss"}`, &decodeAccount{}, "cannot unmarshal string"},
+		{"truncated", `{"Name":"Acme"`, &decodeAccount{}, "unexpected end"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := decodeRecord([]byte(tt.raw), tt.target, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v, want error of %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeRecordDeleted(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		target interface{}
+		want   bool
+	}{
+		{"struct without field", `{"Name":"A","IsDeleted":true}`, &decodeOwner{}, true},
+		{"struct with field", `{"IsDeleted":true}`, &struct{ IsDeleted bool }{}, true},
+		{"map", `{"isdeleted":true}`, &map[string]interface{}{}, true},
+		{"decodes itself", `{"IsDeleted":true}`, &json.RawMessage{}, true},
+		{"not deleted", `{"IsDeleted":false}`, &decodeOwner{}, false},
+		{"child not reported", `{"Contacts":{"records":[{"IsDeleted":true}]}}`, &decodeAccount{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deleted bool
+			d := &recordDecoder{deleted: &deleted}
+			if err := d.unmarshal([]byte(tt.raw), tt.target, nil); err != nil {
+				t.Fatal(err)
+			}
+			if deleted != tt.want {
+				t.Errorf("got deleted %v, want %v", deleted, tt.want)
+			}
+		})
+	}
+	target := &struct{ IsDeleted bool }{}
+	if err := (&recordDecoder{deleted: new(bool)}).unmarshal([]byte(`{"IsDeleted":true}`), target, nil); err != nil || !target.IsDeleted {
+		t.Errorf("got %+v, %v, want IsDeleted decoded", target, err)
+	}
+}
@@ -0,0 +1,434 @@
+package gosf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/sidebiequ/gosf/soql"
+)
+
+/************************************/
+/************* DESCRIBE *************/
+/************************************/
+
+// FieldType is the type of a sobject field in describe results.
+type FieldType string
+
+// Field types of salesforce.
+const (
+	FieldTypeID              FieldType = "id"
+	FieldTypeBoolean         FieldType = "boolean"
+	FieldTypeString          FieldType = "string"
+	FieldTypeTextArea        FieldType = "textarea"
+	FieldTypeEncryptedString FieldType = "encryptedstring"
+	FieldTypePicklist        FieldType = "picklist"
+	FieldTypeMultiPicklist   FieldType = "multipicklist"
+	FieldTypeCombobox        FieldType = "combobox"
+	FieldTypeReference       FieldType = "reference"
+	FieldTypeCurrency        FieldType = "currency"
+	FieldTypeDouble          FieldType = "double"
+	FieldTypePercent         FieldType = "percent"
+	FieldTypeInt             FieldType = "int"
+	FieldTypeLong            FieldType = "long"
+	FieldTypeDate            FieldType = "date"
+	FieldTypeDateTime        FieldType = "datetime"
+	FieldTypeTime            FieldType = "time"
+	FieldTypeEmail           FieldType = "email"
+	FieldTypePhone           FieldType = "phone"
+	FieldTypeURL             FieldType = "url"
+	FieldTypeBase64          FieldType = "base64"
+	FieldTypeAddress         FieldType = "address"
+	FieldTypeLocation        FieldType = "location"
+	FieldTypeAnyType         FieldType = "anyType"
+)
+
+// Currency is the amount of a currency field, in the currency of the
+// record, which is CurrencyIsoCode in multi-currency orgs.
+type Currency float64
+
+type (
+	// DescribeGlobalResult lists the sobjects available in the org.
+	DescribeGlobalResult struct {
+		Encoding     string             `json:"encoding"`
+		MaxBatchSize int                `json:"maxBatchSize"`
+		Sobjects     []*SobjectMetadata `json:"sobjects"`
+	}
+
+	// SobjectMetadata is the basic metadata of a sobject, what it is and
+	// what can be done with it. URLs are keyed by names like "sobject",
+	// "describe" and "rowTemplate".
+	SobjectMetadata struct {
+		Name                string            `json:"name"`
+		Label               string            `json:"label"`
+		LabelPlural         string            `json:"labelPlural"`
+		KeyPrefix           string            `json:"keyPrefix"`
+		Custom              bool              `json:"custom"`
+		CustomSetting       bool              `json:"customSetting"`
+		Activateable        bool              `json:"activateable"`
+		Createable          bool              `json:"createable"`
+		Updateable          bool              `json:"updateable"`
+		Deletable           bool              `json:"deletable"`
+		Undeletable         bool              `json:"undeletable"`
+		Mergeable           bool              `json:"mergeable"`
+		Queryable           bool              `json:"queryable"`
+		Retrieveable        bool              `json:"retrieveable"`
+		Searchable          bool              `json:"searchable"`
+		Replicateable       bool              `json:"replicateable"`
+		Triggerable         bool              `json:"triggerable"`
+		Layoutable          bool              `json:"layoutable"`
+		FeedEnabled         bool              `json:"feedEnabled"`
+		DeprecatedAndHidden bool              `json:"deprecatedAndHidden"`
+		URLs                map[string]string `json:"urls"`
+	}
+
+	// SobjectDescription is the full metadata of a sobject.
+	SobjectDescription struct {
+		SobjectMetadata
+		Fields             []*FieldDescription  `json:"fields"`
+		ChildRelationships []*ChildRelationship `json:"childRelationships"`
+		RecordTypeInfos    []*RecordTypeInfo    `json:"recordTypeInfos"`
+	}
+
+	// FieldDescription is the metadata of a field. ReferenceTo and
+	// RelationshipName are set for reference fields, ReferenceTo has more
+	// than one sobject if the relationship is polymorphic.
+	FieldDescription struct {
+		Name              string           `json:"name"`
+		Label             string           `json:"label"`
+		Type              FieldType        `json:"type"`
+		SoapType          string           `json:"soapType"`
+		Length            int              `json:"length"`
+		ByteLength        int              `json:"byteLength"`
+		Precision         int              `json:"precision"`
+		Scale             int              `json:"scale"`
+		Digits            int              `json:"digits"`
+		Nillable          bool             `json:"nillable"`
+		Createable        bool             `json:"createable"`
+		Updateable        bool             `json:"updateable"`
+		Filterable        bool             `json:"filterable"`
+		Sortable          bool             `json:"sortable"`
+		Groupable         bool             `json:"groupable"`
+		Unique            bool             `json:"unique"`
+		ExternalID        bool             `json:"externalId"`
+		IDLookup          bool             `json:"idLookup"`
+		NameField         bool             `json:"nameField"`
+		Custom            bool             `json:"custom"`
+		Calculated        bool             `json:"calculated"`
+		DefaultedOnCreate bool             `json:"defaultedOnCreate"`
+		PicklistValues    []*PicklistValue `json:"picklistValues"`
+		ReferenceTo       []string         `json:"referenceTo"`
+		RelationshipName  string           `json:"relationshipName"`
+	}
+
+	// PicklistValue is a value of a picklist field.
+	PicklistValue struct {
+		Value        string `json:"value"`
+		Label        string `json:"label"`
+		Active       bool   `json:"active"`
+		DefaultValue bool   `json:"defaultValue"`
+		ValidFor     string `json:"validFor,omitempty"`
+	}
+
+	// ChildRelationship is a relationship from a child sobject to the
+	// described one, RelationshipName is the name used in subqueries.
+	ChildRelationship struct {
+		ChildSobject        string `json:"childSObject"`
+		Field               string `json:"field"`
+		RelationshipName    string `json:"relationshipName"`
+		CascadeDelete       bool   `json:"cascadeDelete"`
+		RestrictedDelete    bool   `json:"restrictedDelete"`
+		DeprecatedAndHidden bool   `json:"deprecatedAndHidden"`
+	}
+
+	// RecordTypeInfo is a record type of the sobject.
+	RecordTypeInfo struct {
+		RecordTypeID             string            `json:"recordTypeId"`
+		Name                     string            `json:"name"`
+		DeveloperName            string            `json:"developerName"`
+		Active                   bool              `json:"active"`
+		Available                bool              `json:"available"`
+		DefaultRecordTypeMapping bool              `json:"defaultRecordTypeMapping"`
+		Master                   bool              `json:"master"`
+		URLs                     map[string]string `json:"urls"`
+	}
+
+	opDescribeGlobal struct {
+		cond   conditional
+		result *DescribeGlobalResult
+	}
+
+	opDescribe struct {
+		sobjectName string
+		cond        conditional
+		result      *SobjectDescription
+	}
+)
+
+// Sobject returns the metadata of the sobject by name case insensitively,
+// nil if it's not found.
+func (r *DescribeGlobalResult) Sobject(name string) *SobjectMetadata {
+	for _, sobject := range r.Sobjects {
+		if strings.EqualFold(sobject.Name, name) {
+			return sobject
+		}
+	}
+	return nil
+}
+
+// SobjectOf returns the metadata of the sobject id belongs to by its key
+// prefix, nil if it's not found.
+func (r *DescribeGlobalResult) SobjectOf(id ID) *SobjectMetadata {
+	prefix := id.KeyPrefix()
+	if prefix == "" {
+		return nil
+	}
+	for _, sobject := range r.Sobjects {
+		if sobject.KeyPrefix == prefix {
+			return sobject
+		}
+	}
+	return nil
+}
+
+// Field returns the field by name case insensitively, nil if it's not found.
+func (d *SobjectDescription) Field(name string) *FieldDescription {
+	for _, field := range d.Fields {
+		if strings.EqualFold(field.Name, name) {
+			return field
+		}
+	}
+	return nil
+}
+
+// ParentRelationship returns the reference field of the parent relationship
+// by name case insensitively, nil if it's not found.
+func (d *SobjectDescription) ParentRelationship(name string) *FieldDescription {
+	for _, field := range d.Fields {
+		if field.RelationshipName != "" && strings.EqualFold(field.RelationshipName, name) {
+			return field
+		}
+	}
+	return nil
+}
+
+// ChildRelationship returns the child relationship by name case
+// insensitively, nil if it's not found.
+func (d *SobjectDescription) ChildRelationship(name string) *ChildRelationship {
+	for _, child := range d.ChildRelationships {
+		if child.RelationshipName != "" && strings.EqualFold(child.RelationshipName, name) {
+			return child
+		}
+	}
+	return nil
+}
+
+// PicklistValues returns the active values of the picklist field, nil if
+// the field is not found.
+func (d *SobjectDescription) PicklistValues(field string) []string {
+	f := d.Field(field)
+	if f == nil {
+		return nil
+	}
+	values := make([]string, 0, len(f.PicklistValues))
+	for _, value := range f.PicklistValues {
+		if value.Active {
+			values = append(values, value.Value)
+		}
+	}
+	return values
+}
+
+func (op *opDescribeGlobal) Make(ctx *RequestCtx) (*Request, error) {
+	return op.cond.request(ctx.SobjectURL()), nil
+}
+
+func (op *opDescribeGlobal) Operation() Operation {
+	return Operation{Name: "describeGlobal"}
+}
+
+func (op *opDescribeGlobal) Handle(resp *http.Response) error {
+	if err := op.cond.handle(resp, "describe global"); err != nil {
+		return err
+	}
+	return op.decode(op.cond.entry.Body)
+}
+
+func (op *opDescribeGlobal) conditional() *conditional {
+	return &op.cond
+}
+
+func (op *opDescribeGlobal) decode(body []byte) error {
+	return json.Unmarshal(body, &op.result)
+}
+
+func (op *opDescribe) Make(ctx *RequestCtx) (*Request, error) {
+	if op.sobjectName == "" {
+		return nil, errors.New("missing Sobject name")
+	}
+	return op.cond.request(ctx.SobjectDescribeURL(op.sobjectName)), nil
+}
+
+func (op *opDescribe) Operation() Operation {
+	return Operation{Name: "describe", Sobject: op.sobjectName}
+}
+
+func (op *opDescribe) Handle(resp *http.Response) error {
+	if err := op.cond.handle(resp, "describe"); err != nil {
+		return err
+	}
+	return op.decode(op.cond.entry.Body)
+}
+
+func (op *opDescribe) conditional() *conditional {
+	return &op.cond
+}
+
+func (op *opDescribe) decode(body []byte) error {
+	return json.Unmarshal(body, &op.result)
+}
+
+// DescribeGlobal lists the sobjects available in the org and their basic
+// metadata. The result is cached if Config.DescribeCache is set.
+func (c *Client) DescribeGlobal() (result *DescribeGlobalResult, err error) {
+	op := &opDescribeGlobal{}
+	if err = c.describe(op); err != nil {
+		return
+	}
+	result = op.result
+	return
+}
+
+// Describe returns the metadata of sobjectName, its fields, child
+// relationships and record types. The result is cached if
+// Config.DescribeCache is set.
+func (c *Client) Describe(sobjectName string) (result *SobjectDescription, err error) {
+	op := &opDescribe{sobjectName: sobjectName}
+	if err = c.describe(op); err != nil {
+		return
+	}
+	result = op.result
+	return
+}
+
+// SobjectOf returns the name of the sobject id belongs to, it's told by the
+// key prefix of id from DescribeGlobal.
+func (c *Client) SobjectOf(id ID) (sobjectName string, err error) {
+	if err = id.Validate(); err != nil {
+		return
+	}
+	global, err := c.DescribeGlobal()
+	if err != nil {
+		return
+	}
+	sobject := global.SobjectOf(id)
+	if sobject == nil {
+		err = fmt.Errorf("no sobject has key prefix %s of id %s", id.KeyPrefix(), id)
+		return
+	}
+	sobjectName = sobject.Name
+	return
+}
+
+/************************************/
+/********* DESCRIBE SCHEMA **********/
+/************************************/
+
+// DescribeSchema is a soql.Schema backed by describe results of a Client,
+// so queries can be validated against the org before running:
+//
+//	schema := client.DescribeSchema()
+//	if err := op.Validate(schema); err != nil {
+//		...
+//	}
+//
+// Describe results are kept by the DescribeSchema once fetched, use a new
+// one to see metadata changes. Set Config.DescribeCache to share describe
+// results between DescribeSchemas.
+type DescribeSchema struct {
+	client   *Client
+	mu       sync.Mutex
+	global   *DescribeGlobalResult
+	sobjects map[string]*SobjectDescription
+}
+
+var _ soql.Schema = (*DescribeSchema)(nil)
+
+// DescribeSchema returns a DescribeSchema of c.
+func (c *Client) DescribeSchema() *DescribeSchema {
+	return &DescribeSchema{
+		client:   c,
+		sobjects: make(map[string]*SobjectDescription),
+	}
+}
+
+// describe returns the description of sobject, nil if it doesn't exist.
+func (s *DescribeSchema) describe(sobject string) (*SobjectDescription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.global == nil {
+		global, err := s.client.DescribeGlobal()
+		if err != nil {
+			return nil, err
+		}
+		s.global = global
+	}
+	metadata := s.global.Sobject(sobject)
+	if metadata == nil {
+		return nil, nil
+	}
+	if description, ok := s.sobjects[metadata.Name]; ok {
+		return description, nil
+	}
+	description, err := s.client.Describe(metadata.Name)
+	if err != nil {
+		return nil, err
+	}
+	s.sobjects[metadata.Name] = description
+	return description, nil
+}
+
+// HasSobject implements soql.Schema.
+func (s *DescribeSchema) HasSobject(sobject string) (bool, error) {
+	description, err := s.describe(sobject)
+	return description != nil, err
+}
+
+// HasField implements soql.Schema.
+func (s *DescribeSchema) HasField(sobject, field string) (bool, error) {
+	description, err := s.describe(sobject)
+	if description == nil {
+		return false, err
+	}
+	return description.Field(field) != nil, nil
+}
+
+// Parent implements soql.Schema.
+func (s *DescribeSchema) Parent(sobject, relationship string) (string, bool, error) {
+	description, err := s.describe(sobject)
+	if description == nil {
+		return "", false, err
+	}
+	field := description.ParentRelationship(relationship)
+	if field == nil || len(field.ReferenceTo) == 0 {
+		return "", false, nil
+	}
+	return field.ReferenceTo[0], true, nil
+}
+
+// Child implements soql.Schema.
+func (s *DescribeSchema) Child(sobject, relationship string) (string, bool, error) {
+	description, err := s.describe(sobject)
+	if description == nil {
+		return "", false, err
+	}
+	child := description.ChildRelationship(relationship)
+	if child == nil {
+		return "", false, nil
+	}
+	return child.ChildSobject, true, nil
+}
@@ -0,0 +1,242 @@
+// Package gosfotel instruments gosf.Client with OpenTelemetry tracing and
+// metrics. Set the Transport as gosf.Config.Transport:
+//
+//	config.Transport = gosfotel.NewTransport(nil)
+//	client := gosf.NewClient(config, logger)
+//
+// Each request sent by the Client, including the token exchange, gets a span
+// named after its gosf.Operation, like "gosf.query".
+package gosfotel
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sidebiequ/gosf"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/sidebiequ/gosf/gosfotel"
+
+// maxErrBodySize limits how much of an error response is read to find
+// the salesforce error code.
+const maxErrBodySize = 64 << 10
+
+// Attribute keys set on spans and metrics.
+const (
+	OperationKey = attribute.Key("salesforce.operation")
+	SobjectKey   = attribute.Key("salesforce.sobject")
+	ErrorCodeKey = attribute.Key("salesforce.error_code")
+	AttemptKey   = attribute.Key("salesforce.attempt")
+	StatusKey    = attribute.Key("http.response.status_code")
+	MethodKey    = attribute.Key("http.request.method")
+	URLKey       = attribute.Key("url.full")
+)
+
+/************************************/
+/************** OPTIONS *************/
+/************************************/
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+// Option configures the Transport.
+type Option func(*config)
+
+// WithTracerProvider sets the TracerProvider spans are created by.
+// The global one is used by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tp
+	}
+}
+
+// WithMeterProvider sets the MeterProvider metrics are recorded by.
+// The global one is used by default.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = mp
+	}
+}
+
+/************************************/
+/************* TRANSPORT ************/
+/************************************/
+
+// Transport is a http.RoundTripper records a span and metrics for every
+// request sent to salesforce. It records:
+//   - gosf.requests: count of requests
+//   - gosf.request.duration: latency of requests in seconds
+//   - gosf.retries: count of retried requests
+//   - gosf.api.usage and gosf.api.limit: the api usage reported by salesforce
+type Transport struct {
+	base   http.RoundTripper
+	tracer trace.Tracer
+
+	requests metric.Int64Counter
+	duration metric.Float64Histogram
+	retries  metric.Int64Counter
+	apiUsage metric.Int64Gauge
+	apiLimit metric.Int64Gauge
+}
+
+// NewTransport returns a Transport sends requests by base.
+// If base is nil, http.DefaultTransport is used.
+func NewTransport(base http.RoundTripper, opts ...Option) *Transport {
+	c := &config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	meter := c.meterProvider.Meter(instrumentationName)
+	t := &Transport{
+		base:   base,
+		tracer: c.tracerProvider.Tracer(instrumentationName),
+	}
+
+	var err error
+	if t.requests, err = meter.Int64Counter("gosf.requests",
+		metric.WithDescription("Number of requests sent to salesforce."),
+		metric.WithUnit("{request}"),
+	); err != nil {
+		otel.Handle(err)
+	}
+	if t.duration, err = meter.Float64Histogram("gosf.request.duration",
+		metric.WithDescription("Duration of requests sent to salesforce."),
+		metric.WithUnit("s"),
+	); err != nil {
+		otel.Handle(err)
+	}
+	if t.retries, err = meter.Int64Counter("gosf.retries",
+		metric.WithDescription("Number of requests retried."),
+		metric.WithUnit("{request}"),
+	); err != nil {
+		otel.Handle(err)
+	}
+	if t.apiUsage, err = meter.Int64Gauge("gosf.api.usage",
+		metric.WithDescription("API requests used in the last 24 hours reported by salesforce."),
+		metric.WithUnit("{request}"),
+	); err != nil {
+		otel.Handle(err)
+	}
+	if t.apiLimit, err = meter.Int64Gauge("gosf.api.limit",
+		metric.WithDescription("API requests allowed in 24 hours reported by salesforce."),
+		metric.WithUnit("{request}"),
+	); err != nil {
+		otel.Handle(err)
+	}
+	return t
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	op, ok := gosf.OperationFromContext(req.Context())
+	if !ok || op.Name == "" {
+		op.Name = "request"
+	}
+
+	attrs := []attribute.KeyValue{OperationKey.String(op.Name)}
+	if op.Sobject != "" {
+		attrs = append(attrs, SobjectKey.String(op.Sobject))
+	}
+
+	ctx, span := t.tracer.Start(req.Context(), "gosf."+op.Name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(
+			MethodKey.String(req.Method),
+			URLKey.String(req.URL.Redacted()),
+		),
+	)
+	defer span.End()
+	if op.Attempt > 1 {
+		span.SetAttributes(AttemptKey.Int(op.Attempt))
+		t.retries.Add(ctx, 1, metric.WithAttributes(attrs...))
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	elapsed := time.Since(start).Seconds()
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		attrs = append(attrs, attribute.String("error.type", "transport"))
+		t.requests.Add(ctx, 1, metric.WithAttributes(attrs...))
+		t.duration.Record(ctx, elapsed, metric.WithAttributes(attrs...))
+		return nil, err
+	}
+
+	span.SetAttributes(StatusKey.Int(resp.StatusCode))
+	attrs = append(attrs, StatusKey.Int(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		code := peekErrorCode(resp)
+		if code == "" {
+			code = strconv.Itoa(resp.StatusCode)
+		} else {
+			span.SetAttributes(ErrorCodeKey.String(code))
+		}
+		span.SetStatus(codes.Error, code)
+	}
+	t.requests.Add(ctx, 1, metric.WithAttributes(attrs...))
+	t.duration.Record(ctx, elapsed, metric.WithAttributes(attrs...))
+
+	if usage, ok := gosf.ParseAPIUsage(resp.Header); ok {
+		t.apiUsage.Record(ctx, int64(usage.Used))
+		t.apiLimit.Record(ctx, int64(usage.Limit))
+	}
+	return resp, nil
+}
+
+// peekErrorCode reads the salesforce error code from the error response
+// and restores the body for the Client to read. Salesforce responds
+//
+//	[{"message": "...", "errorCode": "ERROR_CODE"}]
+//
+// for api errors and
+//
+//	{"error": "ERROR_CODE", "error_description": "..."}
+//
+// for oauth errors.
+func peekErrorCode(resp *http.Response) string {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrBodySize))
+	rest := resp.Body
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), rest), rest}
+	if err != nil {
+		return ""
+	}
+
+	var apiErrs []struct {
+		ErrorCode string `json:"errorCode"`
+	}
+	if json.Unmarshal(body, &apiErrs) == nil && len(apiErrs) > 0 {
+		return apiErrs[0].ErrorCode
+	}
+
+	var oauthErr struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &oauthErr) == nil {
+		return oauthErr.Error
+	}
+	return ""
+}
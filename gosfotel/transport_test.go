@@ -0,0 +1,193 @@
+package gosfotel_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sidebiequ/gosf"
+	"github.com/sidebiequ/gosf/gosfotel"
+	"github.com/sidebiequ/gosf/gosftest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// revokeOnce rejects the first api request as if the session was revoked,
+// by replacing its token.
+type revokeOnce struct {
+	once sync.Once
+	base http.RoundTripper
+}
+
+func (r *revokeOnce) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.HasPrefix(req.URL.Path, "/services/data/") {
+		r.once.Do(func() {
+			req = req.Clone(req.Context())
+			req.Header.Set("Authorization", "Bearer revoked")
+		})
+	}
+	return r.base.RoundTrip(req)
+}
+
+type instrumented struct {
+	spans  *tracetest.InMemoryExporter
+	reader *sdkmetric.ManualReader
+	client *gosf.Client
+}
+
+func newInstrumented(t *testing.T, srv *gosftest.Server) *instrumented {
+	spans := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	t.Cleanup(func() {
+		tp.Shutdown(context.Background())
+		mp.Shutdown(context.Background())
+	})
+
+	config := srv.Config()
+	config.Transport = gosfotel.NewTransport(&revokeOnce{base: http.DefaultTransport},
+		gosfotel.WithTracerProvider(tp),
+		gosfotel.WithMeterProvider(mp),
+	)
+	return &instrumented{
+		spans:  spans,
+		reader: reader,
+		client: gosf.NewClient(config, gosf.NewSlogLogger(slog.New(slog.DiscardHandler))),
+	}
+}
+
+func attrs(kvs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value, len(kvs))
+	for _, kv := range kvs {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestTransportSpans(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	id := srv.Insert("Account", gosftest.Record{"Name": "Acme"})
+	in := newInstrumented(t, srv)
+
+	var account map[string]interface{}
+	if err := in.client.GetSobject("Account", id, &account); err != nil {
+		t.Fatal(err)
+	}
+	if err := in.client.DeleteSobject("Account", id); err != nil {
+		t.Fatal(err)
+	}
+	if err := in.client.GetSobject("Account", id, &account); err == nil {
+		t.Fatal("expect error of deleted record")
+	}
+
+	type want struct {
+		name      string
+		sobject   string
+		status    int64
+		attempt   int64
+		errorCode string
+	}
+	wants := []want{
+		{name: "gosf.token", status: 200},
+		{name: "gosf.get", sobject: "Account", status: 401, errorCode: "INVALID_SESSION_ID"},
+		{name: "gosf.token", status: 200},
+		{name: "gosf.get", sobject: "Account", status: 200, attempt: 2},
+		{name: "gosf.delete", sobject: "Account", status: 204},
+		{name: "gosf.get", sobject: "Account", status: 404, errorCode: "ENTITY_IS_DELETED"},
+	}
+	spans := in.spans.GetSpans()
+	if len(spans) != len(wants) {
+		t.Fatalf("got %d spans, want %d: %v", len(spans), len(wants), spans)
+	}
+	for i, w := range wants {
+		span := spans[i]
+		got := attrs(span.Attributes)
+		if span.Name != w.name {
+			t.Errorf("span %d: got name %s, want %s", i, span.Name, w.name)
+		}
+		if got[gosfotel.SobjectKey].AsString() != w.sobject {
+			t.Errorf("span %d: got sobject %q, want %q", i, got[gosfotel.SobjectKey].AsString(), w.sobject)
+		}
+		if got[gosfotel.StatusKey].AsInt64() != w.status {
+			t.Errorf("span %d: got status %d, want %d", i, got[gosfotel.StatusKey].AsInt64(), w.status)
+		}
+		if got[gosfotel.AttemptKey].AsInt64() != w.attempt {
+			t.Errorf("span %d: got attempt %d, want %d", i, got[gosfotel.AttemptKey].AsInt64(), w.attempt)
+		}
+		if got[gosfotel.ErrorCodeKey].AsString() != w.errorCode {
+			t.Errorf("span %d: got error code %q, want %q", i, got[gosfotel.ErrorCodeKey].AsString(), w.errorCode)
+		}
+		if wantErr := w.status >= 400; (span.Status.Code == codes.Error) != wantErr {
+			t.Errorf("span %d: got status %v, want error %v", i, span.Status, wantErr)
+		}
+	}
+}
+
+func TestTransportMetrics(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	srv.Insert("Account", gosftest.Record{"Name": "Acme"})
+	in := newInstrumented(t, srv)
+
+	for i := 0; i < 2; i++ {
+		if _, err := in.client.QuerySobject(gosf.NewOpQuery("Account").Select("Name")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := in.reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	metrics := make(map[string]metricdata.Aggregation)
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+
+	// token, rejected query, token, retried query, query
+	requests := metrics["gosf.requests"].(metricdata.Sum[int64])
+	var total, tokens int64
+	for _, dp := range requests.DataPoints {
+		total += dp.Value
+		if op, _ := dp.Attributes.Value(gosfotel.OperationKey); op.AsString() == "token" {
+			tokens += dp.Value
+		}
+	}
+	if total != 5 || tokens != 2 {
+		t.Errorf("got %d requests with %d tokens, want 5 with 2", total, tokens)
+	}
+
+	duration := metrics["gosf.request.duration"].(metricdata.Histogram[float64])
+	var count uint64
+	for _, dp := range duration.DataPoints {
+		count += dp.Count
+	}
+	if count != 5 {
+		t.Errorf("got %d durations, want 5", count)
+	}
+
+	retries := metrics["gosf.retries"].(metricdata.Sum[int64])
+	if len(retries.DataPoints) != 1 || retries.DataPoints[0].Value != 1 {
+		t.Errorf("got retries %+v, want 1", retries.DataPoints)
+	}
+	if op, _ := retries.DataPoints[0].Attributes.Value(gosfotel.OperationKey); op.AsString() != "query" {
+		t.Errorf("got retried operation %s, want query", op.AsString())
+	}
+
+	usage := metrics["gosf.api.usage"].(metricdata.Gauge[int64])
+	limit := metrics["gosf.api.limit"].(metricdata.Gauge[int64])
+	if usage.DataPoints[0].Value != 1 || limit.DataPoints[0].Value != 15000 {
+		t.Errorf("got api usage %d/%d, want 1/15000", usage.DataPoints[0].Value, limit.DataPoints[0].Value)
+	}
+}
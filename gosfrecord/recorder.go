@@ -0,0 +1,311 @@
+// Package gosfrecord records the traffic between gosf.Client and salesforce
+// to cassette files and replays it, so code using the Client can be tested
+// without an org. Set the Recorder as gosf.Config.Transport:
+//
+//	rec, err := gosfrecord.New("testdata/accounts.json", gosfrecord.ModeAuto, nil)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer rec.Stop()
+//	config.Transport = rec
+//	client := gosf.NewClient(config, nil)
+//
+// Authorization headers, passwords, client secrets and tokens are scrubbed
+// before an interaction is recorded.
+package gosfrecord
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Scrubbed replaces secrets in recorded interactions.
+const Scrubbed = "[SCRUBBED]"
+
+/************************************/
+/************* CASSETTE *************/
+/************************************/
+
+type (
+	// Cassette holds the recorded interactions.
+	Cassette struct {
+		Interactions []*Interaction `json:"interactions"`
+	}
+
+	// Interaction is a request and the response to it.
+	Interaction struct {
+		Request  Request  `json:"request"`
+		Response Response `json:"response"`
+	}
+
+	// Request is a recorded request.
+	Request struct {
+		Method string      `json:"method"`
+		URL    string      `json:"url"`
+		Header http.Header `json:"header,omitempty"`
+		Body   string      `json:"body,omitempty"`
+	}
+
+	// Response is a recorded response.
+	Response struct {
+		StatusCode int         `json:"status_code"`
+		Header     http.Header `json:"header,omitempty"`
+		Body       string      `json:"body,omitempty"`
+	}
+)
+
+// Load reads the cassette from file path.
+func Load(path string) (*Cassette, error) {
+	byts, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cassette{}
+	if err = json.Unmarshal(byts, c); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Save writes the cassette to file path, directories are created if missing.
+func (c *Cassette) Save(path string) error {
+	byts, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(byts, '\n'), 0644)
+}
+
+/************************************/
+/************* RECORDER *************/
+/************************************/
+
+// Mode controls whether a Recorder talks to salesforce.
+type Mode int
+
+const (
+	// ModeRecord sends requests to salesforce and records them.
+	ModeRecord Mode = iota
+	// ModeReplay serves requests from the cassette only.
+	ModeReplay
+	// ModeAuto replays if the cassette file exists, records otherwise.
+	ModeAuto
+)
+
+// Recorder is a http.RoundTripper records or replays interactions.
+type Recorder struct {
+	path     string
+	mode     Mode
+	base     http.RoundTripper
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// New returns a Recorder works with cassette file path. In record mode,
+// requests are sent by base, http.DefaultTransport is used if base is nil.
+func New(path string, mode Mode, base http.RoundTripper) (*Recorder, error) {
+	if mode == ModeAuto {
+		mode = ModeRecord
+		if _, err := os.Stat(path); err == nil {
+			mode = ModeReplay
+		}
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	r := &Recorder{
+		path:     path,
+		mode:     mode,
+		base:     base,
+		cassette: &Cassette{},
+	}
+	if mode == ModeReplay {
+		cassette, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		r.cassette = cassette
+		r.used = make([]bool, len(cassette.Interactions))
+	}
+	return r, nil
+}
+
+// Mode returns the mode the Recorder works in, ModeAuto is resolved.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Stop saves the cassette if recording.
+func (r *Recorder) Stop() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cassette.Save(r.path)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(strings.NewReader(body))
+	}
+	recorded := scrubRequest(req, body)
+
+	if r.mode == ModeReplay {
+		return r.replay(req, recorded)
+	}
+	return r.record(req, recorded)
+}
+
+func (r *Recorder) record(req *http.Request, recorded Request) (*http.Response, error) {
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{
+		Request: recorded,
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			Body:       scrubBody(header.Get("Content-Type"), string(body)),
+		},
+	})
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// replay serves the first unused interaction matches the request. Requests
+// are matched by method, path, query and scrubbed body, the host is ignored.
+func (r *Recorder) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || !match(interaction.Request, recorded) {
+			continue
+		}
+		r.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Response.Header.Clone(),
+			Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("gosfrecord: no recorded interaction for %s %s", req.Method, req.URL.RequestURI())
+}
+
+func match(a, b Request) bool {
+	if a.Method != b.Method || a.Body != b.Body {
+		return false
+	}
+	ua, err := url.Parse(a.URL)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b.URL)
+	if err != nil {
+		return false
+	}
+	return ua.Path == ub.Path && ua.Query().Encode() == ub.Query().Encode()
+}
+
+/************************************/
+/************** SCRUB ***************/
+/************************************/
+
+var (
+	// scrubbedHeaders are never recorded with their values.
+	scrubbedHeaders = []string{"Authorization", "Cookie"}
+	// scrubbedFields are form fields and json keys hold secrets.
+	scrubbedFields = []string{
+		"password", "client_secret", "access_token", "refresh_token",
+		"id_token", "signature", "sessionId", "assertion",
+	}
+	scrubbedJSON = regexp.MustCompile(`("(?:` + strings.Join(scrubbedFields, "|") + `)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+)
+
+// readBody reads and closes the request body.
+func readBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+	byts, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	return string(byts), err
+}
+
+func scrubRequest(req *http.Request, body string) Request {
+	header := req.Header.Clone()
+	for _, key := range scrubbedHeaders {
+		if header.Get(key) != "" {
+			header.Set(key, Scrubbed)
+		}
+	}
+
+	u := *req.URL
+	if u.RawQuery != "" {
+		u.RawQuery = scrubForm(u.RawQuery)
+	}
+	return Request{
+		Method: req.Method,
+		URL:    u.String(),
+		Header: header,
+		Body:   scrubBody(req.Header.Get("Content-Type"), body),
+	}
+}
+
+func scrubBody(contentType, body string) string {
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		return scrubForm(body)
+	}
+	return scrubbedJSON.ReplaceAllString(body, `${1}"`+Scrubbed+`"`)
+}
+
+func scrubForm(form string) string {
+	values, err := url.ParseQuery(form)
+	if err != nil {
+		return form
+	}
+	for _, key := range scrubbedFields {
+		if _, ok := values[key]; ok {
+			values.Set(key, Scrubbed)
+		}
+	}
+	return values.Encode()
+}
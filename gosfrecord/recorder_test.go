@@ -0,0 +1,135 @@
+package gosfrecord_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sidebiequ/gosf"
+	"github.com/sidebiequ/gosf/gosfrecord"
+	"github.com/sidebiequ/gosf/gosftest"
+)
+
+// tokenSpy is the transport of recording, it keeps the access tokens sent.
+type tokenSpy struct {
+	mu     sync.Mutex
+	tokens []string
+}
+
+func (s *tokenSpy) RoundTrip(req *http.Request) (*http.Response, error) {
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		s.mu.Lock()
+		s.tokens = append(s.tokens, strings.TrimPrefix(auth, "Bearer "))
+		s.mu.Unlock()
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newClient(config *gosf.Config, rec *gosfrecord.Recorder) *gosf.Client {
+	config.Transport = rec
+	return gosf.NewClient(config, gosf.NewSlogLogger(slog.New(slog.DiscardHandler)))
+}
+
+// useClient gets an account and creates one, the token is exchanged first.
+func useClient(t *testing.T, client *gosf.Client, id string) {
+	t.Helper()
+	var account struct{ Name string }
+	if err := client.GetSobject("Account", id, &account); err != nil {
+		t.Fatal(err)
+	}
+	if account.Name != "Acme" {
+		t.Errorf("got %+v, want Acme", account)
+	}
+	if _, err := client.CreaetSobject("Account", map[string]interface{}{"Name": "Globex"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	srv := gosftest.NewServer()
+	id := srv.Insert("Account", gosftest.Record{"Name": "Acme"})
+	path := filepath.Join(t.TempDir(), "cassettes", "accounts.json")
+	config := srv.Config()
+
+	// record
+	spy := &tokenSpy{}
+	rec, err := gosfrecord.New(path, gosfrecord.ModeAuto, spy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Mode() != gosfrecord.ModeRecord {
+		t.Fatalf("got mode %d, want record without a cassette", rec.Mode())
+	}
+	useClient(t, newClient(srv.Config(), rec), id)
+
+	// secrets in headers and bodies sent by other code
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/services/oauth2/token",
+		strings.NewReader(`{"password":"json-password","client_secret":"json-secret","access_token":"json-token","name":"kept"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Cookie", "sid=cookie-session")
+	req.Header.Set("Authorization", "Bearer header-token")
+	resp, err := rec.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if err = rec.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	byts, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cassette := string(byts)
+	if len(spy.tokens) == 0 {
+		t.Fatal("no access token sent")
+	}
+	secrets := []string{
+		config.Password, config.ClientSecret, spy.tokens[0], "gosftest-signature",
+		"json-password", "json-secret", "json-token", "cookie-session", "header-token",
+	}
+	for _, secret := range secrets {
+		if strings.Contains(cassette, secret) {
+			t.Errorf("%s is recorded", secret)
+		}
+	}
+	for _, kept := range []string{url.QueryEscape(config.Username), "kept", "Acme", gosfrecord.Scrubbed} {
+		if !strings.Contains(cassette, kept) {
+			t.Errorf("%s is not recorded", kept)
+		}
+	}
+
+	// replay offline, the server is closed
+	rec, err = gosfrecord.New(path, gosfrecord.ModeAuto, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Mode() != gosfrecord.ModeReplay {
+		t.Fatalf("got mode %d, want replay of the cassette", rec.Mode())
+	}
+	client := newClient(config, rec)
+	useClient(t, client, id)
+
+	// the interactions are used up, and others were never recorded
+	var account struct{ Name string }
+	for _, get := range []string{id, "001000000000999AAA"} {
+		err = client.GetSobject("Account", get, &account)
+		if err == nil || !strings.Contains(err.Error(), "no recorded interaction") {
+			t.Errorf("got %v, want no recorded interaction for %s", err, get)
+		}
+	}
+}
+
+func TestReplayWithoutCassette(t *testing.T) {
+	_, err := gosfrecord.New(filepath.Join(t.TempDir(), "missing.json"), gosfrecord.ModeReplay, nil)
+	if !os.IsNotExist(err) {
+		t.Errorf("got %v, want not exist", err)
+	}
+}
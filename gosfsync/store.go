@@ -0,0 +1,94 @@
+package gosfsync
+
+import (
+	"net/url"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sidebiequ/gosf"
+	"github.com/sidebiequ/gosf/internal/jsonfile"
+)
+
+/************************************/
+/************ CHECKPOINTS ***********/
+/************************************/
+
+// Checkpoint is the high-water mark of a sobject, the changes up to it have
+// been handled.
+type Checkpoint struct {
+	Sobject string `json:"sobject"`
+	// SystemModstamp is the latest SystemModstamp handled.
+	SystemModstamp time.Time `json:"systemModstamp"`
+	// IDs are the records handled at exactly SystemModstamp, they're
+	// skipped when the sync resumes from it.
+	IDs []gosf.ID `json:"ids,omitempty"`
+	// DeletedUntil is the end of the range the deleted records have been
+	// handled until.
+	DeletedUntil time.Time `json:"deletedUntil,omitzero"`
+}
+
+// CheckpointStore stores Checkpoint by sobject name. It must be safe for
+// concurrent use.
+type CheckpointStore interface {
+	// Load returns the checkpoint of sobject, nil if there's none.
+	Load(sobject string) (*Checkpoint, error)
+	// Save stores the checkpoint of its sobject.
+	Save(checkpoint *Checkpoint) error
+}
+
+type memoryCheckpointStore struct {
+	mu          sync.RWMutex
+	checkpoints map[string]*Checkpoint
+}
+
+// NewMemoryCheckpointStore returns a CheckpointStore keeps checkpoints in
+// memory, syncs start over after restarts.
+func NewMemoryCheckpointStore() CheckpointStore {
+	return &memoryCheckpointStore{checkpoints: make(map[string]*Checkpoint)}
+}
+
+func (s *memoryCheckpointStore) Load(sobject string) (*Checkpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	checkpoint, ok := s.checkpoints[sobject]
+	if !ok {
+		return nil, nil
+	}
+	copied := *checkpoint
+	copied.IDs = append([]gosf.ID(nil), checkpoint.IDs...)
+	return &copied, nil
+}
+
+func (s *memoryCheckpointStore) Save(checkpoint *Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *checkpoint
+	copied.IDs = append([]gosf.ID(nil), checkpoint.IDs...)
+	s.checkpoints[checkpoint.Sobject] = &copied
+	return nil
+}
+
+type fileCheckpointStore struct {
+	dir string
+}
+
+// NewFileCheckpointStore returns a CheckpointStore keeps the checkpoint of
+// each sobject in a json file under dir, so syncs resume after restarts.
+// dir is created when the first checkpoint is saved.
+func NewFileCheckpointStore(dir string) CheckpointStore {
+	return &fileCheckpointStore{dir: dir}
+}
+
+func (s *fileCheckpointStore) path(sobject string) string {
+	return filepath.Join(s.dir, url.QueryEscape(sobject)+".json")
+}
+
+func (s *fileCheckpointStore) Load(sobject string) (checkpoint *Checkpoint, err error) {
+	_, err = jsonfile.Read(s.path(sobject), &checkpoint)
+	return
+}
+
+func (s *fileCheckpointStore) Save(checkpoint *Checkpoint) error {
+	return jsonfile.Write(s.path(checkpoint.Sobject), checkpoint)
+}
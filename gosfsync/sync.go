@@ -0,0 +1,319 @@
+// Package gosfsync polls salesforce for the records changed since the last
+// run, so services can keep copies of sobjects up to date:
+//
+//	syncer := gosfsync.New(client, gosfsync.NewFileCheckpointStore("/var/lib/sync"),
+//		func(ctx context.Context, changes []*gosfsync.Change) error {
+//			for _, change := range changes {
+//				if change.Deleted {
+//					// delete change.ID
+//				} else {
+//					// upsert change.Record
+//				}
+//			}
+//			return nil
+//		})
+//	syncer.Add("Account", "Name", "Industry").Add("Contact", "LastName", "AccountId")
+//	err := syncer.Run(ctx, 5*time.Minute)
+//
+// Each sobject is tracked by a high-water mark on SystemModstamp. A sync
+// queries the records modified since it in SystemModstamp order and hands
+// them to the handler in batches. The checkpoint is saved after each batch
+// is handled, so a sync resumes after the last handled batch if the
+// process crashes. A batch may be handed again if the process crashes
+// before its checkpoint is saved, handlers should be idempotent.
+//
+// Records are synced up to Lag before now, since SystemModstamp is set
+// when a transaction starts and a record committed late can have an
+// earlier one than records already synced.
+//
+// Deleted records are found by Client.GetDeleted from the end of the range
+// of the last sync, so the records purged from the recycle bin are found
+// too. Salesforce reports the deletes of the last 30 days at most, sync
+// more often than that.
+package gosfsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sidebiequ/gosf"
+)
+
+const (
+	// DefaultBatchSize is the number of changes handed to the handler at
+	// once.
+	DefaultBatchSize = 200
+	// DefaultLag is how long before now records are synced up to.
+	DefaultLag = time.Minute
+)
+
+// systemFields are selected for every sobject.
+var systemFields = []string{"Id", "SystemModstamp"}
+
+// Change is a record created, updated or deleted.
+type Change struct {
+	Sobject        string
+	ID             gosf.ID
+	SystemModstamp time.Time
+	Deleted        bool
+	// Record is the fields selected with Id and SystemModstamp, it's nil
+	// for deleted records, whose SystemModstamp is when they're deleted.
+	Record map[string]interface{}
+}
+
+// Handler handles a batch of changes of a sobject, the records modified
+// in SystemModstamp order, then the deleted ones.
+// If it returns an error the sync stops, and the batch is handed again by
+// the next sync.
+type Handler func(ctx context.Context, changes []*Change) error
+
+// Syncer syncs the changes of sobjects to a Handler.
+type Syncer struct {
+	// BatchSize is the max number of changes handed to the handler at
+	// once, DefaultBatchSize if it's not positive.
+	BatchSize int
+	// Start is where sobjects without checkpoints are synced from, all
+	// records are synced if it's zero.
+	Start time.Time
+	// Lag is how long before now records are synced up to, DefaultLag if
+	// it's not positive.
+	Lag time.Duration
+
+	client   *gosf.Client
+	store    CheckpointStore
+	handler  Handler
+	sobjects []*sobject
+	now      func() time.Time
+}
+
+type sobject struct {
+	name   string
+	fields []string
+}
+
+// New returns a Syncer hands the changes found by client to handler and
+// keeps the checkpoints in store.
+func New(client *gosf.Client, store CheckpointStore, handler Handler) *Syncer {
+	return &Syncer{
+		client:  client,
+		store:   store,
+		handler: handler,
+		now:     time.Now,
+	}
+}
+
+// Add adds sobjectName to sync with fields selected.
+func (s *Syncer) Add(sobjectName string, fields ...string) *Syncer {
+	selected := append([]string(nil), systemFields...)
+	for _, field := range fields {
+		if !containsFold(selected, field) {
+			selected = append(selected, field)
+		}
+	}
+	s.sobjects = append(s.sobjects, &sobject{name: sobjectName, fields: selected})
+	return s
+}
+
+// Run syncs every interval until ctx is done or a sync fails.
+func (s *Syncer) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Sync(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync syncs the changes of each sobject since its checkpoint once.
+func (s *Syncer) Sync(ctx context.Context) error {
+	switch {
+	case s.client == nil:
+		return errors.New("missing client")
+	case s.store == nil:
+		return errors.New("missing checkpoint store")
+	case s.handler == nil:
+		return errors.New("missing handler")
+	}
+	for _, sobject := range s.sobjects {
+		if err := s.syncSobject(ctx, sobject); err != nil {
+			return fmt.Errorf("sync %s: %w", sobject.name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Syncer) syncSobject(ctx context.Context, sobject *sobject) error {
+	checkpoint, err := s.store.Load(sobject.name)
+	if err != nil {
+		return fmt.Errorf("load checkpoint: %w", err)
+	}
+	lag := s.Lag
+	if lag <= 0 {
+		lag = DefaultLag
+	}
+	until := s.now().Add(-lag).UTC().Truncate(time.Second)
+	if checkpoint == nil {
+		// the records deleted before the first sync are never handed
+		checkpoint = &Checkpoint{Sobject: sobject.name, SystemModstamp: s.Start, DeletedUntil: until}
+	}
+	if err = s.syncModified(ctx, sobject, checkpoint, until); err != nil {
+		return err
+	}
+	return s.syncDeleted(ctx, sobject, checkpoint, until)
+}
+
+func (s *Syncer) batchSize() int {
+	if s.BatchSize <= 0 {
+		return DefaultBatchSize
+	}
+	return s.BatchSize
+}
+
+// handle hands changes to the handler if there are any, then moves
+// checkpoint by advance and saves it.
+func (s *Syncer) handle(ctx context.Context, checkpoint *Checkpoint, changes []*Change, advance func()) error {
+	if len(changes) > 0 {
+		if err := s.handler(ctx, changes); err != nil {
+			return err
+		}
+	}
+	advance()
+	if err := s.store.Save(checkpoint); err != nil {
+		return fmt.Errorf("save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// syncModified hands the records modified from the checkpoint until.
+func (s *Syncer) syncModified(ctx context.Context, sobject *sobject, checkpoint *Checkpoint, until time.Time) error {
+	handled := make(map[gosf.ID]bool, len(checkpoint.IDs))
+	for _, id := range checkpoint.IDs {
+		handled[id] = true
+	}
+
+	// SOQL datetimes are in seconds, the records at the second of the
+	// checkpoint are queried again and skipped below
+	op := gosf.NewOpQuery(sobject.name).
+		Select(sobject.fields...).
+		WhereCompare("SystemModstamp", "<", until).
+		OrderAsc("SystemModstamp").
+		OrderAsc("Id")
+	if !checkpoint.SystemModstamp.IsZero() {
+		op.WhereCompare("SystemModstamp", ">=", checkpoint.SystemModstamp.Truncate(time.Second))
+	}
+
+	batch := make([]*Change, 0, s.batchSize())
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := s.handle(ctx, checkpoint, batch, func() { advance(checkpoint, batch) })
+		batch = make([]*Change, 0, s.batchSize())
+		return err
+	}
+
+	it := gosf.QueryIter[map[string]interface{}](ctx, s.client, op)
+	for it.Next() {
+		change, err := changeOf(sobject.name, it.Record())
+		if err != nil {
+			return err
+		}
+		if change.SystemModstamp.Before(checkpoint.SystemModstamp) ||
+			change.SystemModstamp.Equal(checkpoint.SystemModstamp) && handled[change.ID] {
+			continue
+		}
+		if batch = append(batch, change); len(batch) >= s.batchSize() {
+			if err = flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// syncDeleted hands the records deleted from DeletedUntil of checkpoint
+// until, the checkpoint moves when all of them are handled.
+func (s *Syncer) syncDeleted(ctx context.Context, sobject *sobject, checkpoint *Checkpoint, until time.Time) error {
+	if until.Sub(checkpoint.DeletedUntil) < time.Minute {
+		// salesforce covers ranges by minutes
+		return nil
+	}
+	result, err := s.client.GetDeleted(sobject.name, checkpoint.DeletedUntil, until)
+	if err != nil {
+		return fmt.Errorf("get deleted: %w", err)
+	}
+	covered := result.LatestDateCovered.Time
+	if covered.IsZero() || covered.After(until) {
+		covered = until
+	}
+
+	changes := make([]*Change, 0, len(result.DeletedRecords))
+	for _, record := range result.DeletedRecords {
+		changes = append(changes, &Change{
+			Sobject:        sobject.name,
+			ID:             record.ID,
+			SystemModstamp: record.DeletedDate.Time,
+			Deleted:        true,
+		})
+	}
+	for len(changes) > s.batchSize() {
+		if err = s.handler(ctx, changes[:s.batchSize()]); err != nil {
+			return err
+		}
+		changes = changes[s.batchSize():]
+	}
+	return s.handle(ctx, checkpoint, changes, func() { checkpoint.DeletedUntil = covered })
+}
+
+// advance moves checkpoint to the last change of batch, which is sorted by
+// SystemModstamp.
+func advance(checkpoint *Checkpoint, batch []*Change) {
+	for _, change := range batch {
+		if change.SystemModstamp.After(checkpoint.SystemModstamp) {
+			checkpoint.SystemModstamp = change.SystemModstamp
+			checkpoint.IDs = checkpoint.IDs[:0]
+		}
+		checkpoint.IDs = append(checkpoint.IDs, change.ID)
+	}
+}
+
+// changeOf returns the Change of a queried record.
+func changeOf(sobjectName string, record map[string]interface{}) (*Change, error) {
+	rawID, _ := record["Id"].(string)
+	id, err := gosf.ParseID(rawID)
+	if err != nil {
+		return nil, err
+	}
+	rawModstamp, _ := record["SystemModstamp"].(string)
+	modstamp, err := gosf.ParseDateTime(rawModstamp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SystemModstamp of %s: %w", id, err)
+	}
+	return &Change{
+		Sobject:        sobjectName,
+		ID:             id,
+		SystemModstamp: modstamp.Time,
+		Record:         record,
+	}, nil
+}
+
+func containsFold(fields []string, field string) bool {
+	for _, f := range fields {
+		if strings.EqualFold(f, field) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package gosfsync
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sidebiequ/gosf"
+	"github.com/sidebiequ/gosf/gosftest"
+)
+
+// recorder is a Handler keeps the changes handed, it fails the call fail.
+type recorder struct {
+	changes []*Change
+	calls   int
+	fail    int
+}
+
+func (r *recorder) handle(ctx context.Context, changes []*Change) error {
+	if r.calls++; r.calls == r.fail {
+		return errors.New("handler failed")
+	}
+	r.changes = append(r.changes, changes...)
+	return nil
+}
+
+func (r *recorder) ids() (ids []gosf.ID) {
+	for _, change := range r.changes {
+		ids = append(ids, change.ID)
+	}
+	return
+}
+
+type syncTest struct {
+	srv     *gosftest.Server
+	syncer  *Syncer
+	store   CheckpointStore
+	handled *recorder
+	base    time.Time
+	clock   time.Time
+}
+
+func newSyncTest(t *testing.T) *syncTest {
+	st := &syncTest{
+		srv:     gosftest.NewServer(),
+		store:   NewFileCheckpointStore(t.TempDir()),
+		handled: &recorder{},
+		base:    time.Now().UTC().Truncate(time.Minute).Add(-2 * time.Hour),
+	}
+	t.Cleanup(st.srv.Close)
+	st.clock = st.base.Add(time.Hour)
+	st.syncer = New(st.srv.Client(), st.store, st.handled.handle).Add("Account", "Name")
+	st.syncer.BatchSize = 2
+	st.syncer.now = func() time.Time { return st.clock }
+	return st
+}
+
+// insert inserts an Account modified at base plus minutes.
+func (st *syncTest) insert(minutes int, deleted bool) gosf.ID {
+	return gosf.ID(st.srv.Insert("Account", gosftest.Record{
+		"Name":           "Acme",
+		"IsDeleted":      deleted,
+		"SystemModstamp": st.base.Add(time.Duration(minutes) * time.Minute).Format(gosf.DateTimeLayout),
+	}))
+}
+
+func TestSyncResume(t *testing.T) {
+	st := newSyncTest(t)
+	ctx := context.Background()
+	first := st.insert(1, false)
+	same := []gosf.ID{st.insert(2, false), st.insert(2, false), st.insert(2, false)}
+	last := st.insert(3, false)
+
+	// the second batch fails, the checkpoint is at the first one
+	st.handled.fail = 2
+	if err := st.syncer.Sync(ctx); err == nil {
+		t.Fatal("got no error of the handler")
+	}
+	checkpoint, err := st.store.Load("Account")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !checkpoint.SystemModstamp.Equal(st.base.Add(2*time.Minute)) || !reflect.DeepEqual(checkpoint.IDs, same[:1]) {
+		t.Errorf("got checkpoint %+v, want %s at base+2m", checkpoint, same[0])
+	}
+
+	// the records at the modstamp of the checkpoint are not handed again
+	if err = st.syncer.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := []gosf.ID{first, same[0], same[1], same[2], last}
+	if got := st.handled.ids(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err = st.syncer.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := st.handled.ids(); len(got) != len(want) {
+		t.Errorf("got %v handed again", got[len(want):])
+	}
+}
+
+func TestSyncLag(t *testing.T) {
+	st := newSyncTest(t)
+	ctx := context.Background()
+	synced := st.insert(58, false)
+	lagging := st.insert(59, false)
+	st.syncer.Lag = 90 * time.Second
+
+	if err := st.syncer.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := st.handled.ids(); !reflect.DeepEqual(got, []gosf.ID{synced}) {
+		t.Errorf("got %v, want %s only", got, synced)
+	}
+	st.clock = st.clock.Add(time.Minute)
+	if err := st.syncer.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := st.handled.ids(); !reflect.DeepEqual(got, []gosf.ID{synced, lagging}) {
+		t.Errorf("got %v, want %s synced after the lag", got, lagging)
+	}
+}
+
+func TestSyncDeleted(t *testing.T) {
+	st := newSyncTest(t)
+	ctx := context.Background()
+	live := st.insert(1, false)
+	st.insert(2, true) // deleted before the first sync
+
+	if err := st.syncer.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	deleted := []gosf.ID{st.insert(70, true), st.insert(71, true), st.insert(72, true)}
+	st.insert(100, true) // after the lag
+	st.clock = st.base.Add(90 * time.Minute)
+	if err := st.syncer.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := st.handled.ids(), append([]gosf.ID{live}, deleted...); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for _, change := range st.handled.changes[1:] {
+		if !change.Deleted || change.Record != nil || change.SystemModstamp.IsZero() {
+			t.Errorf("got %+v, want a deleted change", change)
+		}
+	}
+	checkpoint, err := st.store.Load("Account")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := st.clock.Add(-DefaultLag); !checkpoint.DeletedUntil.Equal(want) {
+		t.Errorf("got deleted until %s, want %s", checkpoint.DeletedUntil, want)
+	}
+}
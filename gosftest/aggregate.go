@@ -0,0 +1,252 @@
+package gosftest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sidebiequ/gosf/soql"
+)
+
+// group is the records of a group of an aggregate query. fields are the
+// group fields of the grouping set, the others are null in the result.
+type group struct {
+	fields  []*soql.Field
+	records []Record
+}
+
+// isAggregate reports whether the query is an aggregate query.
+func (e *evaluator) isAggregate() bool {
+	if len(e.query.GroupBy) > 0 {
+		return true
+	}
+	for _, item := range e.query.Select {
+		if _, ok := item.(*soql.Aggregate); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// aggregate groups records and returns a record of each group. A COUNT()
+// query returns no records but the count as the totalSize.
+func (e *evaluator) aggregate(records []Record) ([]Record, int, error) {
+	if len(e.query.Select) == 1 {
+		if a, ok := e.query.Select[0].(*soql.Aggregate); ok && a.Func == "COUNT" && a.Field == nil {
+			if len(e.query.GroupBy) > 0 {
+				return nil, 0, malformed("COUNT() can't be used with GROUP BY")
+			}
+			return nil, len(e.page(records)), nil
+		}
+	}
+
+	for _, item := range e.query.Select {
+		switch item := item.(type) {
+		case *soql.Aggregate:
+			if item.Field == nil {
+				return nil, 0, malformed("COUNT() must be the only element in the SELECT list")
+			}
+		case *soql.Field:
+			if !e.isGroupField(item) {
+				return nil, 0, malformed("Field must be grouped or aggregated: %s", item.Name())
+			}
+		default:
+			return nil, 0, malformed("unsupported select item %T in aggregate query", item)
+		}
+	}
+
+	var groups []*group
+	for _, fields := range e.groupingSets() {
+		groups = append(groups, e.groupBy(records, fields)...)
+	}
+
+	if e.query.Having != nil {
+		var having []*group
+		for _, g := range groups {
+			ok, err := e.matchBy(e.query.Having, func(c *soql.Comparison) (interface{}, error) {
+				if c.Aggregate != nil {
+					return e.aggregateValue(c.Aggregate, g.records), nil
+				}
+				if !e.isGroupField(c.Field) {
+					return nil, malformed("Field must be grouped or aggregated: %s", c.Field.Name())
+				}
+				return g.value(e, c.Field), nil
+			})
+			if err != nil {
+				return nil, 0, err
+			}
+			if ok {
+				having = append(having, g)
+			}
+		}
+		groups = having
+	}
+
+	if len(e.query.OrderBy) > 0 {
+		sort.SliceStable(groups, func(i, j int) bool {
+			return e.less(groups[i].first(), groups[j].first())
+		})
+	}
+
+	results := make([]Record, 0, len(groups))
+	for _, g := range groups {
+		out := Record{"attributes": map[string]string{"type": "AggregateResult"}}
+		n := 0
+		for _, item := range e.query.Select {
+			switch item := item.(type) {
+			case *soql.Aggregate:
+				key := item.Alias
+				if key == "" {
+					key = fmt.Sprintf("expr%d", n)
+					n++
+				}
+				out[key] = e.aggregateValue(item, g.records)
+			case *soql.Field:
+				out[item.Path[len(item.Path)-1]] = g.value(e, item)
+			}
+		}
+		results = append(results, out)
+	}
+	results = e.page(results)
+	return results, len(results), nil
+}
+
+// isGroupField reports whether f is one of the GROUP BY fields.
+func (e *evaluator) isGroupField(f *soql.Field) bool {
+	for _, g := range e.query.GroupBy {
+		if strings.EqualFold(strings.Join(e.path(g), "."), strings.Join(e.path(f), ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupingSets returns the sets of fields to group by. It's the GROUP BY
+// fields for plain GROUP BY, with their prefixes for ROLLUP and all of
+// their combinations for CUBE, which are the subtotals.
+func (e *evaluator) groupingSets() [][]*soql.Field {
+	fields := e.query.GroupBy
+	switch e.query.GroupByFunc {
+	case "ROLLUP":
+		sets := make([][]*soql.Field, 0, len(fields)+1)
+		for n := len(fields); n >= 0; n-- {
+			sets = append(sets, fields[:n])
+		}
+		return sets
+	case "CUBE":
+		sets := make([][]*soql.Field, 0, 1<<len(fields))
+		for mask := 1<<len(fields) - 1; mask >= 0; mask-- {
+			var set []*soql.Field
+			for i, f := range fields {
+				if mask&(1<<i) != 0 {
+					set = append(set, f)
+				}
+			}
+			sets = append(sets, set)
+		}
+		return sets
+	default:
+		return [][]*soql.Field{fields}
+	}
+}
+
+// groupBy groups records by the values of fields in order of appearance,
+// strings are grouped case insensitively. Without fields all the records
+// are a group, even if there are none.
+func (e *evaluator) groupBy(records []Record, fields []*soql.Field) []*group {
+	if len(fields) == 0 {
+		return []*group{{records: records}}
+	}
+
+	var groups []*group
+	index := make(map[string]*group)
+	for _, rec := range records {
+		key := make([]string, len(fields))
+		for i, f := range fields {
+			key[i] = strings.ToLower(fmt.Sprintf("%#v", e.value(rec, e.path(f))))
+		}
+		k := strings.Join(key, "\x00")
+		g, ok := index[k]
+		if !ok {
+			g = &group{fields: fields}
+			index[k] = g
+			groups = append(groups, g)
+		}
+		g.records = append(g.records, rec)
+	}
+	return groups
+}
+
+// first returns the first record of the group, or an empty one.
+func (g *group) first() Record {
+	if len(g.records) == 0 {
+		return Record{}
+	}
+	return g.records[0]
+}
+
+// value returns the value of the group field f, null if it's not grouped
+// in this grouping set.
+func (g *group) value(e *evaluator, f *soql.Field) interface{} {
+	for _, grouped := range g.fields {
+		if strings.EqualFold(strings.Join(e.path(grouped), "."), strings.Join(e.path(f), ".")) {
+			return e.value(g.first(), e.path(f))
+		}
+	}
+	return nil
+}
+
+// aggregateValue computes a over records, it's null if there are no values
+// except for counts.
+func (e *evaluator) aggregateValue(a *soql.Aggregate, records []Record) interface{} {
+	if a.Field == nil {
+		return len(records)
+	}
+
+	var values []interface{}
+	for _, rec := range records {
+		if v := e.value(rec, e.path(a.Field)); v != nil {
+			values = append(values, v)
+		}
+	}
+
+	switch a.Func {
+	case "COUNT":
+		return len(values)
+	case "COUNT_DISTINCT":
+		distinct := make(map[string]bool)
+		for _, v := range values {
+			distinct[strings.ToLower(fmt.Sprint(v))] = true
+		}
+		return len(distinct)
+	case "SUM", "AVG":
+		var sum float64
+		n := 0
+		for _, v := range values {
+			if f, ok := toFloat(v); ok {
+				sum += f
+				n++
+			}
+		}
+		switch {
+		case n == 0:
+			return nil
+		case a.Func == "AVG":
+			return sum / float64(n)
+		default:
+			return sum
+		}
+	default: // MIN and MAX
+		var result interface{}
+		for _, v := range values {
+			if result == nil {
+				result = v
+				continue
+			}
+			if cmp := compareValues(v, result); (a.Func == "MIN" && cmp < 0) || (a.Func == "MAX" && cmp > 0) {
+				result = v
+			}
+		}
+		return result
+	}
+}
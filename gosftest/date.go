@@ -0,0 +1,130 @@
+package gosftest
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// period is a unit of date literals.
+type period int
+
+const (
+	day period = iota
+	week
+	month
+	quarter
+	year
+)
+
+// start returns the start of the period t is in, weeks start on sunday and
+// fiscal years are calendar years.
+func (p period) start(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch p {
+	case week:
+		return time.Date(y, m, d-int(t.Weekday()), 0, 0, 0, 0, t.Location())
+	case month:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	case quarter:
+		return time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, t.Location())
+	case year:
+		return time.Date(y, 1, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// add returns t moved by n periods.
+func (p period) add(t time.Time, n int) time.Time {
+	switch p {
+	case week:
+		return t.AddDate(0, 0, 7*n)
+	case month:
+		return t.AddDate(0, n, 0)
+	case quarter:
+		return t.AddDate(0, 3*n, 0)
+	case year:
+		return t.AddDate(n, 0, 0)
+	default:
+		return t.AddDate(0, 0, n)
+	}
+}
+
+var periods = map[string]period{
+	"DAY": day, "DAYS": day,
+	"WEEK": week, "WEEKS": week,
+	"MONTH": month, "MONTHS": month,
+	"QUARTER": quarter, "QUARTERS": quarter, "FISCAL_QUARTER": quarter, "FISCAL_QUARTERS": quarter,
+	"YEAR": year, "YEARS": year, "FISCAL_YEAR": year, "FISCAL_YEARS": year,
+}
+
+var (
+	relativeLiteral = regexp.MustCompile(`^(LAST|THIS|NEXT)_([A-Z_]+)$`)
+	lastNextLiteral = regexp.MustCompile(`^(LAST|NEXT)_N_([A-Z_]+):(\d+)$`)
+	agoLiteral      = regexp.MustCompile(`^N_([A-Z_]+)_AGO:(\d+)$`)
+)
+
+// dateLiteralRange returns the range [start, end) of the date literal
+// relative to now.
+func dateLiteralRange(literal string, now time.Time) (start, end time.Time, ok bool) {
+	literal = strings.ToUpper(literal)
+	switch literal {
+	case "YESTERDAY":
+		literal = "LAST_DAY"
+	case "TODAY":
+		literal = "THIS_DAY"
+	case "TOMORROW":
+		literal = "NEXT_DAY"
+	case "LAST_90_DAYS":
+		literal = "LAST_N_DAYS:90"
+	case "NEXT_90_DAYS":
+		literal = "NEXT_N_DAYS:90"
+	}
+
+	if m := relativeLiteral.FindStringSubmatch(literal); m != nil {
+		p, found := periods[m[2]]
+		if !found {
+			return start, end, false
+		}
+		current := p.start(now)
+		switch m[1] {
+		case "LAST":
+			return p.add(current, -1), current, true
+		case "THIS":
+			return current, p.add(current, 1), true
+		default:
+			return p.add(current, 1), p.add(current, 2), true
+		}
+	}
+
+	if m := lastNextLiteral.FindStringSubmatch(literal); m != nil {
+		p, found := periods[m[2]]
+		n, _ := strconv.Atoi(m[3])
+		if !found {
+			return start, end, false
+		}
+		current := p.start(now)
+		switch {
+		case m[1] == "NEXT":
+			return p.add(current, 1), p.add(current, n+1), true
+		case p == day:
+			// LAST_N_DAYS includes today
+			return p.add(current, -n), p.add(current, 1), true
+		default:
+			return p.add(current, -n), current, true
+		}
+	}
+
+	if m := agoLiteral.FindStringSubmatch(literal); m != nil {
+		p, found := periods[m[1]]
+		n, _ := strconv.Atoi(m[2])
+		if !found {
+			return start, end, false
+		}
+		current := p.start(now)
+		return p.add(current, -n), p.add(current, 1-n), true
+	}
+	return start, end, false
+}
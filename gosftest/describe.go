@@ -0,0 +1,191 @@
+package gosftest
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sidebiequ/gosf"
+)
+
+// systemFields are the fields the store maintains, they can't be updated.
+var systemFields = map[string]bool{
+	"Id":               true,
+	"IsDeleted":        true,
+	"CreatedDate":      true,
+	"LastModifiedDate": true,
+	"SystemModstamp":   true,
+}
+
+func (s *Server) handleDescribe(w http.ResponseWriter, r *http.Request) {
+	name := s.store.name(r.PathValue("sobject"))
+	if s.store.all(name) == nil {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", errNotFound.Error())
+		return
+	}
+	if s.notModified(w, r) {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.describe(r.PathValue("version"), name))
+}
+
+// metadata returns the basic metadata of the sobject t.
+func metadata(version string, t *table) gosf.SobjectMetadata {
+	base := "/services/data/" + version + "/sobjects/" + t.name
+	return gosf.SobjectMetadata{
+		Name:          t.name,
+		Label:         t.name,
+		LabelPlural:   plural(t.name),
+		KeyPrefix:     t.prefix,
+		Custom:        strings.HasSuffix(t.name, "__c"),
+		Createable:    true,
+		Updateable:    true,
+		Deletable:     true,
+		Undeletable:   true,
+		Queryable:     true,
+		Retrieveable:  true,
+		Searchable:    true,
+		Replicateable: true,
+		URLs: map[string]string{
+			"sobject":     base,
+			"describe":    base + "/describe",
+			"rowTemplate": base + "/{ID}",
+		},
+	}
+}
+
+// describe returns the description of sobject inferred from its records:
+// field types are told by the values, string fields hold ids of other
+// sobjects are references, and the references of other sobjects to it are
+// child relationships named like Contacts and Child__r.
+func (s *Server) describe(version, sobjectName string) *gosf.SobjectDescription {
+	var t *table
+	for _, candidate := range s.store.sobjects() {
+		if candidate.name == sobjectName {
+			t = candidate
+		}
+	}
+	description := &gosf.SobjectDescription{
+		SobjectMetadata:    metadata(version, t),
+		Fields:             s.describeFields(sobjectName),
+		ChildRelationships: make([]*gosf.ChildRelationship, 0),
+		RecordTypeInfos: []*gosf.RecordTypeInfo{{
+			RecordTypeID:             "012000000000000AAA",
+			Name:                     "Master",
+			DeveloperName:            "Master",
+			Active:                   true,
+			Available:                true,
+			DefaultRecordTypeMapping: true,
+			Master:                   true,
+		}},
+	}
+
+	for _, child := range s.store.sobjects() {
+		for _, field := range s.describeFields(child.name) {
+			if field.Type != gosf.FieldTypeReference || field.ReferenceTo[0] != sobjectName {
+				continue
+			}
+			description.ChildRelationships = append(description.ChildRelationships, &gosf.ChildRelationship{
+				ChildSobject:     child.name,
+				Field:            field.Name,
+				RelationshipName: plural(child.name),
+			})
+		}
+	}
+	return description
+}
+
+// describeFields infers the fields of sobject from the values of its
+// records, sorted by name with Id first.
+func (s *Server) describeFields(sobjectName string) []*gosf.FieldDescription {
+	fields := make(map[string]*gosf.FieldDescription)
+	for _, rec := range s.store.all(sobjectName) {
+		for name, value := range rec {
+			if field, ok := fields[name]; ok && field.Type != gosf.FieldTypeAnyType {
+				continue
+			}
+			fields[name] = s.describeField(name, value)
+		}
+	}
+
+	described := make([]*gosf.FieldDescription, 0, len(fields))
+	for _, field := range fields {
+		described = append(described, field)
+	}
+	sort.Slice(described, func(i, j int) bool {
+		if described[i].Name == "Id" || described[j].Name == "Id" {
+			return described[i].Name == "Id"
+		}
+		return described[i].Name < described[j].Name
+	})
+	return described
+}
+
+func (s *Server) describeField(name string, value interface{}) *gosf.FieldDescription {
+	system := systemFields[name]
+	field := &gosf.FieldDescription{
+		Name:       name,
+		Label:      name,
+		Type:       gosf.FieldTypeAnyType,
+		SoapType:   "xsd:anyType",
+		Nillable:   !system,
+		Createable: !system,
+		Updateable: !system,
+		Filterable: true,
+		Sortable:   true,
+		Groupable:  true,
+		Custom:     strings.HasSuffix(name, "__c"),
+		NameField:  name == "Name",
+	}
+
+	switch value := value.(type) {
+	case bool:
+		field.Type, field.SoapType = gosf.FieldTypeBoolean, "xsd:boolean"
+		field.Nillable = false
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		field.Type, field.SoapType = gosf.FieldTypeInt, "xsd:int"
+	case float32, float64:
+		field.Type, field.SoapType = gosf.FieldTypeDouble, "xsd:double"
+	case string:
+		field.Type, field.SoapType, field.Length = gosf.FieldTypeString, "xsd:string", 255
+		if name == "Id" {
+			field.Type, field.SoapType, field.Length = gosf.FieldTypeID, "tns:ID", 18
+		} else if parent, ok := s.store.sobjectOf(value); ok {
+			field.Type, field.SoapType, field.Length = gosf.FieldTypeReference, "tns:ID", 18
+			field.ReferenceTo = []string{parent}
+			field.RelationshipName = relationshipName(name)
+		} else if _, err := time.Parse(dateTimeLayout, value); err == nil {
+			field.Type, field.SoapType, field.Length = gosf.FieldTypeDateTime, "xsd:dateTime", 0
+		} else if _, err := time.Parse(gosf.DateLayout, value); err == nil {
+			field.Type, field.SoapType, field.Length = gosf.FieldTypeDate, "xsd:date", 0
+		}
+	}
+	return field
+}
+
+// relationshipName returns the parent relationship of the reference field,
+// like Account for AccountId and Parent__r for Parent__c.
+func relationshipName(field string) string {
+	switch lower := strings.ToLower(field); {
+	case strings.HasSuffix(lower, "__c"):
+		return field[:len(field)-1] + "r"
+	case strings.HasSuffix(lower, "id") && len(field) > 2:
+		return field[:len(field)-2]
+	default:
+		return ""
+	}
+}
+
+// plural returns the child relationship name of sobject, the reverse of
+// evaluator.childSobject.
+func plural(sobjectName string) string {
+	switch lower := strings.ToLower(sobjectName); {
+	case strings.HasSuffix(lower, "__c"):
+		return sobjectName[:len(sobjectName)-1] + "r"
+	case strings.HasSuffix(lower, "y"):
+		return sobjectName[:len(sobjectName)-1] + "ies"
+	default:
+		return sobjectName + "s"
+	}
+}
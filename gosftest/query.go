@@ -0,0 +1,165 @@
+package gosftest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sidebiequ/gosf/soql"
+)
+
+// cursor holds the rest records of a query for the next pages.
+type cursor struct {
+	records []Record
+	total   int
+}
+
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	if explain := r.URL.Query().Get("explain"); explain != "" {
+		s.handleExplain(w, r, explain)
+		return
+	}
+
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		writeError(w, http.StatusBadRequest, "MALFORMED_QUERY", "No query string provided")
+		return
+	}
+
+	query, err := soql.Parse(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "MALFORMED_QUERY", err.Error())
+		return
+	}
+
+	e := &evaluator{
+		store:   s.store,
+		version: r.PathValue("version"),
+		query:   query,
+		all:     strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/queryAll"),
+	}
+	records, total, err := e.evaluate()
+	if err != nil {
+		if qerr, ok := err.(*queryError); ok {
+			writeError(w, http.StatusBadRequest, qerr.errorCode, qerr.message)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "UNKNOWN_EXCEPTION", err.Error())
+		return
+	}
+	s.writeQueryPage(w, r, &cursor{
+		records: records,
+		total:   total,
+	})
+}
+
+// handleExplain responds the plan of query. The Server only has the Id
+// index: a query filters by Id leads with Index, others with TableScan.
+func (s *Server) handleExplain(w http.ResponseWriter, r *http.Request, q string) {
+	query, err := soql.Parse(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "MALFORMED_QUERY", err.Error())
+		return
+	}
+	e := &evaluator{
+		store:   s.store,
+		version: r.PathValue("version"),
+		query:   query,
+	}
+	_, cardinality, err := e.evaluate()
+	if err != nil {
+		if qerr, ok := err.(*queryError); ok {
+			writeError(w, http.StatusBadRequest, qerr.errorCode, qerr.message)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "UNKNOWN_EXCEPTION", err.Error())
+		return
+	}
+
+	sobjectCardinality := 0
+	for _, rec := range s.store.all(e.from) {
+		if !isDeleted(rec) {
+			sobjectCardinality++
+		}
+	}
+	plan := map[string]interface{}{
+		"cardinality":          cardinality,
+		"fields":               []string{},
+		"leadingOperationType": "TableScan",
+		"notes":                []interface{}{},
+		"relativeCost":         1.0,
+		"sobjectCardinality":   sobjectCardinality,
+		"sobjectType":          e.from,
+	}
+	if filtersByID(query.Where) {
+		plan["fields"] = []string{"Id"}
+		plan["leadingOperationType"] = "Index"
+		plan["relativeCost"] = 0.0
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"plans":       []interface{}{plan},
+		"sourceQuery": q,
+	})
+}
+
+// filtersByID reports whether expr requires Id to be some values.
+func filtersByID(expr soql.Expr) bool {
+	switch expr := expr.(type) {
+	case *soql.And:
+		for _, sub := range expr.Exprs {
+			if filtersByID(sub) {
+				return true
+			}
+		}
+	case *soql.Comparison:
+		return expr.Field != nil && strings.EqualFold(expr.Field.Name(), "Id") && (expr.Op == "=" || expr.Op == "IN")
+	}
+	return false
+}
+
+func (s *Server) handleQueryMore(w http.ResponseWriter, r *http.Request) {
+	locator := r.PathValue("locator")
+	s.mu.Lock()
+	c, ok := s.cursors[locator]
+	delete(s.cursors, locator)
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusBadRequest, "INVALID_QUERY_LOCATOR", "invalid query locator")
+		return
+	}
+	s.writeQueryPage(w, r, c)
+}
+
+// writeQueryPage responds the first page of c, and keeps the rest for the
+// nextRecordsUrl if there are more than BatchSize records.
+func (s *Server) writeQueryPage(w http.ResponseWriter, r *http.Request, c *cursor) {
+	batchSize := s.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	body := map[string]interface{}{
+		"totalSize": c.total,
+		"done":      true,
+	}
+	page := c.records
+	if len(page) > batchSize {
+		page = c.records[:batchSize]
+		rest := &cursor{
+			records: c.records[batchSize:],
+			total:   c.total,
+		}
+
+		s.mu.Lock()
+		s.seq++
+		locator := fmt.Sprintf("01gGOSFTEST%07d-%d", s.seq, c.total-len(rest.records))
+		s.cursors[locator] = rest
+		s.mu.Unlock()
+
+		body["done"] = false
+		body["nextRecordsUrl"] = fmt.Sprintf("/services/data/%s/query/%s", r.PathValue("version"), locator)
+	}
+	body["records"] = page
+	writeJSON(w, http.StatusOK, body)
+}
@@ -0,0 +1,107 @@
+package gosftest
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sidebiequ/gosf"
+)
+
+// replicationRange parses the start and end parameters of a replication
+// request, it responds 400 if they're invalid or the range is longer than
+// gosf.MaxReplicationRange.
+func replicationRange(w http.ResponseWriter, r *http.Request) (start, end time.Time, ok bool) {
+	query := r.URL.Query()
+	startDT, err := gosf.ParseDateTime(query.Get("start"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REPLICATION_DATE", "start is not a valid datetime")
+		return
+	}
+	endDT, err := gosf.ParseDateTime(query.Get("end"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REPLICATION_DATE", "end is not a valid datetime")
+		return
+	}
+	start, end = startDT.Time, endDT.Time
+	switch {
+	case !end.After(start):
+		writeError(w, http.StatusBadRequest, "INVALID_REPLICATION_DATE", "end must be after start")
+	case end.Sub(start) > gosf.MaxReplicationRange:
+		writeError(w, http.StatusBadRequest, "INVALID_REPLICATION_DATE", "the range must not exceed 30 days")
+	default:
+		ok = true
+	}
+	return
+}
+
+// inRange returns true if at is in the range from start to end, both ends
+// included like salesforce does, so a record at the end of a range is
+// reported by the next range too.
+func inRange(at, start, end time.Time) bool {
+	return !at.Before(start) && !at.After(end)
+}
+
+// modstamp returns SystemModstamp of rec, zero if it's missing.
+func modstamp(rec Record) time.Time {
+	v, _ := rec["SystemModstamp"].(string)
+	t, _ := time.Parse(dateTimeLayout, v)
+	return t
+}
+
+// coveredUntil returns latestDateCovered of a range ends at end, it's
+// truncated to minutes and never later than now.
+func (s *Server) coveredUntil(end time.Time) gosf.DateTime {
+	if now := s.store.now(); end.After(now) {
+		end = now
+	}
+	return gosf.DateTime{Time: end.UTC().Truncate(time.Minute)}
+}
+
+// handleUpdated responds the ids of live records modified in the range.
+func (s *Server) handleUpdated(w http.ResponseWriter, r *http.Request) {
+	sobjectName := s.store.name(r.PathValue("sobject"))
+	start, end, ok := replicationRange(w, r)
+	if !ok {
+		return
+	}
+
+	result := &gosf.UpdatedResult{IDs: make([]gosf.ID, 0)}
+	for _, rec := range s.store.all(sobjectName) {
+		if at := modstamp(rec); !isDeleted(rec) && inRange(at, start, end) {
+			result.IDs = append(result.IDs, gosf.ID(rec["Id"].(string)))
+		}
+	}
+	result.LatestDateCovered = s.coveredUntil(end)
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleDeleted responds the records deleted in the range, the deleted date
+// of a record is its last SystemModstamp.
+func (s *Server) handleDeleted(w http.ResponseWriter, r *http.Request) {
+	sobjectName := s.store.name(r.PathValue("sobject"))
+	start, end, ok := replicationRange(w, r)
+	if !ok {
+		return
+	}
+
+	result := &gosf.DeletedResult{DeletedRecords: make([]*gosf.DeletedRecord, 0)}
+	earliest := start
+	for _, rec := range s.store.all(sobjectName) {
+		if !isDeleted(rec) {
+			continue
+		}
+		at := modstamp(rec)
+		if at.Before(earliest) {
+			earliest = at
+		}
+		if inRange(at, start, end) {
+			result.DeletedRecords = append(result.DeletedRecords, &gosf.DeletedRecord{
+				ID:          gosf.ID(rec["Id"].(string)),
+				DeletedDate: gosf.DateTime{Time: at},
+			})
+		}
+	}
+	result.EarliestDateAvailable = gosf.DateTime{Time: earliest.UTC().Truncate(time.Minute)}
+	result.LatestDateCovered = s.coveredUntil(end)
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,430 @@
+package gosftest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sidebiequ/gosf/soql"
+)
+
+// search is a parsed SOSL search or parameterized search.
+type search struct {
+	// terms are the OR-ed groups of AND-ed term patterns.
+	terms [][]*regexp.Regexp
+	group string
+	// returning are the queries of the sobjects returned, all sobjects are
+	// searched for the ids if it's empty.
+	returning []*soql.Query
+	limit     int
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		writeError(w, http.StatusBadRequest, "MALFORMED_SEARCH", "No search string provided")
+		return
+	}
+	parsed, err := parseSOSL(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "MALFORMED_SEARCH", err.Error())
+		return
+	}
+	s.writeSearch(w, r, parsed)
+}
+
+func (s *Server) handleParameterizedSearch(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Q        string `json:"q"`
+		In       string `json:"in"`
+		Sobjects []struct {
+			Name    string   `json:"name"`
+			Fields  []string `json:"fields"`
+			Where   string   `json:"where"`
+			OrderBy string   `json:"orderBy"`
+			Limit   int      `json:"limit"`
+		} `json:"sobjects"`
+		OverallLimit int `json:"overallLimit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "JSON_PARSER_ERROR", err.Error())
+		return
+	}
+	if strings.TrimSpace(body.Q) == "" {
+		writeError(w, http.StatusBadRequest, "MISSING_ARGUMENT", "Missing required 'q' parameter")
+		return
+	}
+
+	parsed := &search{
+		terms: parseTerms(body.Q, false),
+		group: strings.ToUpper(body.In),
+		limit: body.OverallLimit,
+	}
+	for _, sobject := range body.Sobjects {
+		fields := "Id"
+		if len(sobject.Fields) > 0 {
+			fields = strings.Join(sobject.Fields, ",")
+		}
+		statment := fmt.Sprintf("SELECT %s FROM %s", fields, sobject.Name)
+		if sobject.Where != "" {
+			statment += " WHERE " + sobject.Where
+		}
+		if sobject.OrderBy != "" {
+			statment += " ORDER BY " + sobject.OrderBy
+		}
+		if sobject.Limit > 0 {
+			statment += " LIMIT " + strconv.Itoa(sobject.Limit)
+		}
+		query, err := soql.Parse(statment)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_SEARCH", err.Error())
+			return
+		}
+		parsed.returning = append(parsed.returning, query)
+	}
+	s.writeSearch(w, r, parsed)
+}
+
+// writeSearch responds the records found by parsed in the searchRecords
+// shape of api v37.0 and later.
+func (s *Server) writeSearch(w http.ResponseWriter, r *http.Request, parsed *search) {
+	returning := parsed.returning
+	if len(returning) == 0 {
+		for _, t := range s.store.sobjects() {
+			returning = append(returning, &soql.Query{
+				Select: []soql.SelectItem{&soql.Field{Path: []string{"Id"}}},
+				From:   t.name,
+			})
+		}
+	}
+
+	records := make([]Record, 0)
+	for _, query := range returning {
+		e := &evaluator{
+			store:   s.store,
+			version: r.PathValue("version"),
+			query:   query,
+			from:    s.store.name(query.From),
+		}
+		var candidates []Record
+		for _, rec := range s.store.all(e.from) {
+			if parsed.match(rec) {
+				candidates = append(candidates, rec)
+			}
+		}
+		found, _, err := e.evaluateRecords(candidates)
+		if err != nil {
+			if qerr, ok := err.(*queryError); ok {
+				writeError(w, http.StatusBadRequest, "INVALID_SEARCH", qerr.message)
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "UNKNOWN_EXCEPTION", err.Error())
+			return
+		}
+		records = append(records, found...)
+	}
+	if parsed.limit > 0 && len(records) > parsed.limit {
+		records = records[:parsed.limit]
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"searchRecords": records,
+	})
+}
+
+// match reports whether any field of rec in the search group matches the
+// terms.
+func (p *search) match(rec Record) bool {
+	var values []string
+	for field, v := range rec {
+		str, ok := v.(string)
+		if !ok || !inGroup(field, p.group) {
+			continue
+		}
+		values = append(values, str)
+	}
+
+	for _, all := range p.terms {
+		matched := true
+		for _, term := range all {
+			found := false
+			for _, value := range values {
+				if term.MatchString(value) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// inGroup reports whether field is searched in the search group.
+func inGroup(field, group string) bool {
+	lower := strings.ToLower(field)
+	if lower == "id" || lower == "attributes" || strings.HasSuffix(lower, "id") {
+		return false
+	}
+	switch strings.TrimSuffix(group, " FIELDS") {
+	case "NAME", "SIDEBAR":
+		return strings.HasSuffix(lower, "name")
+	case "EMAIL":
+		return strings.Contains(lower, "email")
+	case "PHONE":
+		return strings.Contains(lower, "phone")
+	default:
+		return true
+	}
+}
+
+// parseSOSL parses the SOSL statment q:
+// FIND {<TERM>} [IN <GROUP>] [RETURNING <SOBJECT>[(<FIELDS> [<CLAUSES>])] [,...]] [LIMIT n]
+func parseSOSL(q string) (*search, error) {
+	rest := strings.TrimSpace(q)
+	if len(rest) < 4 || !strings.EqualFold(rest[:4], "FIND") {
+		return nil, fmt.Errorf("search statment should start with FIND: %s", q)
+	}
+	rest = strings.TrimSpace(rest[4:])
+	if !strings.HasPrefix(rest, "{") {
+		return nil, fmt.Errorf("search term should be in braces: %s", q)
+	}
+	end := closingBrace(rest)
+	if end < 0 {
+		return nil, fmt.Errorf("unclosed search term: %s", q)
+	}
+	parsed := &search{terms: parseTerms(rest[1:end], true)}
+	rest = strings.TrimSpace(rest[end+1:])
+
+	if after, ok := cutKeyword(rest, "IN"); ok {
+		fields := strings.Index(strings.ToUpper(after), "FIELDS")
+		if fields < 0 {
+			return nil, fmt.Errorf("unexpected search group: %s", after)
+		}
+		parsed.group = strings.ToUpper(strings.TrimSpace(after[:fields])) + " FIELDS"
+		rest = strings.TrimSpace(after[fields+len("FIELDS"):])
+	}
+
+	if after, ok := cutKeyword(rest, "RETURNING"); ok {
+		var err error
+		if parsed.returning, rest, err = parseReturning(after); err != nil {
+			return nil, err
+		}
+	}
+
+	if after, ok := cutKeyword(rest, "LIMIT"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(after))
+		if err != nil {
+			return nil, fmt.Errorf("unexpected limit: %s", after)
+		}
+		parsed.limit = n
+		rest = ""
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("unexpected token: %s", rest)
+	}
+	return parsed, nil
+}
+
+// closingBrace returns the index of the brace closes the one s starts with,
+// skipping escaped characters.
+func closingBrace(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '}':
+			return i
+		}
+	}
+	return -1
+}
+
+// cutKeyword cuts the keyword s starts with, case insensitively.
+func cutKeyword(s, keyword string) (after string, ok bool) {
+	if len(s) <= len(keyword) || !strings.EqualFold(s[:len(keyword)], keyword) || s[len(keyword)] != ' ' {
+		return s, false
+	}
+	return strings.TrimSpace(s[len(keyword):]), true
+}
+
+var returningName = regexp.MustCompile(`^\w+`)
+
+// parseReturning parses the sobjects of RETURNING and returns the rest of s.
+func parseReturning(s string) (queries []*soql.Query, rest string, err error) {
+	rest = s
+	for {
+		name := returningName.FindString(rest)
+		if name == "" {
+			return nil, rest, fmt.Errorf("missing sobject of RETURNING: %s", s)
+		}
+		rest = strings.TrimSpace(rest[len(name):])
+
+		statment := "SELECT Id FROM " + name
+		if strings.HasPrefix(rest, "(") {
+			end := closingParen(rest)
+			if end < 0 {
+				return nil, rest, fmt.Errorf("unclosed fields of %s", name)
+			}
+			fields, clauses := splitClauses(rest[1:end])
+			statment = fmt.Sprintf("SELECT %s FROM %s %s", fields, name, clauses)
+			rest = strings.TrimSpace(rest[end+1:])
+		}
+		query, perr := soql.Parse(statment)
+		if perr != nil {
+			return nil, rest, perr
+		}
+		queries = append(queries, query)
+
+		if !strings.HasPrefix(rest, ",") {
+			return
+		}
+		rest = strings.TrimSpace(rest[1:])
+	}
+}
+
+// closingParen returns the index of the parenthesis closes the one s starts
+// with, skipping string literals.
+func closingParen(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			for i++; i < len(s) && s[i] != '\''; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+var clauseKeyword = regexp.MustCompile(`(?i)^\s(WHERE|ORDER\s+BY|LIMIT|OFFSET)\s`)
+
+// splitClauses splits the fields of a RETURNING sobject from its clauses.
+func splitClauses(s string) (fields, clauses string) {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '\'':
+			quoted = !quoted
+		case !quoted:
+			if clauseKeyword.MatchString(s[i:]) {
+				return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i:])
+			}
+		}
+	}
+	return strings.TrimSpace(s), ""
+}
+
+// parseTerms parses the search expression into OR-ed groups of AND-ed term
+// patterns. Terms match whole words case insensitively, * and ? are
+// wildcards; escaped reserved characters are unescaped if escaped is true.
+// Parentheses and AND NOT are not supported.
+func parseTerms(expr string, escaped bool) [][]*regexp.Regexp {
+	var terms [][]*regexp.Regexp
+	for _, or := range splitOperator(expr, "OR") {
+		var all []*regexp.Regexp
+		for _, term := range splitOperator(or, "AND") {
+			if term = strings.Trim(strings.TrimSpace(term), `"`); term != "" {
+				all = append(all, termPattern(term, escaped))
+			}
+		}
+		if len(all) > 0 {
+			terms = append(terms, all)
+		}
+	}
+	return terms
+}
+
+func splitOperator(expr, operator string) []string {
+	return regexp.MustCompile(`\s+`+operator+`\s+`).Split(expr, -1)
+}
+
+// termPattern returns the pattern matches term as whole words.
+func termPattern(term string, escaped bool) *regexp.Regexp {
+	var pattern strings.Builder
+	for i := 0; i < len(term); i++ {
+		c := term[i]
+		switch {
+		case c == '\\' && escaped && i+1 < len(term):
+			i++
+			pattern.WriteString(regexp.QuoteMeta(term[i : i+1]))
+		case c == '*':
+			pattern.WriteString(`\S*`)
+		case c == '?':
+			pattern.WriteString(`\S`)
+		default:
+			pattern.WriteString(regexp.QuoteMeta(term[i : i+1]))
+		}
+	}
+	return regexp.MustCompile(`(?i)(^|\W)` + pattern.String() + `($|\W)`)
+}
+
+func (s *Server) handleSearchSuggestions(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	term, sobjectName := params.Get("q"), params.Get("sobject")
+	if term == "" || sobjectName == "" {
+		writeError(w, http.StatusBadRequest, "MISSING_ARGUMENT", "Missing required 'q' or 'sobject' parameter")
+		return
+	}
+	limit := 5
+	if n, err := strconv.Atoi(params.Get("limit")); err == nil && n > 0 {
+		limit = n
+	}
+
+	name := s.store.name(sobjectName)
+	e := &evaluator{store: s.store, version: r.PathValue("version"), from: name}
+	lower := strings.ToLower(term)
+	records := make([]Record, 0)
+	hasMore := false
+	for _, rec := range s.store.all(name) {
+		value, _ := rec.Get("Name")
+		str, _ := value.(string)
+		if isDeleted(rec) || !strings.Contains(strings.ToLower(str), lower) {
+			continue
+		}
+		if len(records) == limit {
+			hasMore = true
+			break
+		}
+		out := e.attributes(name, rec)
+		out["Id"] = rec["Id"]
+		out["Name"] = str
+		records = append(records, out)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"autoSuggestResults": records,
+		"hasMoreResults":     hasMore,
+	})
+}
+
+func (s *Server) handleSearchScopeOrder(w http.ResponseWriter, r *http.Request) {
+	base := "/services/data/" + r.PathValue("version") + "/sobjects/"
+	scopes := make([]map[string]string, 0)
+	for _, t := range s.store.sobjects() {
+		scopes = append(scopes, map[string]string{
+			"type": t.name,
+			"url":  base + t.name,
+		})
+	}
+	writeJSON(w, http.StatusOK, scopes)
+}
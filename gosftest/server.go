@@ -0,0 +1,435 @@
+// Package gosftest provides an in-process fake salesforce server for
+// testing code uses gosf.Client end to end:
+//
+//	srv := gosftest.NewServer()
+//	defer srv.Close()
+//
+//	id := srv.Insert("Account", gosftest.Record{"Name": "Acme"})
+//	client := srv.Client()
+//	result, err := client.QuerySobject(gosf.NewOpQuery("Account").Select("Id", "Name"))
+//
+// Records live in memory, ids are generated 18-char salesforce ids with key
+// prefixes of the sobjects. Deleted records stay with IsDeleted true, only
+// queryAll can see them. Errors are responded in salesforce's json shape.
+package gosftest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/sidebiequ/gosf"
+)
+
+// Credentials the Server accepts in the token exchange.
+const (
+	ClientID     = "gosftest-client-id"
+	ClientSecret = "gosftest-client-secret"
+	Username     = "gosftest@example.com"
+	Password     = "gosftest-password"
+)
+
+const (
+	// defaultBatchSize is the max records per query response page.
+	defaultBatchSize = 2000
+	// accessToken is the token the Server issues.
+	accessToken = "00Dgosftest!gosftest-access-token"
+)
+
+// Server is a fake salesforce server.
+type Server struct {
+	*httptest.Server
+
+	// BatchSize is the max number of records per query response page.
+	// Set it before querying to test paging.
+	BatchSize int
+	// Versions are the api versions the Server supports.
+	Versions []gosf.APIVersion
+
+	store   *store
+	mu      sync.Mutex
+	cursors map[string]*cursor
+	seq     int
+}
+
+// NewServer starts and returns a Server, the caller should Close it.
+func NewServer() *Server {
+	s := &Server{
+		BatchSize: defaultBatchSize,
+		store:     newStore(),
+		cursors:   make(map[string]*cursor),
+	}
+	for v := 20; v <= 66; v++ {
+		s.Versions = append(s.Versions, gosf.APIVersion(v))
+	}
+	s.Server = httptest.NewServer(s.handler())
+	return s
+}
+
+// LatestVersion returns the newest api version the Server supports.
+func (s *Server) LatestVersion() gosf.APIVersion {
+	return s.Versions[len(s.Versions)-1]
+}
+
+// Config returns a gosf.Config authenticates with the Server.
+func (s *Server) Config() *gosf.Config {
+	return &gosf.Config{
+		Host:         s.URL,
+		ClientID:     ClientID,
+		ClientSecret: ClientSecret,
+		Username:     Username,
+		Password:     Password,
+		ExpiresIn:    3600,
+		APIVersion:   s.LatestVersion(),
+	}
+}
+
+// Client returns a gosf.Client connects to the Server and discards logs.
+func (s *Server) Client() *gosf.Client {
+	return gosf.NewClient(s.Config(), gosf.NewSlogLogger(slog.New(slog.DiscardHandler)))
+}
+
+// Insert stores a record of sobject and returns its id.
+func (s *Server) Insert(sobjectName string, record Record) string {
+	return s.store.insert(sobjectName, record)["Id"].(string)
+}
+
+// Get returns a copy of the record of sobject by id, deleted records are
+// not found.
+func (s *Server) Get(sobjectName, id string) (Record, bool) {
+	rec, err := s.store.get(sobjectName, id)
+	return rec, err == nil
+}
+
+// Records returns copies of all records of sobject in insertion order,
+// deleted records are included with IsDeleted true.
+func (s *Server) Records(sobjectName string) []Record {
+	return s.store.all(sobjectName)
+}
+
+/************************************/
+/************* HANDLERS *************/
+/************************************/
+
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /services/oauth2/token", s.handleToken)
+	mux.HandleFunc("GET /services/data", s.handleVersions)
+	mux.HandleFunc("GET /services/data/{$}", s.handleVersions)
+
+	api := http.NewServeMux()
+	api.HandleFunc("GET /services/data/{version}", s.handleResources)
+	api.HandleFunc("GET /services/data/{version}/{$}", s.handleResources)
+	api.HandleFunc("GET /services/data/{version}/sobjects", s.handleSobjects)
+	api.HandleFunc("GET /services/data/{version}/sobjects/{$}", s.handleSobjects)
+	api.HandleFunc("POST /services/data/{version}/sobjects/{sobject}", s.handleCreate)
+	api.HandleFunc("POST /services/data/{version}/sobjects/{sobject}/{$}", s.handleCreate)
+	api.HandleFunc("GET /services/data/{version}/sobjects/{sobject}/describe", s.handleDescribe)
+	api.HandleFunc("GET /services/data/{version}/sobjects/{sobject}/describe/{$}", s.handleDescribe)
+	api.HandleFunc("GET /services/data/{version}/sobjects/{sobject}/updated", s.handleUpdated)
+	api.HandleFunc("GET /services/data/{version}/sobjects/{sobject}/updated/{$}", s.handleUpdated)
+	api.HandleFunc("GET /services/data/{version}/sobjects/{sobject}/deleted", s.handleDeleted)
+	api.HandleFunc("GET /services/data/{version}/sobjects/{sobject}/deleted/{$}", s.handleDeleted)
+	api.HandleFunc("GET /services/data/{version}/sobjects/{sobject}/{id}", s.handleGet)
+	api.HandleFunc("GET /services/data/{version}/sobjects/{sobject}/{field}/{value}", s.handleGetByExternalID)
+	api.HandleFunc("PATCH /services/data/{version}/sobjects/{sobject}/{id}", s.handleUpdate)
+	api.HandleFunc("DELETE /services/data/{version}/sobjects/{sobject}/{id}", s.handleDelete)
+	api.HandleFunc("GET /services/data/{version}/query", s.handleQuery)
+	api.HandleFunc("GET /services/data/{version}/query/{$}", s.handleQuery)
+	api.HandleFunc("GET /services/data/{version}/query/{locator}", s.handleQueryMore)
+	api.HandleFunc("GET /services/data/{version}/queryAll", s.handleQuery)
+	api.HandleFunc("GET /services/data/{version}/queryAll/{$}", s.handleQuery)
+	api.HandleFunc("GET /services/data/{version}/queryAll/{locator}", s.handleQueryMore)
+	api.HandleFunc("GET /services/data/{version}/search", s.handleSearch)
+	api.HandleFunc("GET /services/data/{version}/search/{$}", s.handleSearch)
+	api.HandleFunc("GET /services/data/{version}/search/suggestions", s.handleSearchSuggestions)
+	api.HandleFunc("GET /services/data/{version}/search/scopeOrder", s.handleSearchScopeOrder)
+	api.HandleFunc("POST /services/data/{version}/parameterizedSearch", s.handleParameterizedSearch)
+	api.HandleFunc("POST /services/data/{version}/parameterizedSearch/{$}", s.handleParameterizedSearch)
+	mux.Handle("/services/data/", s.authorize(api))
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+	})
+	return mux
+}
+
+// authorize rejects requests without the issued token or with an
+// unsupported api version.
+func (s *Server) authorize(next *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+accessToken {
+			writeError(w, http.StatusUnauthorized, "INVALID_SESSION_ID", "Session expired or invalid")
+			return
+		}
+
+		_, pattern := next.Handler(r)
+		if pattern == "" {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+			return
+		}
+		segments := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/services/data/"), "/", 2)
+		if !s.supports(segments[0]) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) supports(version string) bool {
+	if !strings.HasPrefix(version, "v") {
+		return false
+	}
+	v, err := gosf.ParseAPIVersion(version)
+	if err != nil {
+		return false
+	}
+	for _, supported := range s.Versions {
+		if v == supported {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, "invalid_request", err.Error())
+		return
+	}
+	switch {
+	case r.PostForm.Get("grant_type") != "password":
+		writeOAuthError(w, "unsupported_grant_type", "grant type not supported")
+	case r.PostForm.Get("client_id") != ClientID || r.PostForm.Get("client_secret") != ClientSecret:
+		writeOAuthError(w, "invalid_client_id", "client identifier invalid")
+	case r.PostForm.Get("username") != Username || r.PostForm.Get("password") != Password:
+		writeOAuthError(w, "invalid_grant", "authentication failure")
+	default:
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"access_token": accessToken,
+			"instance_url": s.URL,
+			"id":           s.URL + "/id/00Dgosftest/005gosftest",
+			"token_type":   "Bearer",
+			"issued_at":    fmt.Sprint(s.store.now().UnixMilli()),
+			"signature":    "gosftest-signature",
+		})
+	}
+}
+
+func (s *Server) handleVersions(w http.ResponseWriter, r *http.Request) {
+	versions := make([]*gosf.Version, 0, len(s.Versions))
+	for _, v := range s.Versions {
+		versions = append(versions, &gosf.Version{
+			Label:   "gosftest",
+			URL:     "/services/data/v" + v.String(),
+			Version: v.String(),
+		})
+	}
+	writeJSON(w, http.StatusOK, versions)
+}
+
+func (s *Server) handleResources(w http.ResponseWriter, r *http.Request) {
+	base := "/services/data/" + r.PathValue("version")
+	writeJSON(w, http.StatusOK, map[string]string{
+		"sobjects": base + "/sobjects",
+		"query":    base + "/query",
+		"queryAll": base + "/queryAll",
+		"search":   base + "/search",
+
+		"parameterizedSearch": base + "/parameterizedSearch",
+	})
+}
+
+func (s *Server) handleSobjects(w http.ResponseWriter, r *http.Request) {
+	if s.notModified(w, r) {
+		return
+	}
+	sobjects := make([]*gosf.SobjectMetadata, 0)
+	for _, t := range s.store.sobjects() {
+		sobject := metadata(r.PathValue("version"), t)
+		sobjects = append(sobjects, &sobject)
+	}
+	writeJSON(w, http.StatusOK, &gosf.DescribeGlobalResult{
+		Encoding:     "UTF-8",
+		MaxBatchSize: 200,
+		Sobjects:     sobjects,
+	})
+}
+
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	fields, ok := decodeRecord(w, r)
+	if !ok {
+		return
+	}
+	rec := s.store.insert(r.PathValue("sobject"), fields)
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"id":      rec["Id"],
+		"success": true,
+		"errors":  []interface{}{},
+	})
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	sobjectName := r.PathValue("sobject")
+	rec, err := s.store.get(sobjectName, r.PathValue("id"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	s.writeRecord(w, r, s.store.name(sobjectName), rec)
+}
+
+// handleGetByExternalID responds the record whose field equals the value,
+// or 300 with the URLs of the records if there are more than one.
+func (s *Server) handleGetByExternalID(w http.ResponseWriter, r *http.Request) {
+	sobjectName := s.store.name(r.PathValue("sobject"))
+	field, value := r.PathValue("field"), r.PathValue("value")
+	if !s.store.hasField(sobjectName, field) {
+		writeError(w, http.StatusBadRequest, "INVALID_FIELD", fmt.Sprintf("No such column '%s' on entity '%s'", field, sobjectName))
+		return
+	}
+
+	var found []Record
+	for _, rec := range s.store.all(sobjectName) {
+		if v, ok := rec.Get(field); ok && v != nil && !isDeleted(rec) && fmt.Sprint(v) == value {
+			found = append(found, rec)
+		}
+	}
+	switch len(found) {
+	case 0:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", errNotFound.Error())
+	case 1:
+		s.writeRecord(w, r, sobjectName, found[0])
+	default:
+		urls := make([]string, 0, len(found))
+		for _, rec := range found {
+			urls = append(urls, fmt.Sprintf("/services/data/%s/sobjects/%s/%s", r.PathValue("version"), sobjectName, rec["Id"]))
+		}
+		writeJSON(w, http.StatusMultipleChoices, urls)
+	}
+}
+
+// writeRecord responds rec with the attributes envelope, only the fields in
+// the fields parameter if it's set.
+func (s *Server) writeRecord(w http.ResponseWriter, r *http.Request, sobjectName string, rec Record) {
+	if fields := r.URL.Query().Get("fields"); fields != "" {
+		projected := Record{"Id": rec["Id"]}
+		for _, field := range strings.Split(fields, ",") {
+			field = strings.TrimSpace(field)
+			if !s.store.hasField(sobjectName, field) {
+				writeError(w, http.StatusBadRequest, "INVALID_FIELD", fmt.Sprintf("No such column '%s' on entity '%s'", field, sobjectName))
+				return
+			}
+			v, _ := rec.Get(field)
+			projected[rec.key(field)] = v
+		}
+		rec = projected
+	}
+	writeJSON(w, http.StatusOK, withAttributes(r, sobjectName, rec["Id"].(string), rec))
+}
+
+func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	fields, ok := decodeRecord(w, r)
+	if !ok {
+		return
+	}
+	if err := s.store.update(r.PathValue("sobject"), r.PathValue("id"), fields); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if err := s.store.delete(r.PathValue("sobject"), r.PathValue("id")); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// notModified responds 304 if the schema is not modified since the
+// If-Modified-Since header of r, otherwise sets the Last-Modified header.
+func (s *Server) notModified(w http.ResponseWriter, r *http.Request) bool {
+	modified := s.store.modified()
+	if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !modified.After(since) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+	return false
+}
+
+// writeStoreError responds the error of the store.
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch err {
+	case errDeleted:
+		writeError(w, http.StatusNotFound, "ENTITY_IS_DELETED", err.Error())
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", err.Error())
+	}
+}
+
+// withAttributes returns a copy of rec with the attributes envelope.
+func withAttributes(r *http.Request, sobjectName, id string, rec Record) Record {
+	out := rec.copy()
+	out["attributes"] = map[string]string{
+		"type": sobjectName,
+		"url":  fmt.Sprintf("/services/data/%s/sobjects/%s/%s", r.PathValue("version"), sobjectName, id),
+	}
+	return out
+}
+
+/************************************/
+/************* HELPERS **************/
+/************************************/
+
+// decodeRecord decodes the json body of r into a Record, responds an error
+// and returns false if fail.
+func decodeRecord(w http.ResponseWriter, r *http.Request) (Record, bool) {
+	byts, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "JSON_PARSER_ERROR", err.Error())
+		return nil, false
+	}
+
+	var fields Record
+	if err = json.Unmarshal(byts, &fields); err != nil || fields == nil {
+		writeError(w, http.StatusBadRequest, "JSON_PARSER_ERROR", "The request body is not a json object")
+		return nil, false
+	}
+	return fields, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Sforce-Limit-Info", "api-usage=1/15000")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError responds an api error in salesforce's shape:
+//
+//	[{"message": "...", "errorCode": "..."}]
+func writeError(w http.ResponseWriter, status int, errorCode, message string) {
+	writeJSON(w, status, []map[string]string{{
+		"message":   message,
+		"errorCode": errorCode,
+	}})
+}
+
+// writeOAuthError responds an oauth error in salesforce's shape:
+//
+//	{"error": "...", "error_description": "..."}
+func writeOAuthError(w http.ResponseWriter, errorCode, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{
+		"error":             errorCode,
+		"error_description": description,
+	})
+}
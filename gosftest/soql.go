@@ -0,0 +1,610 @@
+package gosftest
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sidebiequ/gosf/soql"
+)
+
+// queryError is an error of a query responded with errorCode.
+type queryError struct {
+	errorCode string
+	message   string
+}
+
+func (e *queryError) Error() string {
+	return e.errorCode + ": " + e.message
+}
+
+func malformed(format string, args ...interface{}) *queryError {
+	return &queryError{errorCode: "MALFORMED_QUERY", message: fmt.Sprintf(format, args...)}
+}
+
+// evaluator evaluates a parsed query against the store.
+type evaluator struct {
+	store   *store
+	version string
+	query   *soql.Query
+	from    string
+	// all is true for queryAll, which includes deleted records.
+	all bool
+}
+
+// evaluate returns the records match the query, projected to the selected
+// fields with attributes envelopes, and the totalSize of the result.
+func (e *evaluator) evaluate() ([]Record, int, error) {
+	e.from = e.store.name(e.query.From)
+	return e.evaluateRecords(e.store.all(e.from))
+}
+
+// evaluateRecords filters, sorts and projects records of e.from.
+func (e *evaluator) evaluateRecords(all []Record) ([]Record, int, error) {
+	var records []Record
+	for _, rec := range all {
+		if isDeleted(rec) && !e.all {
+			continue
+		}
+		if e.query.Where != nil {
+			ok, err := e.match(rec, e.query.Where)
+			if err != nil {
+				return nil, 0, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		records = append(records, rec)
+	}
+
+	if e.isAggregate() {
+		return e.aggregate(records)
+	}
+	e.sort(records)
+	records = e.page(records)
+
+	projected := make([]Record, 0, len(records))
+	for _, rec := range records {
+		out := e.attributes(e.from, rec)
+		for _, item := range e.query.Select {
+			switch item := item.(type) {
+			case *soql.Field:
+				e.project(out, rec, e.path(item))
+			case *soql.TypeOf:
+				e.projectTypeOf(out, rec, item)
+			case *soql.Subquery:
+				children, err := e.children(rec, item.Query)
+				if err != nil {
+					return nil, 0, err
+				}
+				out[item.Query.From] = children
+			default:
+				return nil, 0, malformed("unsupported select item %T", item)
+			}
+		}
+		projected = append(projected, out)
+	}
+	return projected, len(projected), nil
+}
+
+// page applies OFFSET and LIMIT to records.
+func (e *evaluator) page(records []Record) []Record {
+	if offset := e.query.Offset; offset != nil {
+		if *offset >= len(records) {
+			return nil
+		}
+		records = records[*offset:]
+	}
+	if limit := e.query.Limit; limit != nil && *limit < len(records) {
+		records = records[:*limit]
+	}
+	return records
+}
+
+// children evaluates the child relationship subquery q for the parent rec,
+// the result is null if there are no child records, as salesforce does.
+func (e *evaluator) children(rec Record, q *soql.Query) (interface{}, error) {
+	name, ok := e.childSobject(q.From)
+	if !ok {
+		return nil, malformed("Didn't understand relationship '%s' in FROM part of query call", q.From)
+	}
+
+	var related []Record
+	for _, child := range e.store.all(name) {
+		if isChildOf(child, rec["Id"]) {
+			related = append(related, child)
+		}
+	}
+
+	sub := &evaluator{store: e.store, version: e.version, query: q, from: name, all: e.all}
+	records, _, err := sub.evaluateRecords(related)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return Record{
+		"totalSize": len(records),
+		"done":      true,
+		"records":   records,
+	}, nil
+}
+
+// childSobject returns the sobject of a child relationship by its name,
+// like Contact for Contacts, Opportunity for Opportunities and Child__c
+// for Child__r.
+func (e *evaluator) childSobject(relationship string) (string, bool) {
+	lower := strings.ToLower(relationship)
+	var candidates []string
+	switch {
+	case strings.HasSuffix(lower, "__r"):
+		candidates = append(candidates, relationship[:len(relationship)-1]+"c")
+	case strings.HasSuffix(lower, "ies"):
+		candidates = append(candidates, relationship[:len(relationship)-3]+"y")
+		fallthrough
+	case strings.HasSuffix(lower, "s"):
+		candidates = append(candidates, relationship[:len(relationship)-1])
+	}
+	for _, candidate := range candidates {
+		if name := e.store.name(candidate); e.store.all(name) != nil {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// isChildOf reports whether child refers to the parent id by any of its
+// lookup fields.
+func isChildOf(child Record, id interface{}) bool {
+	for field, v := range child {
+		lower := strings.ToLower(field)
+		if lower == "id" || !(strings.HasSuffix(lower, "id") || strings.HasSuffix(lower, "__c")) {
+			continue
+		}
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// path returns the path of field relative to the queried sobject, the
+// sobject name prefix like Account.Name in FROM Account is removed.
+func (e *evaluator) path(f *soql.Field) []string {
+	if len(f.Path) > 1 && strings.EqualFold(f.Path[0], e.from) {
+		return f.Path[1:]
+	}
+	return f.Path
+}
+
+func (e *evaluator) attributes(sobjectName string, rec Record) Record {
+	return Record{
+		"attributes": map[string]string{
+			"type": sobjectName,
+			"url":  fmt.Sprintf("/services/data/%s/sobjects/%s/%s", e.version, sobjectName, rec["Id"]),
+		},
+	}
+}
+
+// lookupField returns the field holds the id of the parent relationship,
+// like AccountId for Account and Owner__c for Owner__r.
+func lookupField(relationship string) string {
+	if strings.HasSuffix(strings.ToLower(relationship), "__r") {
+		return relationship[:len(relationship)-1] + "c"
+	}
+	return relationship + "Id"
+}
+
+// parent returns the parent record of rec by relationship name.
+func (e *evaluator) parent(rec Record, relationship string) (string, Record, bool) {
+	id, _ := rec.Get(lookupField(relationship))
+	str, ok := id.(string)
+	if !ok || str == "" {
+		return "", nil, false
+	}
+	return e.store.byID(str)
+}
+
+// value resolves the value of field path on rec, following parent relationships.
+func (e *evaluator) value(rec Record, path []string) interface{} {
+	for len(path) > 1 {
+		_, parent, ok := e.parent(rec, path[0])
+		if !ok {
+			return nil
+		}
+		rec, path = parent, path[1:]
+	}
+	v, _ := rec.Get(path[0])
+	return v
+}
+
+// project copies the value of field path from rec to out, parent
+// relationships become nested records.
+func (e *evaluator) project(out, rec Record, path []string) {
+	if len(path) == 1 {
+		v, _ := rec.Get(path[0])
+		out[rec.key(path[0])] = v
+		return
+	}
+
+	relationship := path[0]
+	name, parent, ok := e.parent(rec, relationship)
+	if !ok {
+		if _, exists := out[relationship]; !exists {
+			out[relationship] = nil
+		}
+		return
+	}
+	nested, _ := out[relationship].(Record)
+	if nested == nil {
+		nested = e.attributes(name, parent)
+		out[relationship] = nested
+	}
+	e.project(nested, parent, path[1:])
+}
+
+// projectTypeOf copies the fields of the polymorphic relationship chosen by
+// the type of the parent record from rec to out.
+func (e *evaluator) projectTypeOf(out, rec Record, t *soql.TypeOf) {
+	relationship := e.path(t.Field)
+	var name string
+	for len(relationship) > 0 {
+		var ok bool
+		if name, rec, ok = e.parent(rec, relationship[0]); !ok {
+			out[relationship[0]] = nil
+			return
+		}
+		relationship = relationship[1:]
+	}
+
+	fields := t.Else
+	for _, when := range t.Whens {
+		if strings.EqualFold(when.Sobject, name) {
+			fields = when.Fields
+			break
+		}
+	}
+	nested := e.attributes(name, rec)
+	for _, f := range fields {
+		e.project(nested, rec, f.Path)
+	}
+	out[t.Field.Name()] = nested
+}
+
+/************************************/
+/************** WHERE ***************/
+/************************************/
+
+func (e *evaluator) match(rec Record, expr soql.Expr) (bool, error) {
+	return e.matchBy(expr, func(c *soql.Comparison) (interface{}, error) {
+		if c.Field == nil {
+			return nil, malformed("aggregate %s is not allowed in WHERE", c.Aggregate.Expr())
+		}
+		return e.value(rec, e.path(c.Field)), nil
+	})
+}
+
+// matchBy evaluates expr with the compared values resolved by value.
+func (e *evaluator) matchBy(expr soql.Expr, value func(*soql.Comparison) (interface{}, error)) (bool, error) {
+	switch expr := expr.(type) {
+	case *soql.And:
+		for _, sub := range expr.Exprs {
+			if ok, err := e.matchBy(sub, value); err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case *soql.Or:
+		for _, sub := range expr.Exprs {
+			if ok, err := e.matchBy(sub, value); err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case *soql.Not:
+		ok, err := e.matchBy(expr.Expr, value)
+		return !ok, err
+	case *soql.Comparison:
+		v, err := value(expr)
+		if err != nil {
+			return false, err
+		}
+		return e.compare(v, expr)
+	default:
+		return false, malformed("unsupported condition %T", expr)
+	}
+}
+
+func (e *evaluator) compare(v interface{}, c *soql.Comparison) (bool, error) {
+	switch c.Op {
+	case "IN", "NOT IN":
+		in := false
+		for _, lit := range c.Values {
+			cmp, ok, err := e.compareLiteral(v, lit)
+			if err != nil {
+				return false, err
+			}
+			if ok && cmp == 0 {
+				in = true
+				break
+			}
+		}
+		return in == (c.Op == "IN"), nil
+
+	case "LIKE":
+		str, ok := v.(string)
+		if !ok {
+			return false, nil
+		}
+		return likePattern(c.Value.Text).MatchString(str), nil
+	}
+
+	if c.Value.Kind == soql.Null {
+		switch c.Op {
+		case "=":
+			return v == nil, nil
+		case "!=":
+			return v != nil, nil
+		default:
+			return false, malformed("invalid operator %s for null", c.Op)
+		}
+	}
+
+	cmp, ok, err := e.compareLiteral(v, c.Value)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		// nulls and values of other types only satisfy !=
+		return c.Op == "!=", nil
+	}
+	switch c.Op {
+	case "=":
+		return cmp == 0, nil
+	case "!=":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	default:
+		return false, malformed("unsupported operator %s", c.Op)
+	}
+}
+
+// compareLiteral compares v with lit, ok is false if they are not comparable.
+// A value is equal to a date literal if it's in the range of the literal.
+func (e *evaluator) compareLiteral(v interface{}, lit soql.Literal) (cmp int, ok bool, err error) {
+	if v == nil || lit.Kind == soql.Null {
+		return 0, v == nil && lit.Kind == soql.Null, nil
+	}
+
+	switch lit.Kind {
+	case soql.String:
+		str, isStr := v.(string)
+		if !isStr {
+			return 0, false, nil
+		}
+		return strings.Compare(strings.ToLower(str), strings.ToLower(lit.Text)), true, nil
+
+	case soql.Number:
+		f, isNum := toFloat(v)
+		if !isNum {
+			return 0, false, nil
+		}
+		n, _ := strconv.ParseFloat(lit.Text, 64)
+		return compareFloat(f, n), true, nil
+
+	case soql.Boolean:
+		b, isBool := v.(bool)
+		if !isBool {
+			return 0, false, nil
+		}
+		return compareBool(b, lit.Text == "true"), true, nil
+
+	case soql.Date, soql.DateTime:
+		t, isTime := toTime(v)
+		if !isTime {
+			return 0, false, nil
+		}
+		lt, isTime := toTime(lit.Text)
+		if !isTime {
+			return 0, false, malformed("invalid date %s", lit.Text)
+		}
+		if lit.Kind == soql.Date {
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		}
+		return t.Compare(lt), true, nil
+
+	case soql.DateLiteral:
+		start, end, valid := dateLiteralRange(lit.Text, e.store.now().UTC())
+		if !valid {
+			return 0, false, malformed("unknown date literal %s", lit.Text)
+		}
+		t, isTime := toTime(v)
+		switch {
+		case !isTime:
+			return 0, false, nil
+		case t.Before(start):
+			return -1, true, nil
+		case t.Before(end):
+			return 0, true, nil
+		default:
+			return 1, true, nil
+		}
+
+	default:
+		return 0, false, malformed("unsupported literal %s", lit.Text)
+	}
+}
+
+// compareValues orders two record values, nils are equal to each other.
+func compareValues(a, b interface{}) int {
+	if fa, ok := toFloat(a); ok {
+		if fb, ok := toFloat(b); ok {
+			return compareFloat(fa, fb)
+		}
+	}
+	if ba, ok := a.(bool); ok {
+		if bb, ok := b.(bool); ok {
+			return compareBool(ba, bb)
+		}
+	}
+	if ta, ok := toTime(a); ok {
+		if tb, ok := toTime(b); ok {
+			return ta.Compare(tb)
+		}
+	}
+	return strings.Compare(strings.ToLower(fmt.Sprint(a)), strings.ToLower(fmt.Sprint(b)))
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case b:
+		return -1
+	default:
+		return 1
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case interface{ Float64() (float64, error) }:
+		f, err := v.Float64()
+		return f, err == nil && !math.IsNaN(f)
+	default:
+		return 0, false
+	}
+}
+
+// timeLayouts are the formats dates and datetimes are stored or written in.
+var timeLayouts = []string{
+	dateTimeLayout,
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05.000Z07:00",
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02",
+}
+
+func toTime(v interface{}) (time.Time, bool) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// likePattern converts a LIKE pattern to a case insensitive regexp,
+// % matches any characters and _ matches one, \% and \_ match themselves.
+func likePattern(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?is)^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case r == '\\' && i+1 < len(runes):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case r == '%':
+			b.WriteString(".*")
+		case r == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+/************************************/
+/************ ORDER BY **************/
+/************************************/
+
+func (e *evaluator) sort(records []Record) {
+	if len(e.query.OrderBy) == 0 {
+		return
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return e.less(records[i], records[j])
+	})
+}
+
+// less reports whether record x sorts before y by the ORDER BY items.
+func (e *evaluator) less(x, y Record) bool {
+	for _, item := range e.query.OrderBy {
+		path := e.path(item.Field)
+		a, b := e.value(x, path), e.value(y, path)
+
+		// nulls come first by default in ascending order, last in descending
+		nullsFirst := item.Nulls == "FIRST" || (item.Nulls == "" && !item.Desc)
+		switch {
+		case a == nil && b == nil:
+			continue
+		case a == nil:
+			return nullsFirst
+		case b == nil:
+			return !nullsFirst
+		}
+
+		cmp := compareValues(a, b)
+		if cmp == 0 {
+			continue
+		}
+		if item.Desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package gosftest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sidebiequ/gosf/soql"
+)
+
+// newQueryStore returns a store of accounts with owners for the queries.
+func newQueryStore() *store {
+	s := newStore()
+	boss := s.insert("User", Record{"Name": "Boss"})["Id"]
+	s.insert("Account", Record{"Name": "Acme", "Industry": "Energy", "Rating": 3, "OwnerId": boss})
+	s.insert("Account", Record{"Name": "Acme 100%", "Industry": "Media", "Rating": 1})
+	s.insert("Account", Record{"Name": "Bolt_Co", "Industry": nil, "Rating": 2, "OwnerId": boss})
+	s.insert("Account", Record{"Name": "BoltXCo", "Industry": "Energy", "Rating": nil})
+	s.insert("Account", Record{"Name": "Globex", "Industry": "Retail", "Rating": 5})
+	return s
+}
+
+func TestEvaluate(t *testing.T) {
+	s := newQueryStore()
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"all in insertion order", "SELECT Name FROM Account", []string{"Acme", "Acme 100%", "Bolt_Co", "BoltXCo", "Globex"}},
+		{"and", "SELECT Name FROM Account WHERE Industry = 'Energy' AND Rating > 1", []string{"Acme"}},
+		{"or", "SELECT Name FROM Account WHERE Industry = 'Media' OR Rating >= 5", []string{"Acme 100%", "Globex"}},
+		{"grouped or and", "SELECT Name FROM Account WHERE Name = 'Globex' OR (Industry = 'Energy' AND Rating = 3)", []string{"Acme", "Globex"}},
+		{"not", "SELECT Name FROM Account WHERE NOT (Industry = 'Energy' OR Industry = null)", []string{"Acme 100%", "Globex"}},
+		{"equal ignores case", "SELECT Name FROM Account WHERE Name = 'ACME'", []string{"Acme"}},
+		{"like percent", "SELECT Name FROM Account WHERE Name LIKE 'acme%'", []string{"Acme", "Acme 100%"}},
+		{"like underscore", "SELECT Name FROM Account WHERE Name LIKE 'Bolt_Co'", []string{"Bolt_Co", "BoltXCo"}},
+		{"like escaped underscore", `SELECT Name FROM Account WHERE Name LIKE 'Bolt\_Co'`, []string{"Bolt_Co"}},
+		{"like escaped percent", `SELECT Name FROM Account WHERE Name LIKE '%100\%'`, []string{"Acme 100%"}},
+		{"in", "SELECT Name FROM Account WHERE Industry IN ('Media', 'Retail')", []string{"Acme 100%", "Globex"}},
+		{"not in skips nulls", "SELECT Name FROM Account WHERE Rating NOT IN (1, 2, 3)", []string{"BoltXCo", "Globex"}},
+		{"equal null", "SELECT Name FROM Account WHERE Industry = null", []string{"Bolt_Co"}},
+		{"not equal null", "SELECT Name FROM Account WHERE Rating != null", []string{"Acme", "Acme 100%", "Bolt_Co", "Globex"}},
+		{"not equal includes nulls", "SELECT Name FROM Account WHERE Industry != 'Energy'", []string{"Acme 100%", "Bolt_Co", "Globex"}},
+		{"order asc nulls first", "SELECT Name FROM Account ORDER BY Rating", []string{"BoltXCo", "Acme 100%", "Bolt_Co", "Acme", "Globex"}},
+		{"order desc nulls last", "SELECT Name FROM Account ORDER BY Rating DESC", []string{"Globex", "Acme", "Bolt_Co", "Acme 100%", "BoltXCo"}},
+		{"order asc nulls last", "SELECT Name FROM Account ORDER BY Rating ASC NULLS LAST", []string{"Acme 100%", "Bolt_Co", "Acme", "Globex", "BoltXCo"}},
+		{"order desc nulls first", "SELECT Name FROM Account ORDER BY Rating DESC NULLS FIRST", []string{"BoltXCo", "Globex", "Acme", "Bolt_Co", "Acme 100%"}},
+		{"order by columns", "SELECT Name FROM Account ORDER BY Industry DESC NULLS LAST, Name DESC", []string{"Globex", "Acme 100%", "BoltXCo", "Acme", "Bolt_Co"}},
+		{"limit", "SELECT Name FROM Account ORDER BY Name LIMIT 2", []string{"Acme", "Acme 100%"}},
+		{"offset", "SELECT Name FROM Account ORDER BY Name LIMIT 2 OFFSET 3", []string{"BoltXCo", "Globex"}},
+		{"offset past the end", "SELECT Name FROM Account OFFSET 10", nil},
+		{"parent field", "SELECT Name FROM Account WHERE Owner.Name = 'Boss' ORDER BY Name DESC", []string{"Bolt_Co", "Acme"}},
+		{"parent field null", "SELECT Name FROM Account WHERE Owner.Name = null", []string{"Acme 100%", "BoltXCo", "Globex"}},
+		{"order by parent field", "SELECT Name FROM Account WHERE Rating < 4 ORDER BY Owner.Name NULLS LAST, Name", []string{"Acme", "Bolt_Co", "Acme 100%"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := evaluateQuery(t, s, tt.query)
+			var got []string
+			for _, rec := range records {
+				got = append(got, rec["Name"].(string))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateParentRelationships(t *testing.T) {
+	records := evaluateQuery(t, newQueryStore(), "SELECT Name, Owner.Name FROM Account WHERE Rating IN (1, 2)")
+	if len(records) != 2 {
+		t.Fatalf("got %v", records)
+	}
+	if records[0]["Owner"] != nil {
+		t.Errorf("got owner %v of Acme 100%%, want null", records[0]["Owner"])
+	}
+	owner, _ := records[1]["Owner"].(Record)
+	attributes, _ := owner["attributes"].(map[string]string)
+	if owner["Name"] != "Boss" || attributes["type"] != "User" {
+		t.Errorf("got owner %v of Bolt_Co, want the User Boss", records[1]["Owner"])
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	s := newQueryStore()
+	for _, q := range []string{
+		"SELECT Name FROM Account WHERE Rating > null",
+		"SELECT Name FROM Account WHERE COUNT(Id) > 1",
+	} {
+		query, err := soql.Parse(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := &evaluator{store: s, version: "v66.0", query: query}
+		if _, _, err = e.evaluate(); err == nil {
+			t.Errorf("%s: got no error", q)
+		}
+	}
+}
+
+func evaluateQuery(t *testing.T, s *store, q string) []Record {
+	t.Helper()
+	query, err := soql.Parse(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &evaluator{store: s, version: "v66.0", query: query}
+	records, total, err := e.evaluate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != len(records) {
+		t.Errorf("got totalSize %d of %d records", total, len(records))
+	}
+	return records
+}
@@ -0,0 +1,379 @@
+package gosftest
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sidebiequ/gosf"
+)
+
+// dateTimeLayout is the format salesforce renders datetime fields in.
+const dateTimeLayout = "2006-01-02T15:04:05.000-0700"
+
+// Record is a sobject record stored in the Server, keyed by field name.
+type Record map[string]interface{}
+
+// Get returns the value of field, field names are case insensitive.
+func (r Record) Get(field string) (interface{}, bool) {
+	if v, ok := r[field]; ok {
+		return v, true
+	}
+	for k, v := range r {
+		if strings.EqualFold(k, field) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// key returns the stored name of field, or field itself if missing.
+func (r Record) key(field string) string {
+	if _, ok := r[field]; ok {
+		return field
+	}
+	for k := range r {
+		if strings.EqualFold(k, field) {
+			return k
+		}
+	}
+	return field
+}
+
+// copy returns a shallow copy of r.
+func (r Record) copy() Record {
+	c := make(Record, len(r))
+	for k, v := range r {
+		c[k] = v
+	}
+	return c
+}
+
+/************************************/
+/************** STORE ***************/
+/************************************/
+
+// store holds the records of all sobjects in memory.
+type store struct {
+	mu       sync.RWMutex
+	tables   map[string]*table // keyed by lower case sobject name
+	prefixes map[string]string // key prefix to sobject name
+	seq      int64
+	now      func() time.Time
+	// schemaModified is when a sobject or field was added last, in
+	// seconds like Last-Modified headers.
+	schemaModified time.Time
+}
+
+// table holds the records of a sobject in insertion order.
+type table struct {
+	name    string
+	prefix  string
+	records map[string]Record
+	ids     []string
+	fields  map[string]bool // lower case field names
+}
+
+// Errors of the store, they are responded as NOT_FOUND and ENTITY_IS_DELETED.
+var (
+	errNotFound = errors.New("The requested resource does not exist")
+	errDeleted  = errors.New("entity is deleted")
+)
+
+func newStore() *store {
+	return &store{
+		tables:   make(map[string]*table),
+		prefixes: make(map[string]string),
+		now:      time.Now,
+	}
+}
+
+// table returns the table of sobject, creates it if create is true.
+// The caller must hold the write lock to create.
+func (s *store) table(sobjectName string, create bool) *table {
+	t, ok := s.tables[strings.ToLower(sobjectName)]
+	if ok || !create {
+		return t
+	}
+
+	prefix := s.newKeyPrefix(sobjectName)
+	t = &table{
+		name:    sobjectName,
+		prefix:  prefix,
+		records: make(map[string]Record),
+		fields:  make(map[string]bool),
+	}
+	s.tables[strings.ToLower(sobjectName)] = t
+	s.prefixes[prefix] = sobjectName
+	return t
+}
+
+// name returns the sobject name as it was created, or sobjectName itself
+// if it doesn't exist.
+func (s *store) name(sobjectName string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if t := s.table(sobjectName, false); t != nil {
+		return t.name
+	}
+	return sobjectName
+}
+
+// byID finds the record by id in all sobjects, the key prefix of id tells
+// which sobject it belongs to.
+func (s *store) byID(id string) (string, Record, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	name, ok := s.prefixes[gosf.ID(id).KeyPrefix()]
+	if !ok {
+		return "", nil, false
+	}
+	rec, ok := s.lookup(name, id)
+	if !ok {
+		return "", nil, false
+	}
+	return name, rec.copy(), true
+}
+
+// sobjectOf returns the sobject of the record with id, if any.
+func (s *store) sobjectOf(id string) (string, bool) {
+	if gosf.ID(id).Validate() != nil {
+		return "", false
+	}
+	name, _, ok := s.byID(id)
+	return name, ok
+}
+
+func (s *store) insert(sobjectName string, fields Record) Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.table(sobjectName, true)
+	s.seq++
+	id := newID(t.prefix, s.seq)
+	now := s.now().UTC().Format(dateTimeLayout)
+
+	rec := Record{
+		"Id":               id,
+		"IsDeleted":        false,
+		"CreatedDate":      now,
+		"LastModifiedDate": now,
+		"SystemModstamp":   now,
+	}
+	for k, v := range fields {
+		if strings.EqualFold(k, "Id") {
+			continue
+		}
+		rec[rec.key(k)] = v
+	}
+	t.records[id] = rec
+	t.ids = append(t.ids, id)
+	s.addFields(t, rec)
+	return rec.copy()
+}
+
+// get returns a copy of the record, deleted records are not found.
+func (s *store) get(sobjectName, id string) (Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, err := s.live(sobjectName, id)
+	if err != nil {
+		return nil, err
+	}
+	return rec.copy(), nil
+}
+
+// live finds the record not deleted, the caller must hold the lock.
+func (s *store) live(sobjectName, id string) (Record, error) {
+	rec, ok := s.lookup(sobjectName, id)
+	switch {
+	case !ok:
+		return nil, errNotFound
+	case isDeleted(rec):
+		return nil, errDeleted
+	default:
+		return rec, nil
+	}
+}
+
+// lookup finds the record by 15 or 18 char id, the caller must hold the lock.
+func (s *store) lookup(sobjectName, id string) (Record, bool) {
+	t := s.table(sobjectName, false)
+	if t == nil {
+		return nil, false
+	}
+	rec, ok := t.records[string(gosf.ID(id).To18())]
+	return rec, ok
+}
+
+func (s *store) update(sobjectName, id string, fields Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, err := s.live(sobjectName, id)
+	if err != nil {
+		return err
+	}
+	for k, v := range fields {
+		if strings.EqualFold(k, "Id") {
+			continue
+		}
+		rec[rec.key(k)] = v
+	}
+	s.touch(rec)
+	s.addFields(s.table(sobjectName, false), rec)
+	return nil
+}
+
+// addFields adds the fields of rec to t, the schema is modified if any of
+// them is new. The caller must hold the write lock.
+func (s *store) addFields(t *table, rec Record) {
+	modified := false
+	for field := range rec {
+		if lower := strings.ToLower(field); !t.fields[lower] {
+			t.fields[lower] = true
+			modified = true
+		}
+	}
+	if !modified {
+		return
+	}
+	// each modification moves to a later second, so If-Modified-Since of
+	// the former one can't hide it
+	now := s.now().UTC().Truncate(time.Second)
+	if !now.After(s.schemaModified) {
+		now = s.schemaModified.Add(time.Second)
+	}
+	s.schemaModified = now
+}
+
+// hasField reports whether any record of sobject has had field.
+func (s *store) hasField(sobjectName, field string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t := s.table(sobjectName, false)
+	return t != nil && t.fields[strings.ToLower(field)]
+}
+
+// modified returns when the schema was modified last.
+func (s *store) modified() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.schemaModified
+}
+
+// delete moves the record to the recycle bin, it's kept with IsDeleted
+// true and only queryAll can see it.
+func (s *store) delete(sobjectName, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, err := s.live(sobjectName, id)
+	if err != nil {
+		return err
+	}
+	rec["IsDeleted"] = true
+	s.touch(rec)
+	return nil
+}
+
+// touch updates the modified dates of rec, the caller must hold the write lock.
+func (s *store) touch(rec Record) {
+	now := s.now().UTC().Format(dateTimeLayout)
+	rec["LastModifiedDate"] = now
+	rec["SystemModstamp"] = now
+}
+
+func isDeleted(rec Record) bool {
+	deleted, _ := rec["IsDeleted"].(bool)
+	return deleted
+}
+
+// all returns copies of the records of sobject in insertion order,
+// including the deleted ones.
+func (s *store) all(sobjectName string) []Record {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t := s.table(sobjectName, false)
+	if t == nil {
+		return nil
+	}
+	records := make([]Record, 0, len(t.ids))
+	for _, id := range t.ids {
+		records = append(records, t.records[id].copy())
+	}
+	return records
+}
+
+// sobjects returns the sorted names of sobjects have been created.
+func (s *store) sobjects() []*table {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tables := make([]*table, 0, len(s.tables))
+	for _, t := range s.tables {
+		tables = append(tables, t)
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].name < tables[j].name })
+	return tables
+}
+
+/************************************/
+/**************** ID ****************/
+/************************************/
+
+// standardPrefixes are the key prefixes of common standard sobjects.
+var standardPrefixes = map[string]string{
+	"account":     "001",
+	"note":        "002",
+	"contact":     "003",
+	"user":        "005",
+	"opportunity": "006",
+	"lead":        "00Q",
+	"task":        "00T",
+	"event":       "00U",
+	"attachment":  "00P",
+	"case":        "500",
+	"campaign":    "701",
+	"contract":    "800",
+	"order":       "801",
+	"pricebook2":  "01s",
+	"product2":    "01t",
+}
+
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// newKeyPrefix returns the key prefix for sobject. Standard sobjects use
+// their real prefixes, others get "a00", "a01"... like custom objects.
+// The caller must hold the write lock.
+func (s *store) newKeyPrefix(sobjectName string) string {
+	if prefix, ok := standardPrefixes[strings.ToLower(sobjectName)]; ok {
+		if _, used := s.prefixes[prefix]; !used {
+			return prefix
+		}
+	}
+	for n := 0; ; n++ {
+		prefix := "a" + string(base62[n/62%62]) + string(base62[n%62])
+		if _, used := s.prefixes[prefix]; !used {
+			return prefix
+		}
+	}
+}
+
+// newID returns an 18-char id with key prefix and sequence number.
+func newID(prefix string, seq int64) string {
+	// prefix(3) + pod(2) + reserved(1) + sequence(9)
+	id := []byte(prefix + "000000000000")
+	for i := len(id) - 1; i >= 6 && seq > 0; i-- {
+		id[i] = base62[seq%62]
+		seq /= 62
+	}
+	return string(gosf.ID(id).To18())
+}
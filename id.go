@@ -0,0 +1,170 @@
+package gosf
+
+import (
+	"fmt"
+	"strings"
+)
+
+/************************************/
+/*************** IDS ****************/
+/************************************/
+
+// idChecksumChars are the chars of the 3-char checksum of 18-char ids, each
+// tells which of the 5 chars of a chunk are upper case.
+const idChecksumChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ012345"
+
+// ID is a salesforce record id. Ids are 15-char and case sensitive, like
+// the ones of reports and the UI, or 18-char with a checksum of the case,
+// like the ones of the API. Compare ids by Equal, it compares the 15-char
+// and 18-char forms of the same record as equal.
+//
+// ID decodes from json and text by ParseID, so it's validated and always
+// the 18-char form.
+type ID string
+
+// ParseID parses a 15-char or 18-char id, it returns the 18-char form.
+// The case of an 18-char id all in upper or lower case is restored by its
+// checksum, so an id cased by a case insensitive system is still parsed.
+func ParseID(s string) (ID, error) {
+	switch len(s) {
+	case 15:
+		if err := checkIDChars(s[:15]); err != nil {
+			return "", fmt.Errorf("invalid id %q: %w", s, err)
+		}
+		return ID(s + idChecksum(s)), nil
+	case 18:
+		if err := checkIDChars(s[:15]); err != nil {
+			return "", fmt.Errorf("invalid id %q: %w", s, err)
+		}
+		id, ok := restoreIDCase(s)
+		if !ok {
+			return "", fmt.Errorf("invalid id %q: checksum mismatch", s)
+		}
+		return ID(id), nil
+	default:
+		return "", fmt.Errorf("invalid id %q: expect 15 or 18 chars, got %d", s, len(s))
+	}
+}
+
+// Validate returns an error if id is not a valid 15-char or 18-char id.
+func (id ID) Validate() error {
+	_, err := ParseID(string(id))
+	return err
+}
+
+// To15 returns the 15-char form of id.
+func (id ID) To15() ID {
+	if len(id) != 18 {
+		return id
+	}
+	if parsed, err := ParseID(string(id)); err == nil {
+		return parsed[:15]
+	}
+	return id[:15]
+}
+
+// To18 returns the 18-char form of id, invalid ids are returned as is.
+func (id ID) To18() ID {
+	parsed, err := ParseID(string(id))
+	if err != nil {
+		return id
+	}
+	return parsed
+}
+
+// KeyPrefix returns the first 3 chars of id which tell its sobject, see
+// DescribeGlobalResult.SobjectOf.
+func (id ID) KeyPrefix() string {
+	if len(id) < 3 {
+		return ""
+	}
+	return string(id[:3])
+}
+
+// Equal returns true if id and other are the same record, in either the
+// 15-char or 18-char form.
+func (id ID) Equal(other ID) bool {
+	return id.To18() == other.To18()
+}
+
+// IsZero returns true if id is empty.
+func (id ID) IsZero() bool {
+	return id == ""
+}
+
+// String returns id.
+func (id ID) String() string {
+	return string(id)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, an empty text is the
+// empty ID.
+func (id *ID) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*id = ""
+		return nil
+	}
+	parsed, err := ParseID(string(text))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+func checkIDChars(s string) error {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !('0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+			return fmt.Errorf("unexpected char %q", c)
+		}
+	}
+	return nil
+}
+
+// idChecksum returns the 3-char checksum of the 15-char id.
+func idChecksum(id string) string {
+	var checksum [3]byte
+	for chunk := 0; chunk < 3; chunk++ {
+		flags := 0
+		for i := 0; i < 5; i++ {
+			if c := id[chunk*5+i]; 'A' <= c && c <= 'Z' {
+				flags |= 1 << uint(i)
+			}
+		}
+		checksum[chunk] = idChecksumChars[flags]
+	}
+	return string(checksum[:])
+}
+
+// restoreIDCase returns the 18-char id with the case of its first 15 chars
+// told by the checksum. The checksum must fit the id, unless the letters of
+// the id are all in the same case, then the case has been lost and it's
+// restored. ok is false if the checksum doesn't fit.
+func restoreIDCase(id string) (restored string, ok bool) {
+	checksum := strings.ToUpper(id[15:])
+	if idChecksum(id[:15]) == checksum {
+		return id[:15] + checksum, true
+	}
+	if id[:15] != strings.ToUpper(id[:15]) && id[:15] != strings.ToLower(id[:15]) {
+		return "", false
+	}
+
+	byts := []byte(strings.ToLower(id[:15]))
+	for chunk := 0; chunk < 3; chunk++ {
+		flags := strings.IndexByte(idChecksumChars, checksum[chunk])
+		if flags < 0 {
+			return "", false
+		}
+		for i := 0; i < 5; i++ {
+			if flags&(1<<uint(i)) == 0 {
+				continue
+			}
+			c := byts[chunk*5+i]
+			if c < 'a' || c > 'z' {
+				return "", false
+			}
+			byts[chunk*5+i] = c - 'a' + 'A'
+		}
+	}
+	return string(byts) + checksum, true
+}
@@ -0,0 +1,145 @@
+package gosf
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// knownIDs are 15-char ids and their 18-char forms by salesforce.
+var knownIDs = []struct {
+	id15, id18 string
+}{
+	{"001A0000006Vm9r", "001A0000006Vm9rIAC"},
+	{"0015000000Gv7qJ", "0015000000Gv7qJAAR"},
+	{"005000000000000", "005000000000000AAA"},
+}
+
+func TestIDChecksum(t *testing.T) {
+	for _, tt := range knownIDs {
+		if got := idChecksum(tt.id15); got != tt.id18[15:] {
+			t.Errorf("idChecksum(%s) = %s, want %s", tt.id15, got, tt.id18[15:])
+		}
+	}
+}
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want ID
+		err  string
+	}{
+		{"15 chars", "001A0000006Vm9r", "001A0000006Vm9rIAC", ""},
+		{"18 chars", "0015000000Gv7qJAAR", "0015000000Gv7qJAAR", ""},
+		{"lower case checksum", "001A0000006Vm9riac", "001A0000006Vm9rIAC", ""},
+		{"all lower case restored", "001a0000006vm9riac", "001A0000006Vm9rIAC", ""},
+		{"all upper case restored", "0015000000GV7QJAAR", "0015000000Gv7qJAAR", ""},
+		{"all lower case of many upper restored", "0015000000gv7qjaar", "0015000000Gv7qJAAR", ""},
+		{"bad checksum", "001A0000006Vm9rIAD", "", "checksum mismatch"},
+		{"mixed case not fitting the checksum", "001a0000006Vm9rIAC", "", "checksum mismatch"},
+		{"lower case checksum upper casing a digit", "001000000000000baa", "", "checksum mismatch"},
+		{"illegal checksum char", "001a0000006vm9ri-c", "", "checksum mismatch"},
+		{"illegal char", "001A0000006Vm9-", "", "unexpected char"},
+		{"illegal char of 18", "001A00000_6Vm9rIAC", "", "unexpected char"},
+		{"empty", "", "", "expect 15 or 18 chars, got 0"},
+		{"too short", "001A0000006Vm9", "", "expect 15 or 18 chars, got 14"},
+		{"between", "001A0000006Vm9rIA", "", "expect 15 or 18 chars, got 17"},
+		{"too long", "001A0000006Vm9rIACX", "", "expect 15 or 18 chars, got 19"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseID(tt.in)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got %s, %v, want error of %s", got, err, tt.err)
+				}
+				if ID(tt.in).Validate() == nil {
+					t.Error("got valid")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestoreIDCase(t *testing.T) {
+	for _, tt := range knownIDs {
+		for _, in := range []string{tt.id18, strings.ToLower(tt.id18), strings.ToUpper(tt.id18)} {
+			if got, ok := restoreIDCase(in); !ok || got != tt.id18 {
+				t.Errorf("restoreIDCase(%s) = %s, %v, want %s", in, got, ok, tt.id18)
+			}
+		}
+	}
+	if got, ok := restoreIDCase("001A0000006vM9RIAC"); ok {
+		t.Errorf("got %s of a mixed case id, want no fit", got)
+	}
+}
+
+func TestIDForms(t *testing.T) {
+	for _, tt := range knownIDs {
+		id15, id18 := ID(tt.id15), ID(tt.id18)
+		if got := id15.To18(); got != id18 {
+			t.Errorf("%s.To18() = %s, want %s", id15, got, id18)
+		}
+		if got := id18.To18(); got != id18 {
+			t.Errorf("%s.To18() = %s, want %s", id18, got, id18)
+		}
+		if got := id18.To15(); got != id15 {
+			t.Errorf("%s.To15() = %s, want %s", id18, got, id15)
+		}
+		if got := ID(strings.ToLower(tt.id18)).To15(); got != id15 {
+			t.Errorf("lower case %s.To15() = %s, want %s", id18, got, id15)
+		}
+		if got := id15.To15(); got != id15 {
+			t.Errorf("%s.To15() = %s, want %s", id15, got, id15)
+		}
+		if got, want := id18.KeyPrefix(), tt.id15[:3]; got != want {
+			t.Errorf("%s.KeyPrefix() = %s, want %s", id18, got, want)
+		}
+		if !id15.Equal(id18) || !id18.Equal(id15) || !id18.Equal(ID(strings.ToLower(tt.id18))) {
+			t.Errorf("%s and %s are not equal", id15, id18)
+		}
+	}
+
+	if ID("001A0000006Vm9r").Equal("001A0000006VM9r") {
+		t.Error("got ids of different cases equal")
+	}
+	if ID("0015000000Gv7qJ").Equal("001A0000006Vm9rIAC") {
+		t.Error("got different ids equal")
+	}
+	for _, invalid := range []ID{"", "001", "001A0000006Vm9rIAD"} {
+		if got := invalid.To18(); got != invalid {
+			t.Errorf("%q.To18() = %s, want it as is", invalid, got)
+		}
+	}
+	if got := ID("01").KeyPrefix(); got != "" {
+		t.Errorf("got key prefix %q of a short id", got)
+	}
+}
+
+func TestIDUnmarshalText(t *testing.T) {
+	var record struct {
+		ID       ID  `json:"Id"`
+		ParentID *ID `json:"ParentId"`
+		OwnerID  ID  `json:"OwnerId"`
+	}
+	if err := json.Unmarshal([]byte(`{"Id":"001a0000006vm9riac","ParentId":"0015000000Gv7qJ","OwnerId":""}`), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.ID != "001A0000006Vm9rIAC" || record.ParentID == nil || *record.ParentID != "0015000000Gv7qJAAR" || !record.OwnerID.IsZero() {
+		t.Errorf("got %+v", record)
+	}
+
+	for _, invalid := range []string{`{"Id":"001A0000006Vm9rIAD"}`, `{"Id":"001"}`, `{"Id":"001A0000006Vm9-"}`} {
+		if err := json.Unmarshal([]byte(invalid), &record); err == nil || !strings.Contains(err.Error(), "invalid id") {
+			t.Errorf("got %v of %s, want invalid id", err, invalid)
+		}
+	}
+}
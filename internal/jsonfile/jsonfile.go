@@ -0,0 +1,55 @@
+// Package jsonfile reads and writes values in json files, for the file
+// stores of gosf and its subpackages.
+package jsonfile
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Read decodes the json file at path into v, found is false if the file
+// doesn't exist.
+func Read(path string, v interface{}) (found bool, err error) {
+	byts, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(byts, v)
+}
+
+// Write encodes v into the json file at path, its directory is created if
+// it doesn't exist. The file is written to a temporary file and renamed,
+// so readers never see a partial file, nor does a crash leave one.
+func Write(path string, v interface{}) error {
+	byts, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(byts); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -1,6 +1,12 @@
 package gosf
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
 
 // Logger inteface
 type Logger interface {
@@ -8,14 +14,174 @@ type Logger interface {
 	Printf(format string, v ...interface{})
 }
 
+// LeveledLogger is a Logger supports levels and structured fields given as
+// alternating keys and values. Client logs requests through it if possible.
+type LeveledLogger interface {
+	Logger
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
 var defaultLogger Logger = &logger{}
 
 type logger struct{}
 
 func (l *logger) Print(v ...interface{}) {
-	fmt.Println(v...)
+	fmt.Println(redact(fmt.Sprint(v...)))
 }
 
 func (l *logger) Printf(format string, v ...interface{}) {
-	fmt.Printf(format+"\n", v...)
+	fmt.Println(redact(fmt.Sprintf(format, v...)))
+}
+
+// discardLogger is a Logger prints nothing, for statements rendered
+// outside of requests, whose problems are returned as errors.
+type discardLogger struct{}
+
+func (discardLogger) Print(v ...interface{})                 {}
+func (discardLogger) Printf(format string, v ...interface{}) {}
+
+/************************************/
+/*************** SLOG ***************/
+/************************************/
+
+// slogLogger adapts *slog.Logger to LeveledLogger.
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger returns a LeveledLogger writes to l. Print and Printf are
+// logged at info level. Tokens and passwords are redacted from messages and fields.
+func NewSlogLogger(l *slog.Logger) LeveledLogger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &slogLogger{l: l}
+}
+
+func (s *slogLogger) Print(v ...interface{}) {
+	s.log(slog.LevelInfo, fmt.Sprint(v...))
+}
+
+func (s *slogLogger) Printf(format string, v ...interface{}) {
+	s.log(slog.LevelInfo, fmt.Sprintf(format, v...))
+}
+
+func (s *slogLogger) Debug(msg string, keysAndValues ...interface{}) {
+	s.log(slog.LevelDebug, msg, keysAndValues...)
+}
+
+func (s *slogLogger) Info(msg string, keysAndValues ...interface{}) {
+	s.log(slog.LevelInfo, msg, keysAndValues...)
+}
+
+func (s *slogLogger) Warn(msg string, keysAndValues ...interface{}) {
+	s.log(slog.LevelWarn, msg, keysAndValues...)
+}
+
+func (s *slogLogger) Error(msg string, keysAndValues ...interface{}) {
+	s.log(slog.LevelError, msg, keysAndValues...)
+}
+
+func (s *slogLogger) log(level slog.Level, msg string, keysAndValues ...interface{}) {
+	ctx := context.Background()
+	if !s.l.Enabled(ctx, level) {
+		return
+	}
+	s.l.Log(ctx, level, redact(msg), redactFields(keysAndValues)...)
+}
+
+/************************************/
+/************* HELPERS **************/
+/************************************/
+
+// logLevel is the level Client logs a message with.
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+// logFields logs msg with fields through l. If l is not a LeveledLogger,
+// debug and info messages are dropped, others are printed with fields
+// appended as key=value pairs.
+func logFields(l Logger, level logLevel, msg string, keysAndValues ...interface{}) {
+	if ll, ok := l.(LeveledLogger); ok {
+		switch level {
+		case levelDebug:
+			ll.Debug(msg, keysAndValues...)
+		case levelInfo:
+			ll.Info(msg, keysAndValues...)
+		case levelWarn:
+			ll.Warn(msg, keysAndValues...)
+		default:
+			ll.Error(msg, keysAndValues...)
+		}
+		return
+	}
+	if level < levelWarn {
+		return
+	}
+
+	fields := redactFields(keysAndValues)
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i+1 < len(fields); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", fields[i], fields[i+1])
+	}
+	l.Print(redact(b.String()))
+}
+
+const redacted = "[REDACTED]"
+
+var (
+	// sensitiveKey matches field names whose values must never be logged.
+	sensitiveKey = regexp.MustCompile(`(?i)(authorization|token|password|secret|signature|session|^sid$)`)
+	// sensitiveValue matches secrets embedded in free text, like headers,
+	// query strings, form bodies and json documents.
+	sensitiveValue = regexp.MustCompile(`(?i)((?:access_token|refresh_token|password|client_secret|signature|sessionid|sid)"?\s*[=:]\s*"?)[^&\s",}]+`)
+	sensitiveAuth  = regexp.MustCompile(`(?i)\b(bearer|oauth)\s+[^\s",}]+`)
+)
+
+// redact removes tokens and passwords from s.
+func redact(s string) string {
+	s = sensitiveValue.ReplaceAllString(s, "${1}"+redacted)
+	return sensitiveAuth.ReplaceAllString(s, "${1} "+redacted)
+}
+
+// redactFields returns a copy of keysAndValues with sensitive values redacted.
+// slog.Attr elements are supported as well as key-value pairs.
+func redactFields(keysAndValues []interface{}) []interface{} {
+	fields := make([]interface{}, 0, len(keysAndValues))
+	for i := 0; i < len(keysAndValues); i++ {
+		if attr, ok := keysAndValues[i].(slog.Attr); ok {
+			if sensitiveKey.MatchString(attr.Key) {
+				attr.Value = slog.StringValue(redacted)
+			} else if attr.Value.Kind() == slog.KindString {
+				attr.Value = slog.StringValue(redact(attr.Value.String()))
+			}
+			fields = append(fields, attr)
+			continue
+		}
+
+		key := keysAndValues[i]
+		if i+1 >= len(keysAndValues) {
+			fields = append(fields, key)
+			break
+		}
+		i++
+		value := keysAndValues[i]
+		if k, ok := key.(string); ok && sensitiveKey.MatchString(k) {
+			value = redacted
+		} else if str, ok := value.(string); ok {
+			value = redact(str)
+		}
+		fields = append(fields, key, value)
+	}
+	return fields
 }
@@ -0,0 +1,121 @@
+package gosf
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordLogger is a plain Logger records the lines printed.
+type recordLogger struct {
+	lines []string
+}
+
+func (l *recordLogger) Print(v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprint(v...))
+}
+
+func (l *recordLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestLogFieldsPlainLogger(t *testing.T) {
+	tests := []struct {
+		level logLevel
+		want  string
+	}{
+		{levelDebug, ""},
+		{levelInfo, ""},
+		{levelWarn, "msg status=400 token=[REDACTED]"},
+		{levelError, "msg status=400 token=[REDACTED]"},
+	}
+	for _, tt := range tests {
+		l := &recordLogger{}
+		logFields(l, tt.level, "msg", "status", 400, "token", "abc")
+		got := strings.Join(l.lines, "\n")
+		if got != tt.want {
+			t.Errorf("level %d: got %q, want %q", tt.level, got, tt.want)
+		}
+	}
+}
+
+// secrets are the values which must never be logged.
+var secrets = []string{"s3cr3t-token", "s3cr3t-pass", "s3cr3t-client", "s3cr3t-sig", "s3cr3t-sid"}
+
+func checkRedacted(t *testing.T, out string) {
+	t.Helper()
+	for _, secret := range secrets {
+		if strings.Contains(out, secret) {
+			t.Errorf("%s is logged in %s", secret, out)
+		}
+	}
+	if !strings.Contains(out, redacted) {
+		t.Errorf("nothing redacted in %s", out)
+	}
+}
+
+// logCases log secrets in messages, urls and fields.
+var logCases = []struct {
+	name   string
+	msg    string
+	fields []interface{}
+}{
+	{"bearer header", "Authorization: Bearer s3cr3t-token", nil},
+	{"oauth header", "Authorization: OAuth s3cr3t-token", nil},
+	{"url query", "GET https://x.my.salesforce.com/services/data?access_token=s3cr3t-token&q=1", nil},
+	{"form body", "grant_type=password&client_secret=s3cr3t-client&password=s3cr3t-pass", nil},
+	{"json body", `{"access_token": "s3cr3t-token", "signature":"s3cr3t-sig"}`, nil},
+	{"cookie", "Cookie: sid=s3cr3t-sid", nil},
+	{"key fields", "request", []interface{}{"authorization", "Bearer s3cr3t-token", "client_secret", "s3cr3t-client", "SessionId", "s3cr3t-sid"}},
+	{"value fields", "request", []interface{}{"url", "https://x/?access_token=s3cr3t-token", "body", "password=s3cr3t-pass"}},
+	{"attr fields", "request", []interface{}{slog.String("password", "s3cr3t-pass"), slog.String("header", "Bearer s3cr3t-token")}},
+}
+
+func TestRedactPlainLogger(t *testing.T) {
+	for _, tt := range logCases {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &recordLogger{}
+			logFields(l, levelError, tt.msg, tt.fields...)
+			checkRedacted(t, strings.Join(l.lines, "\n"))
+		})
+	}
+}
+
+func TestRedactSlogLogger(t *testing.T) {
+	for _, tt := range logCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+			logFields(l, levelDebug, tt.msg, tt.fields...)
+			l.Print(tt.msg)
+			l.Printf("%s", tt.msg)
+			checkRedacted(t, buf.String())
+		})
+	}
+}
+
+func TestConfigAndTokenLogValues(t *testing.T) {
+	config := Config{
+		Host:         "https://login.salesforce.com",
+		ClientID:     "client",
+		ClientSecret: "s3cr3t-client",
+		Username:     "user@example.com",
+		Password:     "s3cr3t-pass",
+	}
+	tok := &token{AccessToken: "s3cr3t-token", TokenType: "Bearer", Signature: "s3cr3t-sig", ExpiresAt: time.Now()}
+
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, nil))
+	l.Info("config", "config", config, "pointer", &config, "token", tok)
+	fmt.Fprint(&buf, config.String(), config, &config)
+	out := buf.String()
+	checkRedacted(t, out)
+	for _, want := range []string{"user@example.com", "https://login.salesforce.com", "Bearer"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("%s is not logged in %s", want, out)
+		}
+	}
+}
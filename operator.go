@@ -1,11 +1,17 @@
 package gosf
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/sidebiequ/gosf/soql"
 )
 
 /*************************************/
@@ -22,6 +28,45 @@ type Operator interface {
 	Handle(*http.Response) error
 }
 
+// Operation describes what a request sent by Client does, for logs, traces
+// and metrics. A http.RoundTripper set as Config.Transport can read it from
+// the request context by OperationFromContext.
+type Operation struct {
+	// Name of the operation, like "create", "query" or "token".
+	Name string
+	// Sobject is the name of the sobject operated on, if any.
+	Sobject string
+	// Attempt is 1 for the first attempt and increases on each retry.
+	Attempt int
+}
+
+// OperationDescriber is an optional interface an Operator can implement to
+// describe the Operation it does.
+type OperationDescriber interface {
+	Operation() Operation
+}
+
+type operationKey struct{}
+
+// withOperation returns a copy of ctx carries op.
+func withOperation(ctx context.Context, op Operation) context.Context {
+	return context.WithValue(ctx, operationKey{}, op)
+}
+
+// OperationFromContext returns the Operation carried by ctx, if any.
+func OperationFromContext(ctx context.Context) (op Operation, ok bool) {
+	op, ok = ctx.Value(operationKey{}).(Operation)
+	return
+}
+
+var (
+	_ OperationDescriber = &opCreate{}
+	_ OperationDescriber = &opUpdate{}
+	_ OperationDescriber = &opDelete{}
+	_ OperationDescriber = &opGet{}
+	_ OperationDescriber = &OpQuery{}
+)
+
 var (
 	_ Operator = &opCreate{}
 	_ Operator = &opUpdate{}
@@ -60,6 +105,10 @@ func (op *opCreate) Make(ctx *RequestCtx) (*Request, error) {
 	}
 }
 
+func (op *opCreate) Operation() Operation {
+	return Operation{Name: "create", Sobject: op.sobjectName}
+}
+
 func (op *opCreate) Handle(resp *http.Response) error {
 	if resp.StatusCode != http.StatusCreated {
 		return fmt.Errorf("create operator can't handle response with code %d, expect %d", resp.StatusCode, http.StatusCreated)
@@ -79,21 +128,30 @@ type opUpdate struct {
 }
 
 func (op *opUpdate) Make(ctx *RequestCtx) (*Request, error) {
+	idErr := ID(op.sobjectID).Validate()
 	switch {
 	case op.sobjectName == "":
 		return nil, errors.New("missing Sobject name")
 	case op.sobjectID == "":
 		return nil, errors.New("missing Sobject id")
+	case idErr != nil:
+		return nil, fmt.Errorf("invalid Sobject id: %w", idErr)
 	case op.sobject == nil:
 		return nil, errors.New("missing Sobject")
+	case isEmptyPatch(op.sobject):
+		return nil, errEmptyPatch
 	default:
 		return NewRequest(http.MethodPatch, ctx.SobjectURLWithID(op.sobjectName, op.sobjectID), op.sobject), nil
 	}
 }
 
+func (op *opUpdate) Operation() Operation {
+	return Operation{Name: "update", Sobject: op.sobjectName}
+}
+
 func (op *opUpdate) Handle(resp *http.Response) error {
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("update operator can't handle response with code %d, expect %d", resp.StatusCode, http.StatusOK)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("update operator can't handle response with code %d, expect %d or %d", resp.StatusCode, http.StatusOK, http.StatusNoContent)
 	}
 	return nil
 }
@@ -109,16 +167,23 @@ type opDelete struct {
 }
 
 func (op *opDelete) Make(ctx *RequestCtx) (*Request, error) {
+	idErr := ID(op.sobjectID).Validate()
 	switch {
 	case op.sobjectName == "":
 		return nil, errors.New("missing Sobject name")
 	case op.sobjectID == "":
 		return nil, errors.New("missing Sobject id")
+	case idErr != nil:
+		return nil, fmt.Errorf("invalid Sobject id: %w", idErr)
 	default:
 		return NewRequest(http.MethodDelete, ctx.SobjectURLWithID(op.sobjectName, op.sobjectID), nil), nil
 	}
 }
 
+func (op *opDelete) Operation() Operation {
+	return Operation{Name: "delete", Sobject: op.sobjectName}
+}
+
 func (op *opDelete) Handle(resp *http.Response) error {
 	if resp.StatusCode != http.StatusNoContent {
 		return fmt.Errorf("delete operator can't handle response with code %d, expect %d", resp.StatusCode, http.StatusNoContent)
@@ -130,29 +195,54 @@ func (op *opDelete) Handle(resp *http.Response) error {
 /*********** GET SOBJECT ************/
 /************************************/
 
-// opGet is a request for getting SObject.
+// opGet is a request for getting SObject by id, or by the value of an
+// external id field if externalField is set. Only fields are retrieved if
+// it's not empty. The response is decoded into target.
 type opGet struct {
-	sobjectName string
-	sobjectID   string
-	target      interface{}
+	sobjectName   string
+	sobjectID     string
+	externalField string
+	fields        []string
+	target        interface{}
 }
 
 func (op *opGet) Make(ctx *RequestCtx) (*Request, error) {
+	var urlStr string
+	var idErr error
+	if op.externalField == "" {
+		idErr = ID(op.sobjectID).Validate()
+	}
 	switch {
 	case op.sobjectName == "":
 		return nil, errors.New("missing Sobject name")
+	case op.sobjectID == "" && op.externalField != "":
+		return nil, fmt.Errorf("missing value of external id %s", op.externalField)
 	case op.sobjectID == "":
 		return nil, errors.New("missing Sobject id")
+	case idErr != nil:
+		return nil, fmt.Errorf("invalid Sobject id: %w", idErr)
+	case op.target == nil:
+		return nil, errors.New("missing target")
+	case op.externalField != "":
+		urlStr = ctx.SobjectURLWithExternalID(op.sobjectName, op.externalField, op.sobjectID)
 	default:
-		return NewRequest(http.MethodGet, ctx.SobjectURLWithID(op.sobjectName, op.sobjectID), nil), nil
+		urlStr = ctx.SobjectURLWithID(op.sobjectName, op.sobjectID)
+	}
+	if len(op.fields) > 0 {
+		urlStr += "?fields=" + url.QueryEscape(strings.Join(op.fields, ","))
 	}
+	return NewRequest(http.MethodGet, urlStr, nil), nil
+}
+
+func (op *opGet) Operation() Operation {
+	return Operation{Name: "get", Sobject: op.sobjectName}
 }
 
 func (op *opGet) Handle(resp *http.Response) error {
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("get operator can't handle response with code %d, expect %d", resp.StatusCode, http.StatusOK)
 	}
-	return json.NewDecoder(resp.Body).Decode(&op.target)
+	return json.NewDecoder(resp.Body).Decode(op.target)
 }
 
 /***********************************/
@@ -163,63 +253,176 @@ type (
 	// OpQuery is a request for quering SObject.
 	// Set which Sobject type and fileds to query by Select() and From() is necessary.
 	// The reset operations below is optional:
-	// 	- OrderDesc(), OrderAsc(), OrderReset(), OrderNullFirst(), OrderNullLast() to control ORDER key.
-	//  - Limit() to control LIMIT key
+	// 	- OrderDesc(), OrderAsc(), OrderBy(), OrderReset(), OrderNullFirst(), OrderNullLast() to control ORDER key.
+	//  - Limit() and Offset() to control LIMIT and OFFSET keys
+	//  - UsingScope(), WithSecurityEnforced(), WithUserMode(), WithSystemMode(),
+	//    ForView(), ForReference() and ForUpdate() to control the other keys
+	//  - QueryAll() to include deleted and archived records
+	// Select() and From() can be derived from a struct by SelectStruct().
+	// Parent relationship fields and child relationship subqueries are
+	// selected by SelectParent() and SelectSubquery().
+	// Aggregate queries are built by SelectCount(), SelectAggregate(),
+	// GroupBy() and Having(), see AggregateResult for their results.
 	// See the methods' doc  for more details.
 	OpQuery struct {
 		sobjectName  string
 		selectFileds []string
+		subqueries   []*OpQuery
 		whereClauses []whereClause
-		order        string
-		nullPriority string
+		whereExprs   []soql.Expr
+		groupBy      []string
+		groupByFunc  string
+		having       []whereClause
+		havingExprs  []soql.Expr
+		typeOfs      []*TypeOf
+		scope        string
+		mode         string
+		orders       []orderItem
+		nullPriority Nulls
 		limit        int
+		offset       int
+		lock         string
+		all          bool
 		result       *QueryResult
+		err          error
 	}
 
 	// QueryResult is the result of a query operation.
+	// NextRecordsURL is set if Done is false, the rest records can be
+	// retrieved from it.
 	QueryResult struct {
-		TotalSize int           `json:"totalSize"`
-		Done      bool          `json:"done"`
-		Records   []interface{} `json:"records"`
+		TotalSize      int           `json:"totalSize"`
+		Done           bool          `json:"done"`
+		NextRecordsURL string        `json:"nextRecordsUrl,omitempty"`
+		Records        []interface{} `json:"records"`
+
+		// relationships are the child relationships of the records
+		relationships relationships
 	}
 
 	whereClause struct {
 		field     string
+		operator  string
 		condition interface{}
 	}
+
+	orderItem struct {
+		field     string
+		direction Direction
+		nulls     Nulls
+	}
 )
 
-// Parse parses the result.
+// Parse parses the records into targets, which should be a pointer to a
+// slice. Records are decoded like QueryIter does.
 func (r *QueryResult) Parse(targets interface{}) error {
-	byts, _ := json.Marshal(r.Records)
-	return json.Unmarshal(byts, &targets)
+	byts, err := json.Marshal(r.Records)
+	if err != nil {
+		return err
+	}
+	return decodeRecord(byts, targets, r.relationships)
+}
+
+// ChildResult returns the result of child relationship subquery of the
+// i-th record, which is nil if the record has no child records.
+// NextRecordsURL of it is set if there are more child records than
+// returned in the record.
+func (r *QueryResult) ChildResult(i int, relationship string) (child *QueryResult, err error) {
+	if i < 0 || i >= len(r.Records) {
+		return nil, fmt.Errorf("record index %d out of range [0,%d)", i, len(r.Records))
+	}
+	record, ok := r.Records[i].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("record %d is %T, not an object", i, r.Records[i])
+	}
+	value, ok := record[relationship]
+	if !ok {
+		return nil, fmt.Errorf("record %d has no relationship %s", i, relationship)
+	}
+	if value == nil {
+		return
+	}
+
+	byts, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(byts, &child); err != nil || child == nil {
+		return
+	}
+	child.relationships = r.relationships[strings.ToLower(relationship)]
+	return
 }
 
 // IsValid returns true if whereClause's condition is valid.
-// In SOQL, condition in where clause can only be number, boolean or string.
+// In SOQL, condition in where clause can only be number, boolean, string,
+// id, date, datetime or date literal.
 func (c *whereClause) IsValid() bool {
 	switch c.condition.(type) {
 	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
 		return true
 	case bool:
 		return true
-	case string:
+	case string, ID:
+		return true
+	case time.Time, Date, DateTime, DateLiteral:
 		return true
 	default:
 		return false
 	}
 }
 
+// check returns an error if the condition can't be written into SOQL.
+func (c *whereClause) check() error {
+	if l, ok := c.condition.(DateLiteral); ok && !l.IsValid() {
+		return fmt.Errorf("invalid date literal %s", l)
+	}
+	return nil
+}
+
+// String renders the clause as <FIELD><OPERATOR><CONDITION>.
+func (c *whereClause) String() string {
+	operator := c.operator
+	if operator == "" {
+		operator = "="
+	}
+	return c.field + operator + formatCondition(c.condition)
+}
+
 // Make request by given request context.
 func (op *OpQuery) Make(ctx *RequestCtx) (*Request, error) {
 	switch {
+	case op.err != nil:
+		return nil, op.err
 	case op.sobjectName == "":
 		return nil, errors.New("missing Sobject name")
-	case len(op.selectFileds) <= 0:
+	case len(op.selectFileds) <= 0 && len(op.typeOfs) <= 0 && len(op.subqueries) <= 0:
 		return nil, errors.New("missing select fields")
-	default:
-		return NewRequest(http.MethodGet, ctx.QueryURL(op.makeQueryStatment()), nil), nil
+	case op.offset > 0 && op.lock == "FOR UPDATE":
+		return nil, errors.New("OFFSET can't be used with FOR UPDATE")
+	}
+	for _, sub := range op.subqueries {
+		switch {
+		case sub.err != nil:
+			return nil, sub.err
+		case sub.sobjectName == "":
+			return nil, errors.New("missing relationship name of subquery")
+		case len(sub.selectFileds) <= 0 && len(sub.typeOfs) <= 0:
+			return nil, fmt.Errorf("missing select fields of subquery %s", sub.sobjectName)
+		}
+	}
+	if op.all {
+		return NewRequest(http.MethodGet, ctx.QueryAllURL(op.makeQueryStatment(ctx.Logger())), nil), nil
 	}
+	return NewRequest(http.MethodGet, ctx.QueryURL(op.makeQueryStatment(ctx.Logger())), nil), nil
+}
+
+// Operation describes the query operation.
+func (op *OpQuery) Operation() Operation {
+	if op.all {
+		return Operation{Name: "queryAll", Sobject: op.sobjectName}
+	}
+	return Operation{Name: "query", Sobject: op.sobjectName}
 }
 
 // Handle success response from salesforce.
@@ -227,7 +430,13 @@ func (op *OpQuery) Handle(resp *http.Response) error {
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("get operator can't handle response with code %d, expect %d", resp.StatusCode, http.StatusOK)
 	}
-	return json.NewDecoder(resp.Body).Decode(&op.result)
+	if err := json.NewDecoder(resp.Body).Decode(&op.result); err != nil {
+		return err
+	}
+	if op.result != nil {
+		op.result.relationships = op.relationships()
+	}
+	return nil
 }
 
 // Select defines which columns of SObject will be return in result.
@@ -239,6 +448,37 @@ func (op *OpQuery) Select(fields ...string) *OpQuery {
 	return op
 }
 
+// SelectParent defines the fields of a parent relationship will be return,
+// SelectParent("Owner", "Name", "Email") selects Owner.Name and Owner.Email.
+// The relationship can be a path like "Account.Owner".
+func (op *OpQuery) SelectParent(relationship string, fields ...string) *OpQuery {
+	for _, field := range fields {
+		op.Select(relationship + "." + field)
+	}
+	return op
+}
+
+// SelectSubquery defines a child relationship subquery will be return in
+// result, the From of sub is the relationship name:
+//
+//	op := NewOpQuery("Account").Select("Id").SelectSubquery(
+//		NewOpQuery("Contacts").Select("Id", "Email").Where("Email", "a@example.com"),
+//	)
+//	// SELECT Id,(SELECT Id,Email FROM Contacts WHERE Email='a@example.com') FROM Account
+//
+// The child records are returned in a records wrapper like QueryResult's,
+// see QueryResult.ChildResult. Subqueries can't be nested.
+func (op *OpQuery) SelectSubquery(sub *OpQuery) *OpQuery {
+	switch {
+	case sub == nil:
+	case len(sub.subqueries) > 0:
+		op.err = fmt.Errorf("subquery %s can't have subqueries", sub.sobjectName)
+	default:
+		op.subqueries = append(op.subqueries, sub)
+	}
+	return op
+}
+
 // From defines which SObject will be query.
 func (op *OpQuery) From(sobjectName string) *OpQuery {
 	op.sobjectName = sobjectName
@@ -246,44 +486,188 @@ func (op *OpQuery) From(sobjectName string) *OpQuery {
 }
 
 // Where defines what condition will be appended to the query.
+// The condition can be a number, boolean, string, time.Time, Date,
+// DateTime or DateLiteral, others are ignored. Strings are quoted and
+// escaped, times are rendered in UTC. The conditions are joined by AND in
+// the order they're added. An unknown DateLiteral fails the query.
 func (op *OpQuery) Where(field string, condition interface{}) *OpQuery {
 	where := whereClause{
 		field:     field,
 		condition: condition,
 	}
+	if err := where.check(); err != nil {
+		op.err = err
+		return op
+	}
 	if where.IsValid() {
 		op.whereClauses = append(op.whereClauses, where)
 	}
 	return op
 }
 
-// OrderDesc defines the order column to sort results descendant.
-func (op *OpQuery) OrderDesc(field string) *OpQuery {
-	op.order = fmt.Sprintf("%s DESC", field)
+// WhereCompare is like Where but compares field with condition by
+// operator, which is one of = != < <= > >=:
+//
+//	op.WhereCompare("CreatedDate", ">", gosf.LastNDays(30))
+func (op *OpQuery) WhereCompare(field, operator string, condition interface{}) *OpQuery {
+	if !comparisonOperators[operator] {
+		op.err = fmt.Errorf("invalid where operator %s", operator)
+		return op
+	}
+	where := whereClause{
+		field:     field,
+		operator:  operator,
+		condition: condition,
+	}
+	if err := where.check(); err != nil {
+		op.err = err
+		return op
+	}
+	if where.IsValid() {
+		op.whereClauses = append(op.whereClauses, where)
+	}
+	return op
+}
+
+// AggregateFunction is a SOQL aggregate function.
+type AggregateFunction string
+
+// Aggregate functions of SOQL.
+const (
+	AggregateCount         AggregateFunction = "COUNT"
+	AggregateCountDistinct AggregateFunction = "COUNT_DISTINCT"
+	AggregateSum           AggregateFunction = "SUM"
+	AggregateAvg           AggregateFunction = "AVG"
+	AggregateMin           AggregateFunction = "MIN"
+	AggregateMax           AggregateFunction = "MAX"
+)
+
+// comparisonOperators are the operators Having() accepts.
+var comparisonOperators = map[string]bool{
+	"=": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+}
+
+// SelectCount selects COUNT(), the count of records matched is returned as
+// the TotalSize of the result without records. It must be the only select
+// field of the query.
+func (op *OpQuery) SelectCount() *OpQuery {
+	return op.Select("COUNT()")
+}
+
+// SelectAggregate selects fn(field) with alias. If alias=="", salesforce
+// names it by its position in the aggregate fields, like expr0, expr1...
+func (op *OpQuery) SelectAggregate(fn AggregateFunction, field, alias string) *OpQuery {
+	expr := fmt.Sprintf("%s(%s)", fn, field)
+	if alias != "" {
+		expr += " " + alias
+	}
+	return op.Select(expr)
+}
+
+// GroupBy defines the fields to group results by.
+func (op *OpQuery) GroupBy(fields ...string) *OpQuery {
+	op.groupBy = append(op.groupBy, fields...)
+	op.groupByFunc = ""
 	return op
 }
 
-// OrderAsc defines the order column to sort results ascendant.
+// GroupByRollup groups results by fields with subtotals,
+// GROUP BY ROLLUP(<FIELD1> [,<FIELD2>]...).
+func (op *OpQuery) GroupByRollup(fields ...string) *OpQuery {
+	op.groupBy = append(op.groupBy, fields...)
+	op.groupByFunc = "ROLLUP"
+	return op
+}
+
+// GroupByCube groups results by fields with subtotals of all combinations,
+// GROUP BY CUBE(<FIELD1> [,<FIELD2>]...).
+func (op *OpQuery) GroupByCube(fields ...string) *OpQuery {
+	op.groupBy = append(op.groupBy, fields...)
+	op.groupByFunc = "CUBE"
+	return op
+}
+
+// Having defines the condition of grouped results, expr is a group field
+// or an aggregate like COUNT(Id), operator is one of = != < <= > >=:
+//
+//	op.GroupBy("LeadSource").Having("COUNT(Name)", ">", 100)
+func (op *OpQuery) Having(expr, operator string, condition interface{}) *OpQuery {
+	if !comparisonOperators[operator] {
+		op.err = fmt.Errorf("invalid having operator %s", operator)
+		return op
+	}
+	having := whereClause{
+		field:     expr,
+		operator:  operator,
+		condition: condition,
+	}
+	if err := having.check(); err != nil {
+		op.err = err
+		return op
+	}
+	if having.IsValid() {
+		op.having = append(op.having, having)
+	}
+	return op
+}
+
+// Direction is the sort direction of an order column.
+type Direction string
+
+// Sort directions.
+const (
+	Ascending  Direction = "ASC"
+	Descending Direction = "DESC"
+)
+
+// Nulls is where null values of an order column are placed.
+// NullsDefault places them first in ascending order and last in descending order.
+type Nulls string
+
+// Placements of null values.
+const (
+	NullsDefault Nulls = ""
+	NullsFirst   Nulls = "NULLS FIRST"
+	NullsLast    Nulls = "NULLS LAST"
+)
+
+// OrderDesc appends an order column to sort results descendant.
+func (op *OpQuery) OrderDesc(field string) *OpQuery {
+	return op.OrderBy(field, Descending, NullsDefault)
+}
+
+// OrderAsc appends an order column to sort results ascendant.
 func (op *OpQuery) OrderAsc(field string) *OpQuery {
-	op.order = fmt.Sprintf("%s ASC", field)
+	return op.OrderBy(field, Ascending, NullsDefault)
+}
+
+// OrderBy appends an order column with direction and the placement of nulls,
+// columns are sorted in the order they are appended:
+//
+//	op.OrderBy("Rating", Descending, NullsLast).OrderAsc("Name")
+//	// ORDER BY Rating DESC NULLS LAST,Name ASC
+func (op *OpQuery) OrderBy(field string, direction Direction, nulls Nulls) *OpQuery {
+	op.orders = append(op.orders, orderItem{field: field, direction: direction, nulls: nulls})
 	return op
 }
 
-// OrderReset resets the order statment=="".
+// OrderReset resets the order columns.
 func (op *OpQuery) OrderReset() *OpQuery {
-	op.order = ""
+	op.orders = nil
 	return op
 }
 
-// OrderNullFirst make null null column value first in query results when given order column.
+// OrderNullFirst make null column value first in query results, for the
+// order columns without their own placement of nulls.
 func (op *OpQuery) OrderNullFirst() *OpQuery {
-	op.nullPriority = "NULL FIRST"
+	op.nullPriority = NullsFirst
 	return op
 }
 
-// OrderNullLast make null column value last in query results when given order column.
+// OrderNullLast make null column value last in query results, for the
+// order columns without their own placement of nulls.
 func (op *OpQuery) OrderNullLast() *OpQuery {
-	op.nullPriority = "NULL LAST"
+	op.nullPriority = NullsLast
 	return op
 }
 
@@ -294,67 +678,170 @@ func (op *OpQuery) Limit(n int) *OpQuery {
 	return op
 }
 
-func (op *OpQuery) makeQueryStatment() string {
-	baseQuery := fmt.Sprintf("SELECT %s FROM %s", op.makeSelectStatment(), op.sobjectName)
-	return strings.Join([]string{
-		baseQuery,
-		op.makeWhereCluasesStatment(),
+func (op *OpQuery) makeQueryStatment(logger Logger) string {
+	statments := []string{
+		fmt.Sprintf("SELECT %s FROM %s", op.makeSelectStatment(logger), op.sobjectName),
+	}
+	for _, statment := range []string{
+		op.makeScopeStatment(),
+		op.makeWhereCluasesStatment(logger),
+		op.makeModeStatment(),
+		op.makeGroupByStatment(),
+		op.makeHavingStatment(logger),
 		op.makeOrderStatment(),
 		op.makeLimitStatment(),
-	}, " ")
+		op.makeOffsetStatment(),
+		op.lock,
+	} {
+		if statment != "" {
+			statments = append(statments, statment)
+		}
+	}
+	return strings.Join(statments, " ")
 }
 
-// makeSelectStatment renders statment as below if r.selectFileds has elements:
-// SELECT <FIELD1> [,<FIELD2>]...
-func (op *OpQuery) makeSelectStatment() string {
-	if len(op.selectFileds) == 0 {
-		defaultLogger.Print("[QuerySObjectRequest] Missing Select fields")
+// makeSelectStatment renders statment as below if r.selectFileds or r.subqueries has elements:
+// SELECT <FIELD1> [,<FIELD2>]... [,(<SUBQUERY1>)]...
+func (op *OpQuery) makeSelectStatment(logger Logger) string {
+	if len(op.selectFileds) == 0 && len(op.typeOfs) == 0 && len(op.subqueries) == 0 {
+		logger.Print("[QuerySObjectRequest] Missing Select fields")
+	}
+	items := append([]string{}, op.selectFileds...)
+	for _, typeOf := range op.typeOfs {
+		items = append(items, typeOf.String())
+	}
+	for _, sub := range op.subqueries {
+		items = append(items, "("+sub.makeQueryStatment(logger)+")")
 	}
-	return strings.Join(op.selectFileds, ",")
+	return strings.Join(items, ",")
 }
 
 // makeWhereCluasesStatment renders statment as below if r.whereClauses has elements:
-// WHERE <FIELD1>=<CODITION1> [,<FIELD2>=<CODITION2>]...
-func (op *OpQuery) makeWhereCluasesStatment() string {
-	var data = make(map[string]interface{})
+// WHERE <FIELD1><OPERATOR1><CODITION1> [AND <FIELD2><OPERATOR2><CODITION2>]...
+func (op *OpQuery) makeWhereCluasesStatment(logger Logger) string {
+	var filters = make([]string, 0, len(op.whereClauses))
 	for _, clause := range op.whereClauses {
 		if !clause.IsValid() {
-			defaultLogger.Printf(
-				"[QuerySObjectRequest] Found invalid where-clause which should be type of number, boolean or string. QuerySObject=%s, Field=%s, ConditionType=%T",
+			logger.Printf(
+				"[QuerySObjectRequest] Found invalid where-clause which should be type of number, boolean, string or date. QuerySObject=%s, Field=%s, ConditionType=%T",
 				op.sobjectName, clause.field, clause.condition,
 			)
 			continue
 		}
-		data[clause.field] = clause.condition
+		filters = append(filters, clause.String())
+	}
+	for _, expr := range op.whereExprs {
+		filters = append(filters, formatExpr(expr))
 	}
 
-	if len(data) == 0 {
+	if len(filters) == 0 {
 		return ""
 	}
+	return fmt.Sprintf("WHERE %s", strings.Join(filters, " AND "))
+}
+
+// makeGroupByStatment renders statment as below if r.groupBy has elements:
+// GROUP BY <FIELD1> [,<FIELD2>]... or GROUP BY <ROLLUP|CUBE>(<FIELD1> [,<FIELD2>]...)
+func (op *OpQuery) makeGroupByStatment() string {
+	if len(op.groupBy) == 0 {
+		return ""
+	}
+	fields := strings.Join(op.groupBy, ",")
+	if op.groupByFunc != "" {
+		return fmt.Sprintf("GROUP BY %s(%s)", op.groupByFunc, fields)
+	}
+	return fmt.Sprintf("GROUP BY %s", fields)
+}
 
-	var filters = make([]string, 0)
-	for field, cond := range data {
-		if str, ok := cond.(string); ok {
-			filters = append(filters, fmt.Sprintf("%s='%s'", field, str))
-		} else {
-			filters = append(filters, fmt.Sprintf("%s=%v", field, cond))
+// makeHavingStatment renders statment as below if r.having has elements:
+// HAVING <EXPR1><OPERATOR1><CODITION1> [AND <EXPR2><OPERATOR2><CODITION2>]...
+func (op *OpQuery) makeHavingStatment(logger Logger) string {
+	var filters = make([]string, 0, len(op.having))
+	for _, clause := range op.having {
+		if !clause.IsValid() {
+			logger.Printf(
+				"[QuerySObjectRequest] Found invalid having-clause which should be type of number, boolean, string or date. QuerySObject=%s, Expr=%s, ConditionType=%T",
+				op.sobjectName, clause.field, clause.condition,
+			)
+			continue
 		}
+		filters = append(filters, clause.String())
+	}
+	for _, expr := range op.havingExprs {
+		filters = append(filters, formatExpr(expr))
+	}
+
+	if len(filters) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("HAVING %s", strings.Join(filters, " AND "))
+}
+
+// soqlEscaper escapes the characters must be escaped in SOQL string literals.
+var soqlEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// formatCondition renders condition as a SOQL literal, strings are quoted
+// and escaped, datetimes are formatted in UTC.
+func formatCondition(condition interface{}) string {
+	switch condition := condition.(type) {
+	case string:
+		return "'" + soqlEscaper.Replace(condition) + "'"
+	case ID:
+		return "'" + soqlEscaper.Replace(string(condition)) + "'"
+	case time.Time:
+		return condition.UTC().Format(soqlDateTimeLayout)
+	case DateTime:
+		return condition.UTC().Format(soqlDateTimeLayout)
+	case float32:
+		return strconv.FormatFloat(float64(condition), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(condition, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", condition)
 	}
-	return fmt.Sprintf("WHERE %s", strings.Join(filters, ","))
 }
 
-// makeOrderStatment renders statment as below if r.order!="":
-// ORDER BY <FIELD> <DESC|ASC> NULL <FIRST|LAST>
+// makeScopeStatment renders statment as below if r.scope!="":
+// USING SCOPE <SCOPE>
+func (op *OpQuery) makeScopeStatment() string {
+	if op.scope == "" {
+		return ""
+	}
+	return fmt.Sprintf("USING SCOPE %s", op.scope)
+}
+
+// makeModeStatment renders statment as below if r.mode!="":
+// WITH <SECURITY_ENFORCED|USER_MODE|SYSTEM_MODE>
+func (op *OpQuery) makeModeStatment() string {
+	if op.mode == "" {
+		return ""
+	}
+	return fmt.Sprintf("WITH %s", op.mode)
+}
+
+// makeOrderStatment renders statment as below if r.orders has elements:
+// ORDER BY <FIELD1> <DESC|ASC> [NULLS <FIRST|LAST>] [,<FIELD2> <DESC|ASC> [NULLS <FIRST|LAST>]]...
 func (op *OpQuery) makeOrderStatment() string {
-	if op.order == "" {
+	if len(op.orders) == 0 {
 		return ""
 	}
 
-	var nullPriority = op.nullPriority
-	if op.nullPriority == "" {
-		nullPriority = "NULL FIRST"
+	items := make([]string, 0, len(op.orders))
+	for _, order := range op.orders {
+		item := order.field
+		if order.direction != "" {
+			item += " " + string(order.direction)
+		}
+		nulls := order.nulls
+		if nulls == NullsDefault {
+			nulls = op.nullPriority
+		}
+		if nulls != NullsDefault {
+			item += " " + string(nulls)
+		}
+		items = append(items, item)
 	}
-	return fmt.Sprintf("ORDER BY %s %s", op.order, nullPriority)
+	return fmt.Sprintf("ORDER BY %s", strings.Join(items, ","))
 }
 
 // makeLimitStatment renders statment as below if r.limit>0:
@@ -366,9 +853,200 @@ func (op *OpQuery) makeLimitStatment() string {
 	return ""
 }
 
+// TypeOf is a TYPEOF clause selects fields of a polymorphic relationship
+// by the type of the referenced sobject:
+//
+//	op.SelectTypeOf(gosf.NewTypeOf("What").
+//		When("Account", "Phone", "NumberOfEmployees").
+//		When("Opportunity", "Amount").
+//		Else("Name"))
+//	// TYPEOF What WHEN Account THEN Phone,NumberOfEmployees WHEN Opportunity THEN Amount ELSE Name END
+type TypeOf struct {
+	relationship string
+	whens        []typeOfWhen
+	elseFields   []string
+}
+
+type typeOfWhen struct {
+	sobjectName string
+	fields      []string
+}
+
+// NewTypeOf returns a TypeOf of the polymorphic relationship.
+func NewTypeOf(relationship string) *TypeOf {
+	return &TypeOf{relationship: relationship}
+}
+
+// When defines the fields selected if the referenced sobject is sobjectName.
+func (t *TypeOf) When(sobjectName string, fields ...string) *TypeOf {
+	t.whens = append(t.whens, typeOfWhen{sobjectName: sobjectName, fields: fields})
+	return t
+}
+
+// Else defines the fields selected if no When matches.
+func (t *TypeOf) Else(fields ...string) *TypeOf {
+	t.elseFields = fields
+	return t
+}
+
+// String renders the TYPEOF clause.
+func (t *TypeOf) String() string {
+	var b strings.Builder
+	b.WriteString("TYPEOF " + t.relationship)
+	for _, when := range t.whens {
+		fmt.Fprintf(&b, " WHEN %s THEN %s", when.sobjectName, strings.Join(when.fields, ","))
+	}
+	if len(t.elseFields) > 0 {
+		fmt.Fprintf(&b, " ELSE %s", strings.Join(t.elseFields, ","))
+	}
+	b.WriteString(" END")
+	return b.String()
+}
+
+// SelectTypeOf selects the fields of a polymorphic relationship by TypeOf,
+// it must have at least one When.
+func (op *OpQuery) SelectTypeOf(t *TypeOf) *OpQuery {
+	switch {
+	case t == nil:
+	case len(t.whens) == 0:
+		op.err = fmt.Errorf("TYPEOF %s has no WHEN clause", t.relationship)
+	default:
+		op.typeOfs = append(op.typeOfs, t)
+	}
+	return op
+}
+
+// Offset defines the number of records will be skipped.
+// if n==0, will treat it as a signal to reset offset.
+func (op *OpQuery) Offset(n int) *OpQuery {
+	op.offset = n
+	return op
+}
+
+// UsingScope limits the records by filter scope, like "mine", "team" or
+// "everything". if scope=="", will reset it.
+func (op *OpQuery) UsingScope(scope string) *OpQuery {
+	op.scope = scope
+	return op
+}
+
+// WithSecurityEnforced checks the field and object level security of the
+// queried fields, the query fails if the user can't access any of them.
+func (op *OpQuery) WithSecurityEnforced() *OpQuery {
+	op.mode = "SECURITY_ENFORCED"
+	return op
+}
+
+// WithUserMode runs the query with the sharing rules and permissions of the user.
+func (op *OpQuery) WithUserMode() *OpQuery {
+	op.mode = "USER_MODE"
+	return op
+}
+
+// WithSystemMode runs the query in system mode, which is the default.
+func (op *OpQuery) WithSystemMode() *OpQuery {
+	op.mode = "SYSTEM_MODE"
+	return op
+}
+
+// ForView updates the LastViewedDate of the returned records.
+func (op *OpQuery) ForView() *OpQuery {
+	op.lock = "FOR VIEW"
+	return op
+}
+
+// ForReference updates the LastReferencedDate of the returned records.
+func (op *OpQuery) ForReference() *OpQuery {
+	op.lock = "FOR REFERENCE"
+	return op
+}
+
+// ForUpdate locks the returned records from being updated by others,
+// it can't be used with Offset.
+func (op *OpQuery) ForUpdate() *OpQuery {
+	op.lock = "FOR UPDATE"
+	return op
+}
+
+// QueryAll makes the query include deleted and archived records, like
+// ALL ROWS in apex. The query is sent to the queryAll resource, select
+// IsDeleted to tell the deleted records, see also QueryIterator.Deleted.
+func (op *OpQuery) QueryAll() *OpQuery {
+	op.all = true
+	return op
+}
+
+// makeOffsetStatment renders statment as below if r.offset>0:
+// OFFSET <OFFSET>
+func (op *OpQuery) makeOffsetStatment() string {
+	if op.offset > 0 {
+		return fmt.Sprintf("OFFSET %d", op.offset)
+	}
+	return ""
+}
+
 // NewOpQuery returns a OpQuery instance with given sobjectName.
 func NewOpQuery(sobjectName string) *OpQuery {
 	return &OpQuery{
 		sobjectName: sobjectName,
 	}
 }
+
+/***********************************/
+/********** EXPLAIN QUERY **********/
+/***********************************/
+
+type (
+	// ExplainResult is the query plans of a query.
+	ExplainResult struct {
+		Plans       []*QueryPlan `json:"plans"`
+		SourceQuery string       `json:"sourceQuery"`
+	}
+
+	// QueryPlan is a plan salesforce may use to run a query, plans are
+	// sorted by RelativeCost, the one with the lowest cost is used.
+	// A plan with RelativeCost over 1 means the query is not selective.
+	QueryPlan struct {
+		Cardinality          int              `json:"cardinality"`
+		Fields               []string         `json:"fields"`
+		LeadingOperationType string           `json:"leadingOperationType"`
+		Notes                []*QueryPlanNote `json:"notes"`
+		RelativeCost         float64          `json:"relativeCost"`
+		SobjectCardinality   int              `json:"sobjectCardinality"`
+		SobjectType          string           `json:"sobjectType"`
+	}
+
+	// QueryPlanNote tells why an index is not used by the plan.
+	QueryPlanNote struct {
+		Description   string   `json:"description"`
+		Fields        []string `json:"fields"`
+		TableEnumOrID string   `json:"tableEnumOrId"`
+	}
+
+	opExplain struct {
+		query  *OpQuery
+		result *ExplainResult
+	}
+)
+
+func (op *opExplain) Make(ctx *RequestCtx) (*Request, error) {
+	if op.query == nil {
+		return nil, errors.New("missing query")
+	}
+	// validates the query the same as running it
+	if _, err := op.query.Make(ctx); err != nil {
+		return nil, err
+	}
+	return NewRequest(http.MethodGet, ctx.QueryExplainURL(op.query.makeQueryStatment(ctx.Logger())), nil), nil
+}
+
+func (op *opExplain) Operation() Operation {
+	return Operation{Name: "explain", Sobject: op.query.sobjectName}
+}
+
+func (op *opExplain) Handle(resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("explain operator can't handle response with code %d, expect %d", resp.StatusCode, http.StatusOK)
+	}
+	return json.NewDecoder(resp.Body).Decode(&op.result)
+}
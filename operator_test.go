@@ -0,0 +1,300 @@
+package gosf
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sidebiequ/gosf/soql"
+)
+
+func TestMakeQueryStatment(t *testing.T) {
+	est := time.FixedZone("EST", -5*3600)
+	tests := []struct {
+		name string
+		op   *OpQuery
+		want string
+	}{
+		{
+			"no clauses",
+			NewOpQuery("Account").Select("Id", "Name"),
+			"SELECT Id,Name FROM Account",
+		},
+		{
+			"one where",
+			NewOpQuery("Account").Select("Id").Where("Name", "Acme"),
+			"SELECT Id FROM Account WHERE Name='Acme'",
+		},
+		{
+			"wheres joined by AND in order",
+			NewOpQuery("Account").Select("Id").Where("Name", "Acme").Where("Industry", "Energy").Where("Name", "Globex"),
+			"SELECT Id FROM Account WHERE Name='Acme' AND Industry='Energy' AND Name='Globex'",
+		},
+		{
+			"strings escaped",
+			NewOpQuery("Contact").Select("Id").Where("LastName", "O'Brien\\\n\t\r"),
+			`SELECT Id FROM Contact WHERE LastName='O\'Brien\\\n\t\r'`,
+		},
+		{
+			"injection quoted",
+			NewOpQuery("Account").Select("Id").Where("Name", "x' OR Name!='"),
+			`SELECT Id FROM Account WHERE Name='x\' OR Name!=\''`,
+		},
+		{
+			"numbers and booleans",
+			NewOpQuery("Account").Select("Id").Where("NumberOfEmployees", 10).Where("Rating", 4.5).Where("IsPartner", true),
+			"SELECT Id FROM Account WHERE NumberOfEmployees=10 AND Rating=4.5 AND IsPartner=true",
+		},
+		{
+			"id quoted",
+			NewOpQuery("Account").Select("Name").Where("Id", ID("001000000000001AAA")),
+			"SELECT Name FROM Account WHERE Id='001000000000001AAA'",
+		},
+		{
+			"times in UTC",
+			NewOpQuery("Account").Select("Id").WhereCompare("CreatedDate", ">=", time.Date(2024, 1, 2, 3, 4, 5, 0, est)),
+			"SELECT Id FROM Account WHERE CreatedDate>=2024-01-02T08:04:05Z",
+		},
+		{
+			"invalid condition skipped",
+			NewOpQuery("Account").Select("Id").Where("Name", []string{"Acme"}).Where("Industry", "Energy"),
+			"SELECT Id FROM Account WHERE Industry='Energy'",
+		},
+		{
+			"date literals",
+			NewOpQuery("Account").Select("Id").Where("CreatedDate", Today).WhereCompare("CloseDate", ">", LastNFiscalQuarters(2)),
+			"SELECT Id FROM Account WHERE CreatedDate=TODAY AND CloseDate>LAST_N_FISCAL_QUARTERS:2",
+		},
+		{
+			"order and limit without where",
+			NewOpQuery("Account").Select("Id").OrderAsc("Name").Limit(10),
+			"SELECT Id FROM Account ORDER BY Name ASC LIMIT 10",
+		},
+		{
+			"floats without exponents",
+			NewOpQuery("Account").Select("Id").WhereCompare("AnnualRevenue", ">", 1e21).WhereCompare("Rating", "<", 1e-7).Where("Score", float32(0.1)),
+			"SELECT Id FROM Account WHERE AnnualRevenue>1000000000000000000000 AND Rating<0.0000001 AND Score=0.1",
+		},
+		{
+			"order by columns",
+			NewOpQuery("Account").Select("Id").OrderBy("Rating", Descending, NullsLast).OrderAsc("Name").OrderBy("Industry", Ascending, NullsFirst),
+			"SELECT Id FROM Account ORDER BY Rating DESC NULLS LAST,Name ASC,Industry ASC NULLS FIRST",
+		},
+		{
+			"order nulls of columns without their own",
+			NewOpQuery("Account").Select("Id").OrderDesc("Rating").OrderBy("Name", Ascending, NullsFirst).OrderNullLast(),
+			"SELECT Id FROM Account ORDER BY Rating DESC NULLS LAST,Name ASC NULLS FIRST",
+		},
+		{
+			"order reset",
+			NewOpQuery("Account").Select("Id").OrderDesc("Rating").OrderReset().OrderAsc("Name"),
+			"SELECT Id FROM Account ORDER BY Name ASC",
+		},
+		{
+			"typeof",
+			NewOpQuery("Event").Select("Id").SelectTypeOf(NewTypeOf("What").When("Account", "Phone", "NumberOfEmployees").When("Opportunity", "Amount").Else("Name")),
+			"SELECT Id,TYPEOF What WHEN Account THEN Phone,NumberOfEmployees WHEN Opportunity THEN Amount ELSE Name END FROM Event",
+		},
+		{
+			"offset",
+			NewOpQuery("Account").Select("Id").OrderAsc("Name").Limit(10).Offset(20),
+			"SELECT Id FROM Account ORDER BY Name ASC LIMIT 10 OFFSET 20",
+		},
+		{
+			"using scope",
+			NewOpQuery("Account").Select("Id").UsingScope("mine"),
+			"SELECT Id FROM Account USING SCOPE mine",
+		},
+		{
+			"with security enforced",
+			NewOpQuery("Account").Select("Id").WithSecurityEnforced(),
+			"SELECT Id FROM Account WITH SECURITY_ENFORCED",
+		},
+		{
+			"for view",
+			NewOpQuery("Account").Select("Id").ForView(),
+			"SELECT Id FROM Account FOR VIEW",
+		},
+		{
+			"for update",
+			NewOpQuery("Account").Select("Id").Where("Name", "Acme").ForUpdate().Limit(1),
+			"SELECT Id FROM Account WHERE Name='Acme' LIMIT 1 FOR UPDATE",
+		},
+		{
+			"clauses in order",
+			NewOpQuery("Account").ForReference().Offset(5).Limit(10).OrderDesc("Name").WithUserMode().
+				Where("Industry", "Energy").UsingScope("team").Select("Id").
+				SelectTypeOf(NewTypeOf("Owner").When("User", "Email")),
+			"SELECT Id,TYPEOF Owner WHEN User THEN Email END FROM Account USING SCOPE team WHERE Industry='Energy' WITH USER_MODE ORDER BY Name DESC LIMIT 10 OFFSET 5 FOR REFERENCE",
+		},
+		{
+			"count",
+			NewOpQuery("Account").SelectCount().Where("Industry", "Energy"),
+			"SELECT COUNT() FROM Account WHERE Industry='Energy'",
+		},
+		{
+			"group by with aggregates",
+			NewOpQuery("Opportunity").Select("StageName").SelectAggregate(AggregateSum, "Amount", "").
+				SelectAggregate(AggregateCount, "Id", "total").GroupBy("StageName"),
+			"SELECT StageName,SUM(Amount),COUNT(Id) total FROM Opportunity GROUP BY StageName",
+		},
+		{
+			"group by rollup",
+			NewOpQuery("Account").Select("Industry", "Type").SelectAggregate(AggregateCount, "Id", "").GroupByRollup("Industry", "Type"),
+			"SELECT Industry,Type,COUNT(Id) FROM Account GROUP BY ROLLUP(Industry,Type)",
+		},
+		{
+			"group by cube",
+			NewOpQuery("Account").Select("Industry").SelectAggregate(AggregateMax, "Rating", "").GroupByCube("Industry"),
+			"SELECT Industry,MAX(Rating) FROM Account GROUP BY CUBE(Industry)",
+		},
+		{
+			"having after where and group by",
+			NewOpQuery("Lead").Select("LeadSource").SelectAggregate(AggregateCountDistinct, "Company", "").
+				Having("COUNT(Name)", ">", 100).Having("LeadSource", "!=", "Web").
+				Where("IsConverted", false).GroupBy("LeadSource").OrderDesc("LeadSource").Limit(5),
+			"SELECT LeadSource,COUNT_DISTINCT(Company) FROM Lead WHERE IsConverted=false GROUP BY LeadSource HAVING COUNT(Name)>100 AND LeadSource!='Web' ORDER BY LeadSource DESC LIMIT 5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.op.makeQueryStatment(&recordLogger{}); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMakeQueryRequestErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		op   *OpQuery
+		want string
+	}{
+		{"offset for update", NewOpQuery("Account").Select("Id").Offset(10).ForUpdate(), "OFFSET can't be used with FOR UPDATE"},
+		{"typeof without when", NewOpQuery("Event").Select("Id").SelectTypeOf(NewTypeOf("What").Else("Name")), "no WHEN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.op.Make(&RequestCtx{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateLogsNothing(t *testing.T) {
+	l := &recordLogger{}
+	saved := defaultLogger
+	defaultLogger = l
+	defer func() { defaultLogger = saved }()
+
+	schema := soql.StaticSchema{"Account": {Fields: []string{"Id", "Name"}}}
+	if err := NewOpQuery("Account").Validate(schema); err == nil {
+		t.Error("got no error of no select fields")
+	}
+	if err := NewOpQuery("Account").Select("Id").Where("Nope", 1).Validate(schema); err == nil {
+		t.Error("got no error of an unknown field")
+	}
+	if len(l.lines) != 0 {
+		t.Errorf("got logs %q", l.lines)
+	}
+}
+
+func TestInvalidHavingOperator(t *testing.T) {
+	op := NewOpQuery("Account").SelectCount().GroupBy("Name").Having("COUNT(Id)", "LIKE", 1)
+	if op.err == nil || !strings.Contains(op.err.Error(), "invalid having operator") {
+		t.Errorf("got %v, want invalid having operator", op.err)
+	}
+}
+
+func TestAggregateResult(t *testing.T) {
+	var count QueryResult
+	if err := json.Unmarshal([]byte(`{"totalSize":42,"done":true,"records":[]}`), &count); err != nil {
+		t.Fatal(err)
+	}
+	result, err := count.Aggregate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Count() != 42 || len(result.Records) != 0 {
+		t.Errorf("got count %d of %d records, want 42 of none", result.Count(), len(result.Records))
+	}
+
+	var grouped QueryResult
+	if err = json.Unmarshal([]byte(`{"totalSize":2,"done":true,"records":[
+		{"attributes":{"type":"AggregateResult"},"StageName":"Won","expr0":1500.5,"expr1":3,"total":2},
+		{"attributes":{"type":"AggregateResult"},"StageName":null,"expr0":null,"expr1":0,"total":1}
+	]}`), &grouped); err != nil {
+		t.Fatal(err)
+	}
+	if result, err = grouped.Aggregate(); err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalSize != 2 || len(result.Records) != 2 {
+		t.Fatalf("got %+v", result)
+	}
+	won, none := result.Records[0], result.Records[1]
+	if _, ok := won["attributes"]; ok {
+		t.Error("got attributes in the record")
+	}
+	if got := won.Expr(0); got != 1500.5 {
+		t.Errorf("got expr0 %v, want 1500.5", got)
+	}
+	if got, ok := won.Float64("expr0"); !ok || got != 1500.5 {
+		t.Errorf("got Float64 %v %v, want 1500.5", got, ok)
+	}
+	if got, ok := won.Int("expr1"); !ok || got != 3 {
+		t.Errorf("got Int %v %v, want 3", got, ok)
+	}
+	if got, ok := won.String("StageName"); !ok || got != "Won" {
+		t.Errorf("got String %q %v, want Won", got, ok)
+	}
+	if _, ok := none.Float64("expr0"); ok {
+		t.Error("got a null expr0 as a number")
+	}
+	if _, ok := none.String("StageName"); ok {
+		t.Error("got a null StageName as a string")
+	}
+	if _, ok := won.Int("StageName"); ok {
+		t.Error("got a string StageName as a number")
+	}
+
+	var rows []struct {
+		StageName *string
+		Sum       *float64 `json:"expr0"`
+		Total     int      `json:"total"`
+	}
+	if err = result.Parse(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || *rows[0].StageName != "Won" || *rows[0].Sum != 1500.5 || rows[0].Total != 2 ||
+		rows[1].StageName != nil || rows[1].Sum != nil || rows[1].Total != 1 {
+		t.Errorf("got rows %+v", rows)
+	}
+
+	bad := QueryResult{Records: []interface{}{"x"}}
+	if _, err = bad.Aggregate(); err == nil {
+		t.Error("got no error of a record not an object")
+	}
+}
+
+func TestInvalidDateLiteral(t *testing.T) {
+	tests := []struct {
+		name string
+		op   *OpQuery
+	}{
+		{"where", NewOpQuery("Account").Select("Id").Where("CreatedDate", DateLiteral("SOMEDAY"))},
+		{"where compare", NewOpQuery("Account").Select("Id").WhereCompare("CreatedDate", ">", LastNDays(-1))},
+		{"having", NewOpQuery("Account").SelectCount().GroupBy("Name").Having("MAX(CreatedDate)", "<", DateLiteral("TODAY:1"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.op.err == nil || !strings.Contains(tt.op.err.Error(), "invalid date literal") {
+				t.Errorf("got %v, want invalid date literal", tt.op.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package gosf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+/************************************/
+/************* NULLABLE *************/
+/************************************/
+
+// Nullable is a field can be unchanged, set to null or set to a value.
+// Tag it with omitzero, an unchanged field is left out of the json and a
+// null one is encoded as null, so UpdateSobject clears it:
+//
+//	type AccountUpdate struct {
+//		Name  gosf.Nullable[string] `json:"Name,omitzero"`
+//		Phone gosf.Nullable[string] `json:"Phone,omitzero"`
+//	}
+//
+//	// PATCH {"Phone":null}
+//	client.UpdateSobject("Account", id, AccountUpdate{Phone: gosf.Null[string]()})
+//
+// An unchanged Nullable can't be encoded, json.Marshal fails if a field
+// isn't tagged with omitzero rather than clearing the field. Decoding a
+// null value gives a null Nullable, a missing field an unchanged one.
+type Nullable[T any] struct {
+	value T
+	set   bool
+	valid bool
+}
+
+// NewNullable returns a Nullable set to v.
+func NewNullable[T any](v T) Nullable[T] {
+	return Nullable[T]{value: v, set: true, valid: true}
+}
+
+// Null returns a Nullable set to null.
+func Null[T any]() Nullable[T] {
+	return Nullable[T]{set: true}
+}
+
+// Get returns the value of n, ok is false if n is null or unchanged.
+func (n Nullable[T]) Get() (v T, ok bool) {
+	return n.value, n.valid
+}
+
+// IsNull returns true if n is set to null.
+func (n Nullable[T]) IsNull() bool {
+	return n.set && !n.valid
+}
+
+// IsZero returns true if n is unchanged, omitzero leaves it out.
+func (n Nullable[T]) IsZero() bool {
+	return !n.set
+}
+
+// MarshalJSON implements json.Marshaler, it fails if n is unchanged.
+func (n Nullable[T]) MarshalJSON() ([]byte, error) {
+	if !n.set {
+		return nil, errUnchangedNullable
+	}
+	if !n.valid {
+		return jsonNull, nil
+	}
+	return json.Marshal(n.value)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (n *Nullable[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, jsonNull) {
+		*n = Null[T]()
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*n = NewNullable(v)
+	return nil
+}
+
+// errUnchangedNullable is returned for encoding an unchanged Nullable.
+var errUnchangedNullable = errors.New("unchanged Nullable can't be encoded, tag the field with omitzero")
+
+/************************************/
+/************** PATCH ***************/
+/************************************/
+
+// Patch is the changes of a sobject, it's encoded as a json object of
+// exactly the fields set, in the order they're set:
+//
+//	patch := gosf.NewPatch().Set("Name", "Acme").SetNull("Phone")
+//	client.UpdateSobject("Account", id, patch)
+//
+// Setting a field again replaces its change. The zero Patch is empty and
+// ready to use.
+type Patch struct {
+	fields []string
+	values map[string]interface{}
+}
+
+// NewPatch returns an empty Patch.
+func NewPatch() *Patch {
+	return &Patch{values: make(map[string]interface{})}
+}
+
+// Set changes field to value, a nil value clears the field.
+func (p *Patch) Set(field string, value interface{}) *Patch {
+	if p.values == nil {
+		p.values = make(map[string]interface{})
+	}
+	if _, ok := p.values[field]; !ok {
+		p.fields = append(p.fields, field)
+	}
+	p.values[field] = value
+	return p
+}
+
+// SetNull clears field.
+func (p *Patch) SetNull(field string) *Patch {
+	return p.Set(field, nil)
+}
+
+// Fields returns the fields changed, in the order they're set.
+func (p *Patch) Fields() []string {
+	return append([]string(nil), p.fields...)
+}
+
+// Len returns the number of fields changed.
+func (p *Patch) Len() int {
+	return len(p.fields)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p Patch) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range p.fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(p.values[field])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// errEmptyPatch is returned for updating by a Patch without changes.
+var errEmptyPatch = errors.New("missing changes of Sobject")
+
+func isEmptyPatch(sobject interface{}) bool {
+	switch patch := sobject.(type) {
+	case *Patch:
+		return patch.Len() == 0
+	case Patch:
+		return patch.Len() == 0
+	default:
+		return false
+	}
+}
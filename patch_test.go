@@ -0,0 +1,101 @@
+package gosf_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sidebiequ/gosf"
+	"github.com/sidebiequ/gosf/gosftest"
+)
+
+type accountUpdate struct {
+	Name  gosf.Nullable[string] `json:"Name,omitzero"`
+	Phone gosf.Nullable[string] `json:"Phone,omitzero"`
+	Count gosf.Nullable[int]    `json:"Count,omitzero"`
+}
+
+func TestNullableMarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"unchanged omitted", accountUpdate{}, `{}`},
+		{"null", accountUpdate{Phone: gosf.Null[string]()}, `{"Phone":null}`},
+		{"zero value", accountUpdate{Count: gosf.NewNullable(0)}, `{"Count":0}`},
+		{"value", accountUpdate{Name: gosf.NewNullable("Acme")}, `{"Name":"Acme"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			byts, err := json.Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(byts) != tt.want {
+				t.Errorf("got %s, want %s", byts, tt.want)
+			}
+		})
+	}
+}
+
+func TestNullableUnchangedWithoutOmitzero(t *testing.T) {
+	v := struct {
+		Name gosf.Nullable[string] `json:"Name,omitempty"`
+	}{}
+	if _, err := json.Marshal(v); err == nil || !strings.Contains(err.Error(), "omitzero") {
+		t.Fatalf("got %v, want error of omitzero", err)
+	}
+}
+
+func TestNullableUnmarshal(t *testing.T) {
+	var v accountUpdate
+	if err := json.Unmarshal([]byte(`{"Phone":null,"Count":3}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if !v.Name.IsZero() || !v.Phone.IsNull() {
+		t.Errorf("got Name %+v, Phone %+v", v.Name, v.Phone)
+	}
+	if count, ok := v.Count.Get(); !ok || count != 3 {
+		t.Errorf("got Count %d, %v", count, ok)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	var zero gosf.Patch
+	zero.Set("Name", "Acme")
+	if byts, err := json.Marshal(&zero); err != nil || string(byts) != `{"Name":"Acme"}` {
+		t.Fatalf("got %s, %v", byts, err)
+	}
+
+	patch := gosf.NewPatch().Set("b", 1).SetNull("a").Set("b", 2)
+	byts, err := json.Marshal(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"b":2,"a":null}`; string(byts) != want {
+		t.Errorf("got %s, want %s", byts, want)
+	}
+}
+
+func TestUpdateSobjectClearsFields(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	id := srv.Insert("Account", gosftest.Record{"Name": "Acme", "Phone": "1", "Count": 3})
+	client := srv.Client()
+
+	if err := client.UpdateSobject("Account", id, accountUpdate{Phone: gosf.Null[string]()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.UpdateSobject("Account", id, gosf.NewPatch().SetNull("Count")); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.UpdateSobject("Account", id, gosf.NewPatch()); err == nil {
+		t.Error("expect error of empty patch")
+	}
+
+	rec, _ := srv.Get("Account", id)
+	if rec["Name"] != "Acme" || rec["Phone"] != nil || rec["Count"] != nil {
+		t.Errorf("got %v", rec)
+	}
+}
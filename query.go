@@ -0,0 +1,191 @@
+package gosf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+/************************************/
+/*********** TYPED QUERY ************/
+/************************************/
+
+// Query runs op and decodes all records into T, following nextRecordsUrl
+// until the query is done. See QueryIter for how records are decoded.
+func Query[T any](ctx context.Context, c *Client, op *OpQuery) ([]T, error) {
+	it := QueryIter[T](ctx, c, op)
+	var records []T
+	for it.Next() {
+		records = append(records, it.Record())
+	}
+	return records, it.Err()
+}
+
+// QueryIterator iterates the records of a query, fetching the next page
+// from salesforce when the current one is consumed:
+//
+//	it := gosf.QueryIter[Account](ctx, client, op)
+//	for it.Next() {
+//		account := it.Record()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type QueryIterator[T any] struct {
+	ctx     context.Context
+	client  *Client
+	page    *queryPage[T]
+	fetched bool
+	index   int
+	record  T
+	deleted bool
+	err     error
+}
+
+// QueryIter returns a QueryIterator of op. Records are decoded from the
+// response stream into T in one pass: the attributes envelope is skipped,
+// parent relationships decode into nested structs (Account.Owner.Name into
+// Account struct{ Owner struct{ Name string } }) and child relationship
+// subqueries of op decode into slices, without their records wrapper.
+func QueryIter[T any](ctx context.Context, c *Client, op *OpQuery) *QueryIterator[T] {
+	return &QueryIterator[T]{
+		ctx:    ctx,
+		client: c,
+		page:   &queryPage[T]{query: op},
+	}
+}
+
+// Next advances to the next record, returns false when there are no more
+// records or an error occurred.
+func (it *QueryIterator[T]) Next() bool {
+	for it.index >= len(it.page.records) {
+		if it.err != nil || (it.fetched && it.page.nextRecordsURL == "") {
+			return false
+		}
+		it.fetch()
+	}
+	it.record = it.page.records[it.index]
+	it.deleted = it.page.deleted[it.index]
+	it.index++
+	return true
+}
+
+func (it *QueryIterator[T]) fetch() {
+	page := &queryPage[T]{
+		query:   it.page.query,
+		nextURL: it.page.nextRecordsURL,
+	}
+	if it.err = it.client.doContext(it.ctx, page); it.err != nil {
+		return
+	}
+	it.page, it.index, it.fetched = page, 0, true
+}
+
+// Record returns the current record.
+func (it *QueryIterator[T]) Record() T {
+	return it.record
+}
+
+// Deleted returns true if the current record is deleted or archived, which
+// are only returned by queries with OpQuery.QueryAll and IsDeleted selected.
+func (it *QueryIterator[T]) Deleted() bool {
+	return it.deleted
+}
+
+// TotalSize returns the total number of records the query matches,
+// it is known after the first call of Next.
+func (it *QueryIterator[T]) TotalSize() int {
+	return it.page.totalSize
+}
+
+// Err returns the error occurred during iteration.
+func (it *QueryIterator[T]) Err() error {
+	return it.err
+}
+
+// queryPage is an Operator gets a page of query results. The first page is
+// made by query, the following pages are got from nextURL.
+type queryPage[T any] struct {
+	query   *OpQuery
+	nextURL string
+
+	totalSize      int
+	done           bool
+	nextRecordsURL string
+	records        []T
+	deleted        []bool
+}
+
+func (p *queryPage[T]) Make(ctx *RequestCtx) (*Request, error) {
+	if p.query == nil {
+		return nil, errors.New("missing query")
+	}
+	if p.nextURL == "" {
+		return p.query.Make(ctx)
+	}
+	return NewRequest(http.MethodGet, ctx.ResourceURL(p.nextURL), nil), nil
+}
+
+func (p *queryPage[T]) Operation() Operation {
+	return p.query.Operation()
+}
+
+// Handle decodes the page from the response stream:
+//
+//	{"totalSize": 1, "done": true, "nextRecordsUrl": "...", "records": [...]}
+func (p *queryPage[T]) Handle(resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("query operator can't handle response with code %d, expect %d", resp.StatusCode, http.StatusOK)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	if err := expectDelim(decoder, '{'); err != nil {
+		return err
+	}
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		switch key {
+		case "totalSize":
+			err = decoder.Decode(&p.totalSize)
+		case "done":
+			err = decoder.Decode(&p.done)
+		case "nextRecordsUrl":
+			err = decoder.Decode(&p.nextRecordsURL)
+		case "records":
+			err = p.decodeRecords(decoder)
+		default:
+			var skip json.RawMessage
+			err = decoder.Decode(&skip)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return expectDelim(decoder, '}')
+}
+
+func (p *queryPage[T]) decodeRecords(decoder *json.Decoder) error {
+	if err := expectDelim(decoder, '['); err != nil {
+		return err
+	}
+	rels := p.query.relationships()
+	d := &recordDecoder{}
+	for decoder.More() {
+		var record T
+		var deleted bool
+		d.deleted = &deleted
+		if err := d.decode(decoder, reflect.ValueOf(&record).Elem(), rels); err != nil {
+			return err
+		}
+		p.records = append(p.records, record)
+		p.deleted = append(p.deleted, deleted)
+	}
+	return expectDelim(decoder, ']')
+}
@@ -0,0 +1,108 @@
+package gosf_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sidebiequ/gosf"
+	"github.com/sidebiequ/gosf/gosftest"
+)
+
+type queryContact struct {
+	Email   string
+	Account *struct {
+		Name  string
+		Owner struct{ Name string }
+	}
+}
+
+type queryAccount struct {
+	Name     string
+	Contacts []queryContact
+}
+
+func TestQueryDecode(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	srv.BatchSize = 1
+	owner := srv.Insert("User", gosftest.Record{"Name": "Boss"})
+	acme := srv.Insert("Account", gosftest.Record{"Name": "Acme", "OwnerId": owner})
+	srv.Insert("Account", gosftest.Record{"Name": "Empty", "OwnerId": owner})
+	srv.Insert("Contact", gosftest.Record{"Email": "a@acme.com", "AccountId": acme})
+	gone := srv.Insert("Contact", gosftest.Record{"Email": "b@acme.com", "AccountId": acme})
+	client := srv.Client()
+	if err := client.DeleteSobject("Contact", gone); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	t.Run("parent relationships", func(t *testing.T) {
+		op := gosf.NewOpQuery("Contact").Select("Email", "Account.Name", "Account.Owner.Name").OrderAsc("Email")
+		contacts, err := gosf.Query[queryContact](ctx, client, op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(contacts) != 1 || contacts[0].Account == nil || contacts[0].Account.Owner.Name != "Boss" {
+			t.Errorf("got %+v", contacts)
+		}
+	})
+
+	subquery := func() *gosf.OpQuery {
+		return gosf.NewOpQuery("Account").Select("Name").OrderAsc("Name").
+			SelectSubquery(gosf.NewOpQuery("Contacts").Select("Email"))
+	}
+	t.Run("child relationships into structs", func(t *testing.T) {
+		accounts, err := gosf.Query[queryAccount](ctx, client, subquery())
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []queryAccount{
+			{Name: "Acme", Contacts: []queryContact{{Email: "a@acme.com"}}},
+			{Name: "Empty"},
+		}
+		if !reflect.DeepEqual(accounts, want) {
+			t.Errorf("got %+v, want %+v", accounts, want)
+		}
+	})
+	t.Run("child relationships into maps", func(t *testing.T) {
+		accounts, err := gosf.Query[map[string]interface{}](ctx, client, subquery())
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []map[string]interface{}{
+			{"Name": "Acme", "Contacts": []interface{}{map[string]interface{}{"Email": "a@acme.com"}}},
+			{"Name": "Empty", "Contacts": nil},
+		}
+		if !reflect.DeepEqual(accounts, want) {
+			t.Errorf("got %+v, want %+v", accounts, want)
+		}
+	})
+	t.Run("parse child relationships", func(t *testing.T) {
+		result, err := client.QuerySobject(subquery())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var accounts []map[string]interface{}
+		if err = result.Parse(&accounts); err != nil {
+			t.Fatal(err)
+		}
+		if contacts, ok := accounts[0]["Contacts"].([]interface{}); !ok || len(contacts) != 1 {
+			t.Errorf("got %+v", accounts)
+		}
+	})
+	t.Run("deleted", func(t *testing.T) {
+		it := gosf.QueryIter[struct{ Email string }](ctx, client,
+			gosf.NewOpQuery("Contact").Select("Email", "IsDeleted").OrderAsc("Email").QueryAll())
+		var deleted []bool
+		for it.Next() {
+			deleted = append(deleted, it.Deleted())
+		}
+		if err := it.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if want := []bool{false, true}; !reflect.DeepEqual(deleted, want) {
+			t.Errorf("got %v, want %v", deleted, want)
+		}
+	})
+}
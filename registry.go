@@ -0,0 +1,246 @@
+package gosf
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+/************************************/
+/************ ATTRIBUTES ************/
+/************************************/
+
+// Attributes is the attributes envelope of records, decode it by a field
+// tagged `json:"attributes"`:
+//
+//	type Account struct {
+//		Attributes gosf.Attributes `json:"attributes"`
+//		Name       string          `sf:"Name"`
+//	}
+//
+// Records are decoded without their attributes unless the target has a
+// field of them.
+type Attributes struct {
+	Type string `json:"type"`
+	URL  string `json:"url,omitempty"`
+}
+
+// ID returns the id of the record in URL, empty if there's none.
+func (a Attributes) ID() ID {
+	if i := strings.LastIndexByte(a.URL, '/'); i >= 0 {
+		return ID(a.URL[i+1:])
+	}
+	return ""
+}
+
+/************************************/
+/*********** TYPE REGISTRY **********/
+/************************************/
+
+// TypeRegistry maps sobject names to Go types, so records of different
+// sobjects can be decoded into their own structs by attributes.type:
+//
+//	registry := gosf.NewTypeRegistry()
+//	registry.Register("Account", Account{})
+//	registry.RegisterSobject(Contact{}, Lead{})
+//
+//	records, err := result.Decode(registry)
+//	for _, record := range records {
+//		switch record := record.(type) {
+//		case *Account:
+//		case *Contact:
+//		}
+//	}
+//
+// Records of sobjects not registered are decoded into
+// map[string]interface{}, see DecodeRecord. It's safe for concurrent use.
+type TypeRegistry struct {
+	mu    sync.RWMutex
+	types map[string]reflect.Type
+}
+
+// DefaultTypeRegistry is the registry of Decode methods given a nil
+// registry, and the one Polymorphic fields are decoded by outside of them.
+var DefaultTypeRegistry = NewTypeRegistry()
+
+// NewTypeRegistry returns an empty TypeRegistry.
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{types: make(map[string]reflect.Type)}
+}
+
+// Register maps sobjectName to the type of prototype, which is a struct or
+// pointer to struct. Records of sobjectName are decoded into pointers to
+// the struct.
+func (r *TypeRegistry) Register(sobjectName string, prototype interface{}) *TypeRegistry {
+	t := reflect.TypeOf(prototype)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("gosf: Register of %s expects a struct, got %T", sobjectName, prototype))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.types[strings.ToLower(sobjectName)] = t
+	return r
+}
+
+// RegisterSobject registers prototypes by their SobjectName.
+func (r *TypeRegistry) RegisterSobject(prototypes ...SobjectNamer) *TypeRegistry {
+	for _, prototype := range prototypes {
+		r.Register(prototype.SobjectName(), prototype)
+	}
+	return r
+}
+
+// Lookup returns the struct type registered for sobjectName case
+// insensitively.
+func (r *TypeRegistry) Lookup(sobjectName string) (t reflect.Type, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok = r.types[strings.ToLower(sobjectName)]
+	return
+}
+
+// DecodeRecord decodes a record in json into a pointer to the struct
+// registered for its attributes.type, or a map[string]interface{} with the
+// attributes kept if the type isn't registered. Records are decoded like
+// QueryResult.Parse does.
+func (r *TypeRegistry) DecodeRecord(raw json.RawMessage) (record interface{}, err error) {
+	return r.decodeRecord(raw, nil)
+}
+
+// decodeRecord is DecodeRecord of a record has the child relationships rels.
+func (r *TypeRegistry) decodeRecord(raw json.RawMessage, rels relationships) (record interface{}, err error) {
+	var envelope struct {
+		Attributes *Attributes `json:"attributes"`
+	}
+	if err = json.Unmarshal(raw, &envelope); err != nil {
+		return
+	}
+	if envelope.Attributes == nil || envelope.Attributes.Type == "" {
+		return nil, fmt.Errorf("record has no attributes.type")
+	}
+
+	t, ok := r.Lookup(envelope.Attributes.Type)
+	if !ok {
+		fields := make(map[string]interface{})
+		d := &recordDecoder{keepAttributes: true, registry: r}
+		err = d.unmarshal(raw, &fields, rels)
+		return fields, err
+	}
+	target := reflect.New(t)
+	if err = (&recordDecoder{registry: r}).unmarshal(raw, target.Interface(), rels); err != nil {
+		return nil, fmt.Errorf("decode %s record: %w", envelope.Attributes.Type, err)
+	}
+	record = target.Interface()
+	return
+}
+
+// decodeRecords decodes records has the child relationships rels by
+// registry, DefaultTypeRegistry if it's nil.
+func decodeRecords(registry *TypeRegistry, records []interface{}, rels relationships) ([]interface{}, error) {
+	if registry == nil {
+		registry = DefaultTypeRegistry
+	}
+	decoded := make([]interface{}, 0, len(records))
+	for i, record := range records {
+		byts, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		value, err := registry.decodeRecord(byts, rels)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+		decoded = append(decoded, value)
+	}
+	return decoded, nil
+}
+
+// Decode decodes the records by the types registered in registry for
+// their attributes.type, DefaultTypeRegistry is used if it's nil.
+func (r *QueryResult) Decode(registry *TypeRegistry) ([]interface{}, error) {
+	return decodeRecords(registry, r.Records, r.relationships)
+}
+
+// Decode decodes the records of all sobjects like QueryResult.Decode.
+func (r *SearchResult) Decode(registry *TypeRegistry) ([]interface{}, error) {
+	return decodeRecords(registry, r.Records, nil)
+}
+
+/************************************/
+/*********** POLYMORPHIC ************/
+/************************************/
+
+// Polymorphic is a polymorphic relationship field like What, Who or Owner,
+// it's decoded by the attributes of the related record, by the registry
+// of the Decode method decoding the record or DefaultTypeRegistry:
+//
+//	type Task struct {
+//		Subject string           `sf:"Subject"`
+//		What    gosf.Polymorphic `sf:"-"` // selected by SelectTypeOf
+//	}
+//
+//	if account, ok := task.What.Value.(*Account); ok {
+//		...
+//	}
+//
+// Value is nil if the relationship is null.
+type Polymorphic struct {
+	Attributes Attributes
+	Value      interface{}
+}
+
+// Type returns the sobject name of the related record.
+func (p Polymorphic) Type() string {
+	return p.Attributes.Type
+}
+
+// MarshalJSON implements json.Marshaler, it encodes Value with
+// Attributes, so it's decoded back to the same type.
+func (p Polymorphic) MarshalJSON() ([]byte, error) {
+	byts, err := json.Marshal(p.Value)
+	if err != nil || firstByte(byts) != '{' || p.Attributes.Type == "" {
+		return byts, err
+	}
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(byts, &fields); err != nil {
+		return nil, err
+	}
+	if _, ok := fields["attributes"]; !ok {
+		if fields["attributes"], err = json.Marshal(p.Attributes); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, it decodes by
+// DefaultTypeRegistry.
+func (p *Polymorphic) UnmarshalJSON(data []byte) error {
+	return p.decode(data, DefaultTypeRegistry)
+}
+
+// decode decodes the related record in data by registry.
+func (p *Polymorphic) decode(data []byte, registry *TypeRegistry) error {
+	if firstByte(data) != '{' {
+		*p = Polymorphic{}
+		return nil
+	}
+	var envelope struct {
+		Attributes Attributes `json:"attributes"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return err
+	}
+	value, err := registry.DecodeRecord(data)
+	if err != nil {
+		return err
+	}
+	*p = Polymorphic{Attributes: envelope.Attributes, Value: value}
+	return nil
+}
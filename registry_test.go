@@ -0,0 +1,81 @@
+package gosf_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/sidebiequ/gosf"
+)
+
+type registryAccount struct {
+	Name string
+}
+
+type registryTask struct {
+	Attributes gosf.Attributes   `json:"attributes"`
+	Subject    string            `json:"Subject"`
+	What       gosf.Polymorphic  `json:"What"`
+	Who        *gosf.Polymorphic `json:"Who"`
+}
+
+func TestDecodePolymorphic(t *testing.T) {
+	registry := gosf.NewTypeRegistry().
+		Register("Task", registryTask{}).
+		Register("Account", registryAccount{})
+	result := &gosf.QueryResult{}
+	err := json.Unmarshal([]byte(`{"totalSize":1,"done":true,"records":[{
+		"attributes":{"type":"Task","url":"/t/00T"},"Subject":"Call",
+		"What":{"attributes":{"type":"Account","url":"/a/001"},"Name":"Acme"},
+		"Who":null}]}`), result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := result.Decode(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task, ok := records[0].(*registryTask)
+	if !ok {
+		t.Fatalf("got %T, want *registryTask", records[0])
+	}
+	if account, ok := task.What.Value.(*registryAccount); !ok || account.Name != "Acme" || task.What.Type() != "Account" {
+		t.Errorf("got What %+v, want *registryAccount by the registry", task.What)
+	}
+	if task.Who != nil {
+		t.Errorf("got Who %+v, want nil", task.Who)
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		task.Who = &gosf.Polymorphic{
+			Attributes: gosf.Attributes{Type: "Account"},
+			Value:      &registryAccount{Name: "Globex"},
+		}
+		byts, err := json.Marshal(task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := registry.DecodeRecord(byts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, task) {
+			t.Errorf("got  %+v\nwant %+v\nof %s", decoded, task, byts)
+		}
+	})
+
+	t.Run("default registry", func(t *testing.T) {
+		var what gosf.Polymorphic
+		if err := json.Unmarshal([]byte(`{"attributes":{"type":"Unregistered__c"},"Name":"x"}`), &what); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]interface{}{"attributes": map[string]interface{}{"type": "Unregistered__c"}, "Name": "x"}
+		if !reflect.DeepEqual(what.Value, want) {
+			t.Errorf("got %+v, want %+v", what.Value, want)
+		}
+		byts, err := json.Marshal(what)
+		if err != nil || string(byts) != `{"Name":"x","attributes":{"type":"Unregistered__c"}}` {
+			t.Errorf("got %s, %v", byts, err)
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package gosf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+/************************************/
+/*********** REPLICATION ************/
+/************************************/
+
+// MaxReplicationRange is the longest range salesforce accepts in a
+// request of updated or deleted records, GetUpdated and GetDeleted split
+// longer ranges.
+const MaxReplicationRange = 30 * 24 * time.Hour
+
+type (
+	// UpdatedResult is the result of GetUpdated.
+	UpdatedResult struct {
+		// IDs are the ids of records updated in the range.
+		IDs []ID `json:"ids"`
+		// LatestDateCovered is the end of the range the result covers,
+		// continue from it in the next sync.
+		LatestDateCovered DateTime `json:"latestDateCovered"`
+	}
+
+	// DeletedResult is the result of GetDeleted.
+	DeletedResult struct {
+		DeletedRecords []*DeletedRecord `json:"deletedRecords"`
+		// EarliestDateAvailable is when the oldest deleted record
+		// salesforce keeps was deleted.
+		EarliestDateAvailable DateTime `json:"earliestDateAvailable"`
+		// LatestDateCovered is the end of the range the result covers,
+		// continue from it in the next sync.
+		LatestDateCovered DateTime `json:"latestDateCovered"`
+	}
+
+	// DeletedRecord is a record deleted in the range of GetDeleted.
+	DeletedRecord struct {
+		ID          ID       `json:"id"`
+		DeletedDate DateTime `json:"deletedDate"`
+	}
+)
+
+// opReplication is a request for the records updated or deleted in a
+// range, kind is "updated" or "deleted".
+type opReplication struct {
+	kind        string
+	sobjectName string
+	start       time.Time
+	end         time.Time
+	result      interface{}
+}
+
+func (op *opReplication) Make(ctx *RequestCtx) (*Request, error) {
+	switch {
+	case op.sobjectName == "":
+		return nil, errors.New("missing Sobject name")
+	case op.start.IsZero() || op.end.IsZero():
+		return nil, errors.New("missing start or end of range")
+	case !op.end.After(op.start):
+		return nil, fmt.Errorf("end %s of range is not after start %s", op.end.Format(time.RFC3339), op.start.Format(time.RFC3339))
+	case op.end.Sub(op.start) > MaxReplicationRange:
+		return nil, fmt.Errorf("range from %s to %s exceeds %s", op.start.Format(time.RFC3339), op.end.Format(time.RFC3339), MaxReplicationRange)
+	default:
+		return NewRequest(http.MethodGet, ctx.SobjectReplicationURL(op.sobjectName, op.kind, op.start, op.end), nil), nil
+	}
+}
+
+func (op *opReplication) Operation() Operation {
+	return Operation{Name: op.kind, Sobject: op.sobjectName}
+}
+
+func (op *opReplication) Handle(resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s operator can't handle response with code %d, expect %d", op.kind, resp.StatusCode, http.StatusOK)
+	}
+	return json.NewDecoder(resp.Body).Decode(op.result)
+}
+
+// replicationRanges splits the range from start to end into ranges no
+// longer than MaxReplicationRange.
+func replicationRanges(start, end time.Time) (ranges [][2]time.Time) {
+	for start.Before(end) {
+		next := start.Add(MaxReplicationRange)
+		if next.After(end) {
+			next = end
+		}
+		ranges = append(ranges, [2]time.Time{start, next})
+		start = next
+	}
+	return
+}
+
+// GetUpdated returns the ids of sobjectName records updated from start to
+// end, ranges longer than MaxReplicationRange are requested in parts.
+func (c *Client) GetUpdated(sobjectName string, start, end time.Time) (result *UpdatedResult, err error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("end %s of range is not after start %s", end.Format(time.RFC3339), start.Format(time.RFC3339))
+	}
+
+	result = &UpdatedResult{IDs: make([]ID, 0)}
+	seen := make(map[ID]bool)
+	for _, r := range replicationRanges(start, end) {
+		part := &UpdatedResult{}
+		op := &opReplication{kind: "updated", sobjectName: sobjectName, start: r[0], end: r[1], result: part}
+		if err = c.do(op); err != nil {
+			return nil, err
+		}
+		// a record updated in several parts is returned once
+		for _, id := range part.IDs {
+			if !seen[id] {
+				seen[id] = true
+				result.IDs = append(result.IDs, id)
+			}
+		}
+		result.LatestDateCovered = part.LatestDateCovered
+	}
+	return
+}
+
+// GetDeleted returns the sobjectName records deleted from start to end,
+// ranges longer than MaxReplicationRange are requested in parts.
+func (c *Client) GetDeleted(sobjectName string, start, end time.Time) (result *DeletedResult, err error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("end %s of range is not after start %s", end.Format(time.RFC3339), start.Format(time.RFC3339))
+	}
+
+	result = &DeletedResult{DeletedRecords: make([]*DeletedRecord, 0)}
+	seen := make(map[ID]bool)
+	for i, r := range replicationRanges(start, end) {
+		part := &DeletedResult{}
+		op := &opReplication{kind: "deleted", sobjectName: sobjectName, start: r[0], end: r[1], result: part}
+		if err = c.do(op); err != nil {
+			return nil, err
+		}
+		// a record deleted at the end of a part is returned by the next too
+		for _, record := range part.DeletedRecords {
+			if !seen[record.ID] {
+				seen[record.ID] = true
+				result.DeletedRecords = append(result.DeletedRecords, record)
+			}
+		}
+		if i == 0 {
+			result.EarliestDateAvailable = part.EarliestDateAvailable
+		}
+		result.LatestDateCovered = part.LatestDateCovered
+	}
+	return
+}
@@ -0,0 +1,76 @@
+package gosf_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sidebiequ/gosf"
+	"github.com/sidebiequ/gosf/gosftest"
+)
+
+func TestReplicationSplit(t *testing.T) {
+	srv := gosftest.NewServer()
+	defer srv.Close()
+	start := time.Now().UTC().Truncate(time.Minute).Add(-90 * 24 * time.Hour)
+	boundary := start.Add(gosf.MaxReplicationRange)
+	end := start.Add(60 * 24 * time.Hour)
+	insert := func(at time.Time, deleted bool) gosf.ID {
+		return gosf.ID(srv.Insert("Account", gosftest.Record{
+			"Name":           "Acme",
+			"IsDeleted":      deleted,
+			"SystemModstamp": at.Format(gosf.DateTimeLayout),
+		}))
+	}
+	updatedFirst := insert(start.Add(time.Hour), false)
+	updatedBoundary := insert(boundary, false)
+	updatedLast := insert(end.Add(-time.Hour), false)
+	insert(end.Add(time.Hour), false)
+	deletedFirst := insert(start.Add(time.Hour), true)
+	deletedBoundary := insert(boundary, true)
+	deletedLast := insert(end.Add(-time.Hour), true)
+	insert(start.Add(-time.Hour), true)
+	client := srv.Client()
+
+	t.Run("updated", func(t *testing.T) {
+		result, err := client.GetUpdated("Account", start, end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []gosf.ID{updatedFirst, updatedBoundary, updatedLast}; !reflect.DeepEqual(result.IDs, want) {
+			t.Errorf("got %v, want %v", result.IDs, want)
+		}
+		if !result.LatestDateCovered.Equal(end) {
+			t.Errorf("got latest date covered %s, want %s", result.LatestDateCovered, end)
+		}
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		result, err := client.GetDeleted("Account", start, end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []gosf.ID
+		for _, record := range result.DeletedRecords {
+			ids = append(ids, record.ID)
+		}
+		if want := []gosf.ID{deletedFirst, deletedBoundary, deletedLast}; !reflect.DeepEqual(ids, want) {
+			t.Errorf("got %v, want %v", ids, want)
+		}
+		if !result.DeletedRecords[1].DeletedDate.Equal(boundary) {
+			t.Errorf("got deleted date %s, want %s", result.DeletedRecords[1].DeletedDate, boundary)
+		}
+		if want := start.Add(-time.Hour); !result.EarliestDateAvailable.Equal(want) {
+			t.Errorf("got earliest date available %s, want %s", result.EarliestDateAvailable, want)
+		}
+		if !result.LatestDateCovered.Equal(end) {
+			t.Errorf("got latest date covered %s, want %s", result.LatestDateCovered, end)
+		}
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		if _, err := client.GetDeleted("Account", end, start); err == nil {
+			t.Error("got no error of end before start")
+		}
+	})
+}
@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 /*************************************/
@@ -18,6 +21,7 @@ type Request struct {
 	method string
 	urlStr string
 	data   interface{}
+	header http.Header
 }
 
 // NewRequest returns a new Request given a method, URL, and optional data.
@@ -29,25 +33,39 @@ func NewRequest(method, urlStr string, data interface{}) *Request {
 	}
 }
 
-func (r *Request) makeRequest() (*http.Request, error) {
+// SetHeader sets a header of the request, like If-Modified-Since.
+func (r *Request) SetHeader(key, value string) *Request {
+	if r.header == nil {
+		r.header = make(http.Header)
+	}
+	r.header.Set(key, value)
+	return r
+}
+
+func (r *Request) makeRequest() (req *http.Request, err error) {
 	if r.method == "" {
 		return nil, errors.New("missing request method")
 	}
 
-	if _, err := url.Parse(r.urlStr); err != nil {
-		return nil, err
+	if _, err = url.Parse(r.urlStr); err != nil {
+		return
 	}
-	fmt.Println("r.data is %s", r.data)
 	if r.data == nil {
-		return http.NewRequest(r.method, r.urlStr, nil)
+		req, err = http.NewRequest(r.method, r.urlStr, nil)
+	} else {
+		var byts []byte
+		if byts, err = json.Marshal(r.data); err != nil {
+			return
+		}
+		req, err = http.NewRequest(r.method, r.urlStr, bytes.NewReader(byts))
 	}
-	fmt.Println("r.data is %s", r.data)
-	byts, err := json.Marshal(r.data)
 	if err != nil {
-		return nil, err
+		return
 	}
-	return http.NewRequest(r.method, r.urlStr, bytes.NewReader(byts))
-
+	for key, values := range r.header {
+		req.Header[key] = values
+	}
+	return
 }
 
 /*************************************/
@@ -55,14 +73,80 @@ func (r *Request) makeRequest() (*http.Request, error) {
 /*************************************/
 
 const (
-	minAPIVersion = 8
-	maxAPIVersion = 37
+	minAPIVersion APIVersion = 8
+	// defaultAPIVersion is used if config.APIVersion is not set, newer
+	// versions can be configured or negotiated.
+	defaultAPIVersion APIVersion = 66
 )
 
+// APIVersion is the version of salesforce rest api, like 59.0.
+type APIVersion float64
+
+// ParseAPIVersion parses version strings like "59.0", "v59.0" or "59".
+func ParseAPIVersion(s string) (APIVersion, error) {
+	f, err := strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(s), "v"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid api version %q", s)
+	}
+	return APIVersion(f), nil
+}
+
+// String returns the version in salesforce's format, like "59.0".
+func (v APIVersion) String() string {
+	return strconv.FormatFloat(float64(v), 'f', 1, 64)
+}
+
+// UnmarshalJSON accepts both numbers (59) and strings ("59.0", "v59.0").
+func (v *APIVersion) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var f float64
+		if err := json.Unmarshal(data, &f); err != nil {
+			return fmt.Errorf("invalid api version %s", data)
+		}
+		*v = APIVersion(f)
+		return nil
+	}
+
+	parsed, err := ParseAPIVersion(s)
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
 // RequestCtx holds the host and api version informations.
 type RequestCtx struct {
 	host    string
-	version int
+	version APIVersion
+	logger  Logger
+}
+
+// Logger returns the logger of the Client makes the request.
+func (ctx *RequestCtx) Logger() Logger {
+	if ctx.logger == nil {
+		return defaultLogger
+	}
+	return ctx.logger
+}
+
+// Version returns the api version requests are made with.
+func (ctx *RequestCtx) Version() APIVersion {
+	return ctx.version
+}
+
+// WithVersion returns a copy of ctx using the given api version, so an
+// Operator can pin the version it needs in Make:
+//
+//	func (op *myOp) Make(ctx *gosf.RequestCtx) (*gosf.Request, error) {
+//		ctx = ctx.WithVersion(59)
+//		return gosf.NewRequest(http.MethodGet, ctx.SobjectURL(), nil), nil
+//	}
+func (ctx *RequestCtx) WithVersion(version APIVersion) *RequestCtx {
+	c := *ctx
+	c.version = version
+	return &c
 }
 
 // BaseURL returns the base URL of salesforce restful api.
@@ -72,10 +156,16 @@ func (ctx *RequestCtx) BaseURL() string {
 	return fmt.Sprintf("%s/services/data", ctx.host)
 }
 
+// ResourceURL returns the absolute URL of path salesforce responds, like
+// nextRecordsUrl "/services/data/v36.0/query/01gD0000002HU6KIAW-2000".
+func (ctx *RequestCtx) ResourceURL(path string) string {
+	return ctx.host + path
+}
+
 // VersionURL returns the URL with version, like:
 // "https://instance.salesforce.com/services/data/v36.0"
 func (ctx *RequestCtx) VersionURL() string {
-	return fmt.Sprintf("%s/v%d.0", ctx.BaseURL(), ctx.version)
+	return fmt.Sprintf("%s/v%s", ctx.BaseURL(), ctx.version)
 }
 
 // QueryURL returns the URL with query SOQL statments, like:
@@ -84,6 +174,50 @@ func (ctx *RequestCtx) QueryURL(q string) string {
 	return fmt.Sprintf("%s/query?q=%s", ctx.VersionURL(), url.QueryEscape(q))
 }
 
+// QueryExplainURL returns the URL gets the query plans of SOQL statment, like:
+// "https://instance.salesforce.com/services/data/v30.0/query/?explain=SELECT+Id+FROM+User"
+func (ctx *RequestCtx) QueryExplainURL(q string) string {
+	return fmt.Sprintf("%s/query/?explain=%s", ctx.VersionURL(), url.QueryEscape(q))
+}
+
+// QueryAllURL returns the URL with query SOQL statments includes deleted
+// and archived records, like:
+// "https://instance.salesforce.com/services/data/v29.0/queryAll?q=SELECT+Id,+Name+FROM+User"
+func (ctx *RequestCtx) QueryAllURL(q string) string {
+	return fmt.Sprintf("%s/queryAll?q=%s", ctx.VersionURL(), url.QueryEscape(q))
+}
+
+// SearchURL returns the URL with SOSL statments, like:
+// "https://instance.salesforce.com/services/data/v37.0/search/?q=FIND+%7BAcme%7D"
+func (ctx *RequestCtx) SearchURL(q string) string {
+	return fmt.Sprintf("%s/search/?q=%s", ctx.VersionURL(), url.QueryEscape(q))
+}
+
+// ParameterizedSearchURL returns the URL of parameterized search, like:
+// "https://instance.salesforce.com/services/data/v37.0/parameterizedSearch/"
+func (ctx *RequestCtx) ParameterizedSearchURL() string {
+	return fmt.Sprintf("%s/parameterizedSearch/", ctx.VersionURL())
+}
+
+// SearchSuggestionsURL returns the URL of search suggestions, limit<=0 is
+// omitted, like:
+// "https://instance.salesforce.com/services/data/v37.0/search/suggestions?q=Acme&sobject=Account"
+func (ctx *RequestCtx) SearchSuggestionsURL(q, sobjectName string, limit int) string {
+	params := url.Values{}
+	params.Set("q", q)
+	params.Set("sobject", sobjectName)
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	return fmt.Sprintf("%s/search/suggestions?%s", ctx.VersionURL(), params.Encode())
+}
+
+// SearchScopeOrderURL returns the URL of search scope and order, like:
+// "https://instance.salesforce.com/services/data/v37.0/search/scopeOrder"
+func (ctx *RequestCtx) SearchScopeOrderURL() string {
+	return fmt.Sprintf("%s/search/scopeOrder", ctx.VersionURL())
+}
+
 // SobjectURL returns the URL can work with SObjects, like:
 // "https://instance.salesforce.com/services/data/v36.0/sobjects"
 func (ctx *RequestCtx) SobjectURL() string {
@@ -97,6 +231,13 @@ func (ctx *RequestCtx) SobjectURLWithName(sobjectName string) string {
 	return fmt.Sprintf("%s/%s", ctx.SobjectURL(), sobjectName)
 }
 
+// SobjectDescribeURL returns the URL describes specific sobject.
+// Assume the given sobject is 'User', the return URL will be:
+// "https://instance.salesforce.com/services/data/v36.0/sobjects/User/describe"
+func (ctx *RequestCtx) SobjectDescribeURL(sobjectName string) string {
+	return fmt.Sprintf("%s/describe", ctx.SobjectURLWithName(sobjectName))
+}
+
 // SobjectURLWithID returns the URL with specific sobject and id.
 // Assume the given sobject is 'User' and id is '00e28000001K04LAA1',
 // the return URL will be:
@@ -105,6 +246,27 @@ func (ctx *RequestCtx) SobjectURLWithID(sobjectName, sobjectID string) string {
 	return fmt.Sprintf("%s/%s", ctx.SobjectURLWithName(sobjectName), sobjectID)
 }
 
+// SobjectURLWithExternalID returns the URL with specific sobject and the
+// value of an external id field, the value is escaped.
+// Assume the given sobject is 'Account', field is 'Code__c' and value is
+// 'A/1', the return URL will be:
+// "https://instance.salesforce.com/services/data/v36.0/sobjects/Account/Code__c/A%2F1"
+func (ctx *RequestCtx) SobjectURLWithExternalID(sobjectName, field, value string) string {
+	return fmt.Sprintf("%s/%s/%s", ctx.SobjectURLWithName(sobjectName), field, url.PathEscape(value))
+}
+
+// SobjectReplicationURL returns the URL of the records of specific sobject
+// updated or deleted in a range, kind is 'updated' or 'deleted'.
+// Assume the given sobject is 'Account', kind is 'updated' and the range is
+// a day, the return URL will be:
+// "https://instance.salesforce.com/services/data/v36.0/sobjects/Account/updated/?end=2024-01-02T00%3A00%3A00Z&start=2024-01-01T00%3A00%3A00Z"
+func (ctx *RequestCtx) SobjectReplicationURL(sobjectName, kind string, start, end time.Time) string {
+	params := url.Values{}
+	params.Set("start", start.UTC().Format(time.RFC3339))
+	params.Set("end", end.UTC().Format(time.RFC3339))
+	return fmt.Sprintf("%s/%s/?%s", ctx.SobjectURLWithName(sobjectName), kind, params.Encode())
+}
+
 func (ctx *RequestCtx) isVersionValid() bool {
-	return ctx.version >= minAPIVersion && ctx.version <= maxAPIVersion
+	return ctx.version >= minAPIVersion
 }
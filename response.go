@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type (
@@ -42,6 +44,18 @@ func parseErrResponse(resp *http.Response) (err error) {
 		status:     resp.Status,
 		statusCode: resp.StatusCode,
 	}
+	// an external id matches more than one record, the body lists their URLs
+	if resp.StatusCode == http.StatusMultipleChoices {
+		var urls []string
+		if err = json.NewDecoder(resp.Body).Decode(&urls); err != nil {
+			return
+		}
+		errResp.errors = []*sfErr{{
+			ErrorCode: "MULTIPLE_CHOICES",
+			Message:   "more than one record found: " + strings.Join(urls, ", "),
+		}}
+		return errResp
+	}
 	if err = json.NewDecoder(resp.Body).Decode(&errResp.errors); err != nil {
 		return
 	}
@@ -49,3 +63,49 @@ func parseErrResponse(resp *http.Response) (err error) {
 	err = errResp
 	return
 }
+
+// APIUsage is the api usage salesforce reports in the Sforce-Limit-Info
+// header of each response, like "api-usage=25/15000".
+type APIUsage struct {
+	Used  int
+	Limit int
+}
+
+func (u APIUsage) String() string {
+	return fmt.Sprintf("%d/%d", u.Used, u.Limit)
+}
+
+// ParseAPIUsage parses the api usage from response header h.
+func ParseAPIUsage(h http.Header) (usage APIUsage, ok bool) {
+	for _, item := range strings.Split(h.Get("Sforce-Limit-Info"), ",") {
+		value := strings.TrimPrefix(strings.TrimSpace(item), "api-usage=")
+		if value == strings.TrimSpace(item) {
+			continue
+		}
+
+		parts := strings.SplitN(value, "/", 2)
+		if len(parts) != 2 {
+			return
+		}
+		used, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return
+		}
+		limit, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return
+		}
+		return APIUsage{Used: used, Limit: limit}, true
+	}
+	return
+}
+
+// requestID returns the id salesforce assigned to the request if any.
+func requestID(h http.Header) string {
+	for _, key := range []string{"X-Request-Id", "Sforce-Request-Id"} {
+		if id := h.Get(key); id != "" {
+			return id
+		}
+	}
+	return ""
+}
@@ -0,0 +1,443 @@
+package gosf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+/************************************/
+/************ SOSL SEARCH ***********/
+/************************************/
+
+// SearchGroup is the scope of fields a search looks in.
+type SearchGroup string
+
+// Search groups of the IN clause, ALL FIELDS is the default of salesforce.
+const (
+	SearchAllFields     SearchGroup = "ALL FIELDS"
+	SearchNameFields    SearchGroup = "NAME FIELDS"
+	SearchEmailFields   SearchGroup = "EMAIL FIELDS"
+	SearchPhoneFields   SearchGroup = "PHONE FIELDS"
+	SearchSidebarFields SearchGroup = "SIDEBAR FIELDS"
+)
+
+// soslEscaper escapes the reserved characters of SOSL search terms.
+var soslEscaper = strings.NewReplacer(
+	`\`, `\\`, `?`, `\?`, `&`, `\&`, `|`, `\|`, `!`, `\!`, `{`, `\{`, `}`, `\}`,
+	`[`, `\[`, `]`, `\]`, `(`, `\(`, `)`, `\)`, `^`, `\^`, `~`, `\~`, `*`, `\*`,
+	`:`, `\:`, `"`, `\"`, `'`, `\'`, `+`, `\+`, `-`, `\-`,
+)
+
+// EscapeSOSL escapes the reserved characters of SOSL in s, so s is searched
+// as it is, wildcards * and ? included.
+func EscapeSOSL(s string) string {
+	return soslEscaper.Replace(s)
+}
+
+type (
+	// OpSearch is a request for searching sobjects by SOSL, like:
+	//
+	//	op := gosf.NewOpSearch("Acme").
+	//		In(gosf.SearchNameFields).
+	//		Returning(
+	//			gosf.NewOpQuery("Account").Select("Id", "Name").Where("Rating", "Hot").Limit(5),
+	//			gosf.NewOpQuery("Contact").Select("Id", "Email"),
+	//		).
+	//		Limit(20)
+	//	// FIND {Acme} IN NAME FIELDS RETURNING Account(Id,Name WHERE Rating='Hot' LIMIT 5),Contact(Id,Email) LIMIT 20
+	//
+	// Each sobject of RETURNING is an OpQuery, only its fields, Where,
+	// Order, Limit and Offset are used. An OpQuery without fields returns
+	// the ids only. See SearchResult for the result.
+	OpSearch struct {
+		term      string
+		expr      bool
+		group     SearchGroup
+		returning []*OpQuery
+		limit     int
+		result    *SearchResult
+	}
+
+	// SearchResult is the result of a search. Records are in the order of
+	// relevance, Groups are the same records grouped by sobject type.
+	SearchResult struct {
+		Records []interface{}
+		Groups  map[string][]interface{}
+	}
+)
+
+// NewOpSearch returns an OpSearch finds term, the reserved characters of
+// term are escaped.
+func NewOpSearch(term string) *OpSearch {
+	return &OpSearch{term: term}
+}
+
+// NewOpSearchExpr returns an OpSearch finds the search expression expr,
+// which is kept as it is, so wildcards, quoted phrases and AND, OR and AND
+// NOT operators can be used. Escape user input in it by EscapeSOSL.
+func NewOpSearchExpr(expr string) *OpSearch {
+	return &OpSearch{term: expr, expr: true}
+}
+
+// In defines the fields to search in.
+func (op *OpSearch) In(group SearchGroup) *OpSearch {
+	op.group = group
+	return op
+}
+
+// Returning defines the sobjects and their fields returned.
+func (op *OpSearch) Returning(sobjects ...*OpQuery) *OpSearch {
+	op.returning = append(op.returning, sobjects...)
+	return op
+}
+
+// Limit defines the max number of records returned of all sobjects.
+func (op *OpSearch) Limit(n int) *OpSearch {
+	op.limit = n
+	return op
+}
+
+// check returns the error of op before making request.
+func (op *OpSearch) check() error {
+	if strings.TrimSpace(op.term) == "" {
+		return errors.New("missing search term")
+	}
+	for _, sobject := range op.returning {
+		switch {
+		case sobject.err != nil:
+			return sobject.err
+		case sobject.sobjectName == "":
+			return errors.New("missing Sobject name of returning")
+		case len(sobject.subqueries) > 0 || len(sobject.typeOfs) > 0 || len(sobject.groupBy) > 0:
+			return fmt.Errorf("returning %s can only select fields", sobject.sobjectName)
+		}
+	}
+	return nil
+}
+
+// Make request by given request context.
+func (op *OpSearch) Make(ctx *RequestCtx) (*Request, error) {
+	if err := op.check(); err != nil {
+		return nil, err
+	}
+	return NewRequest(http.MethodGet, ctx.SearchURL(op.makeSearchStatment(ctx.Logger())), nil), nil
+}
+
+// Operation describes the search operation.
+func (op *OpSearch) Operation() Operation {
+	return Operation{Name: "search"}
+}
+
+// Handle success response from salesforce.
+func (op *OpSearch) Handle(resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("search operator can't handle response with code %d, expect %d", resp.StatusCode, http.StatusOK)
+	}
+	return json.NewDecoder(resp.Body).Decode(&op.result)
+}
+
+// makeSearchStatment renders statment as below:
+// FIND {<TERM>} [IN <GROUP>] [RETURNING <SOBJECT1>[(<FIELDS> [WHERE ...] [ORDER BY ...] [LIMIT n] [OFFSET n])] [,<SOBJECT2>...]] [LIMIT n]
+func (op *OpSearch) makeSearchStatment(logger Logger) string {
+	term := op.term
+	if !op.expr {
+		term = EscapeSOSL(term)
+	}
+	statments := []string{fmt.Sprintf("FIND {%s}", term)}
+	if op.group != "" {
+		statments = append(statments, fmt.Sprintf("IN %s", op.group))
+	}
+	if len(op.returning) > 0 {
+		items := make([]string, 0, len(op.returning))
+		for _, sobject := range op.returning {
+			items = append(items, sobject.makeReturningStatment(logger))
+		}
+		statments = append(statments, fmt.Sprintf("RETURNING %s", strings.Join(items, ",")))
+	}
+	if op.limit > 0 {
+		statments = append(statments, fmt.Sprintf("LIMIT %d", op.limit))
+	}
+	return strings.Join(statments, " ")
+}
+
+// makeReturningStatment renders op as a sobject of RETURNING:
+// <SOBJECT>[(<FIELD1> [,<FIELD2>]... [WHERE ...] [ORDER BY ...] [LIMIT n] [OFFSET n])]
+func (op *OpQuery) makeReturningStatment(logger Logger) string {
+	if len(op.selectFileds) == 0 {
+		return op.sobjectName
+	}
+	statments := []string{strings.Join(op.selectFileds, ",")}
+	for _, statment := range []string{
+		op.makeWhereCluasesStatment(logger),
+		op.makeOrderStatment(),
+		op.makeLimitStatment(),
+		op.makeOffsetStatment(),
+	} {
+		if statment != "" {
+			statments = append(statments, statment)
+		}
+	}
+	return fmt.Sprintf("%s(%s)", op.sobjectName, strings.Join(statments, " "))
+}
+
+// UnmarshalJSON accepts both the searchRecords object responded since api
+// v37.0 and the array of records responded before.
+func (r *SearchResult) UnmarshalJSON(data []byte) error {
+	var records []interface{}
+	if firstByte(data) == '[' {
+		if err := json.Unmarshal(data, &records); err != nil {
+			return err
+		}
+	} else {
+		var body struct {
+			SearchRecords []interface{} `json:"searchRecords"`
+		}
+		if err := json.Unmarshal(data, &body); err != nil {
+			return err
+		}
+		records = body.SearchRecords
+	}
+
+	r.Records = records
+	r.Groups = make(map[string][]interface{})
+	for _, record := range records {
+		sobjectName := recordType(record)
+		r.Groups[sobjectName] = append(r.Groups[sobjectName], record)
+	}
+	return nil
+}
+
+// Sobjects returns the sobject types found, in the order of their first
+// records.
+func (r *SearchResult) Sobjects() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, record := range r.Records {
+		if name := recordType(record); !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Parse parses the records of sobjectName into targets, which should be a
+// pointer to a slice. Records are decoded like QueryResult.Parse does.
+func (r *SearchResult) Parse(sobjectName string, targets interface{}) error {
+	records := r.Groups[sobjectName]
+	if records == nil {
+		records = []interface{}{}
+	}
+	byts, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	return decodeRecord(byts, targets, nil)
+}
+
+// recordType returns attributes.type of record, if any.
+func recordType(record interface{}) string {
+	fields, _ := record.(map[string]interface{})
+	attributes, _ := fields["attributes"].(map[string]interface{})
+	name, _ := attributes["type"].(string)
+	return name
+}
+
+/************************************/
+/******** PARAMETERIZED SEARCH ******/
+/************************************/
+
+type (
+	opParameterizedSearch struct {
+		search *OpSearch
+		result *SearchResult
+	}
+
+	parameterizedSearch struct {
+		Q            string                 `json:"q"`
+		In           string                 `json:"in,omitempty"`
+		Sobjects     []parameterizedSobject `json:"sobjects,omitempty"`
+		OverallLimit int                    `json:"overallLimit,omitempty"`
+	}
+
+	parameterizedSobject struct {
+		Name    string   `json:"name"`
+		Fields  []string `json:"fields,omitempty"`
+		Where   string   `json:"where,omitempty"`
+		OrderBy string   `json:"orderBy,omitempty"`
+		Limit   int      `json:"limit,omitempty"`
+	}
+)
+
+func (op *opParameterizedSearch) Make(ctx *RequestCtx) (*Request, error) {
+	if op.search == nil {
+		return nil, errors.New("missing search")
+	}
+	if err := op.search.check(); err != nil {
+		return nil, err
+	}
+
+	// the term of parameterized search needs no escaping
+	body := &parameterizedSearch{
+		Q:            op.search.term,
+		OverallLimit: op.search.limit,
+	}
+	if op.search.group != "" {
+		body.In = strings.TrimSuffix(string(op.search.group), " FIELDS")
+	}
+	for _, sobject := range op.search.returning {
+		if sobject.offset > 0 {
+			return nil, fmt.Errorf("returning %s of parameterized search can't have offset", sobject.sobjectName)
+		}
+		body.Sobjects = append(body.Sobjects, parameterizedSobject{
+			Name:    sobject.sobjectName,
+			Fields:  sobject.selectFileds,
+			Where:   strings.TrimPrefix(sobject.makeWhereCluasesStatment(ctx.Logger()), "WHERE "),
+			OrderBy: strings.TrimPrefix(sobject.makeOrderStatment(), "ORDER BY "),
+			Limit:   sobject.limit,
+		})
+	}
+	return NewRequest(http.MethodPost, ctx.ParameterizedSearchURL(), body), nil
+}
+
+func (op *opParameterizedSearch) Operation() Operation {
+	return Operation{Name: "parameterizedSearch"}
+}
+
+func (op *opParameterizedSearch) Handle(resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("parameterized search operator can't handle response with code %d, expect %d", resp.StatusCode, http.StatusOK)
+	}
+	return json.NewDecoder(resp.Body).Decode(&op.result)
+}
+
+/************************************/
+/******** SEARCH SUGGESTIONS ********/
+/************************************/
+
+type (
+	// SuggestionResult is the records suggested for a search term.
+	SuggestionResult struct {
+		Records        []interface{} `json:"autoSuggestResults"`
+		HasMoreResults bool          `json:"hasMoreResults"`
+	}
+
+	// SearchScope is a sobject in the search scope of the user.
+	SearchScope struct {
+		Type string `json:"type"`
+		URL  string `json:"url"`
+	}
+
+	opSearchSuggestions struct {
+		term        string
+		sobjectName string
+		limit       int
+		result      *SuggestionResult
+	}
+
+	opSearchScopeOrder struct {
+		result []*SearchScope
+	}
+)
+
+// Parse parses the records into targets, which should be a pointer to a
+// slice. Records are decoded like QueryResult.Parse does.
+func (r *SuggestionResult) Parse(targets interface{}) error {
+	byts, err := json.Marshal(r.Records)
+	if err != nil {
+		return err
+	}
+	return decodeRecord(byts, targets, nil)
+}
+
+func (op *opSearchSuggestions) Make(ctx *RequestCtx) (*Request, error) {
+	switch {
+	case strings.TrimSpace(op.term) == "":
+		return nil, errors.New("missing search term")
+	case op.sobjectName == "":
+		return nil, errors.New("missing Sobject name")
+	}
+	return NewRequest(http.MethodGet, ctx.SearchSuggestionsURL(op.term, op.sobjectName, op.limit), nil), nil
+}
+
+func (op *opSearchSuggestions) Operation() Operation {
+	return Operation{Name: "searchSuggestions", Sobject: op.sobjectName}
+}
+
+func (op *opSearchSuggestions) Handle(resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("search suggestions operator can't handle response with code %d, expect %d", resp.StatusCode, http.StatusOK)
+	}
+	return json.NewDecoder(resp.Body).Decode(&op.result)
+}
+
+func (op *opSearchScopeOrder) Make(ctx *RequestCtx) (*Request, error) {
+	return NewRequest(http.MethodGet, ctx.SearchScopeOrderURL(), nil), nil
+}
+
+func (op *opSearchScopeOrder) Operation() Operation {
+	return Operation{Name: "searchScopeOrder"}
+}
+
+func (op *opSearchScopeOrder) Handle(resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("search scope operator can't handle response with code %d, expect %d", resp.StatusCode, http.StatusOK)
+	}
+	return json.NewDecoder(resp.Body).Decode(&op.result)
+}
+
+/************************************/
+/************ CLIENT API ************/
+/************************************/
+
+// Search searches sobjects by the SOSL statment of op.
+// See also OpSearch.
+func (c *Client) Search(op *OpSearch) (result *SearchResult, err error) {
+	if err = c.do(op); err != nil {
+		return
+	}
+	result = op.result
+	return
+}
+
+// ParameterizedSearch searches by op through the parameterizedSearch
+// resource, which takes the search in json instead of SOSL. The term of op
+// is sent without escaping, returning sobjects can't have Offset.
+func (c *Client) ParameterizedSearch(op *OpSearch) (result *SearchResult, err error) {
+	search := &opParameterizedSearch{search: op}
+	if err = c.do(search); err != nil {
+		return
+	}
+	result = search.result
+	return
+}
+
+// SearchSuggestions returns the records of sobjectName whose names match
+// term, like the auto-complete of salesforce's search box. limit<=0 means
+// the default of salesforce.
+func (c *Client) SearchSuggestions(term, sobjectName string, limit int) (result *SuggestionResult, err error) {
+	op := &opSearchSuggestions{
+		term:        term,
+		sobjectName: sobjectName,
+		limit:       limit,
+	}
+	if err = c.do(op); err != nil {
+		return
+	}
+	result = op.result
+	return
+}
+
+// SearchScopeOrder returns the sobjects searched for the user, in the
+// order of the search results page.
+func (c *Client) SearchScopeOrder() (scopes []*SearchScope, err error) {
+	op := &opSearchScopeOrder{}
+	if err = c.do(op); err != nil {
+		return
+	}
+	scopes = op.result
+	return
+}
@@ -0,0 +1,100 @@
+package gosf
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEscapeSOSL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Acme", "Acme"},
+		{`\`, `\\`},
+		{"?", `\?`},
+		{"&", `\&`},
+		{"|", `\|`},
+		{"!", `\!`},
+		{"{", `\{`},
+		{"}", `\}`},
+		{"[", `\[`},
+		{"]", `\]`},
+		{"(", `\(`},
+		{")", `\)`},
+		{"^", `\^`},
+		{"~", `\~`},
+		{"*", `\*`},
+		{":", `\:`},
+		{`"`, `\"`},
+		{"'", `\'`},
+		{"+", `\+`},
+		{"-", `\-`},
+		{`a\*`, `a\\\*`},
+		{"Acme} RETURNING User(Password", `Acme\} RETURNING User\(Password`},
+		{"O'Brien & Sons", `O\'Brien \& Sons`},
+	}
+	for _, tt := range tests {
+		if got := EscapeSOSL(tt.in); got != tt.want {
+			t.Errorf("EscapeSOSL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMakeSearchStatment(t *testing.T) {
+	tests := []struct {
+		name string
+		op   *OpSearch
+		want string
+	}{
+		{
+			"term escaped",
+			NewOpSearch("Acme*} OR {x"),
+			`FIND {Acme\*\} OR \{x}`,
+		},
+		{
+			"expression kept",
+			NewOpSearchExpr(`Acme* AND "big co"`),
+			`FIND {Acme* AND "big co"}`,
+		},
+		{
+			"group, returning and limit",
+			NewOpSearch("Acme").In(SearchNameFields).Returning(
+				NewOpQuery("Account").Select("Id", "Name").Where("Rating", "Hot").OrderDesc("Name").Limit(5).Offset(10),
+				NewOpQuery("Contact"),
+			).Limit(20),
+			"FIND {Acme} IN NAME FIELDS RETURNING Account(Id,Name WHERE Rating='Hot' ORDER BY Name DESC LIMIT 5 OFFSET 10),Contact LIMIT 20",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.op.makeSearchStatment(&recordLogger{}); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMakeParameterizedSearch(t *testing.T) {
+	op := &opParameterizedSearch{search: NewOpSearch("Acme* (Co)").In(SearchPhoneFields).Returning(
+		NewOpQuery("Account").Select("Id", "Name").Where("Industry", "Energy").OrderAsc("Name").Limit(3),
+		NewOpQuery("Lead"),
+	).Limit(10)}
+	req, err := op.Make(&RequestCtx{host: "https://example.my.salesforce.com", version: 66})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(req.data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"q":"Acme* (Co)","in":"PHONE","sobjects":[{"name":"Account","fields":["Id","Name"],"where":"Industry='Energy'","orderBy":"Name ASC","limit":3},{"name":"Lead"}],"overallLimit":10}`
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	op = &opParameterizedSearch{search: NewOpSearch("Acme").Returning(NewOpQuery("Account").Select("Id").Offset(5))}
+	if _, err = op.Make(&RequestCtx{}); err == nil {
+		t.Error("got no error of a returning with offset")
+	}
+}
@@ -0,0 +1,214 @@
+package gosf
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sidebiequ/gosf/soql"
+)
+
+/************************************/
+/************ RAW SOQL **************/
+/************************************/
+
+// ParseQuery parses a raw SOQL statement into an OpQuery, so queries kept
+// in config files can be checked before running and extended by the
+// builder methods. Syntax errors are *soql.Error.
+func ParseQuery(q string) (*OpQuery, error) {
+	parsed, err := soql.Parse(q)
+	if err != nil {
+		return nil, err
+	}
+	return opQueryOf(parsed)
+}
+
+// WhereExpr appends a condition expressed by the soql package, for the
+// conditions Where can't express, like OR, LIKE and IN:
+//
+//	expr, _ := soql.ParseExpr("Name LIKE 'A%' OR Rating = 'Hot'")
+//	op.WhereExpr(expr)
+func (op *OpQuery) WhereExpr(expr soql.Expr) *OpQuery {
+	if expr != nil {
+		op.whereExprs = append(op.whereExprs, expr)
+	}
+	return op
+}
+
+// Validate checks the sobjects and fields op refers to against schema,
+// see soql.Validate.
+func (op *OpQuery) Validate(schema soql.Schema) error {
+	if op.err != nil {
+		return op.err
+	}
+	parsed, err := soql.Parse(op.makeQueryStatment(discardLogger{}))
+	if err != nil {
+		return err
+	}
+	return soql.Validate(parsed, schema)
+}
+
+// formatExpr renders expr as an operand of AND.
+func formatExpr(expr soql.Expr) string {
+	switch expr.(type) {
+	case *soql.And, *soql.Or:
+		return "(" + expr.String() + ")"
+	default:
+		return expr.String()
+	}
+}
+
+// opQueryOf converts a parsed query to OpQuery.
+func opQueryOf(q *soql.Query) (*OpQuery, error) {
+	op := NewOpQuery(q.From)
+	for _, item := range q.Select {
+		switch item := item.(type) {
+		case *soql.Field:
+			op.Select(item.Name())
+		case *soql.Aggregate:
+			if item.Field == nil {
+				op.SelectCount()
+			} else {
+				op.SelectAggregate(AggregateFunction(item.Func), item.Field.Name(), item.Alias)
+			}
+		case *soql.TypeOf:
+			typeOf := NewTypeOf(item.Field.Name())
+			for _, when := range item.Whens {
+				typeOf.When(when.Sobject, fieldNames(when.Fields)...)
+			}
+			if len(item.Else) > 0 {
+				typeOf.Else(fieldNames(item.Else)...)
+			}
+			op.SelectTypeOf(typeOf)
+		case *soql.Subquery:
+			sub, err := opQueryOf(item.Query)
+			if err != nil {
+				return nil, err
+			}
+			op.SelectSubquery(sub)
+		default:
+			return nil, fmt.Errorf("unsupported select item %s", item)
+		}
+	}
+
+	op.UsingScope(q.Scope)
+	op.whereClauses, op.whereExprs = splitConditions(q.Where)
+	op.mode = q.With
+
+	switch q.GroupByFunc {
+	case "ROLLUP":
+		op.GroupByRollup(fieldNames(q.GroupBy)...)
+	case "CUBE":
+		op.GroupByCube(fieldNames(q.GroupBy)...)
+	default:
+		op.groupBy = fieldNames(q.GroupBy)
+	}
+	op.having, op.havingExprs = splitConditions(q.Having)
+
+	for _, item := range q.OrderBy {
+		direction := Ascending
+		if item.Desc {
+			direction = Descending
+		}
+		nulls := NullsDefault
+		if item.Nulls != "" {
+			nulls = Nulls("NULLS " + item.Nulls)
+		}
+		op.OrderBy(item.Field.Name(), direction, nulls)
+	}
+
+	if q.Limit != nil {
+		if *q.Limit == 0 {
+			return nil, errors.New("LIMIT 0 is not supported by OpQuery")
+		}
+		op.Limit(*q.Limit)
+	}
+	if q.Offset != nil {
+		op.Offset(*q.Offset)
+	}
+	if q.For != "" {
+		op.lock = "FOR " + q.For
+	}
+	return op, op.err
+}
+
+func fieldNames(fields []*soql.Field) []string {
+	if len(fields) == 0 {
+		return nil
+	}
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.Name()
+	}
+	return names
+}
+
+// splitConditions splits the conditions joined by AND into the ones
+// whereClause can express and the others.
+func splitConditions(expr soql.Expr) (clauses []whereClause, exprs []soql.Expr) {
+	if expr == nil {
+		return
+	}
+	conditions := []soql.Expr{expr}
+	if and, ok := expr.(*soql.And); ok {
+		conditions = and.Exprs
+	}
+	for _, condition := range conditions {
+		if clause, ok := clauseOf(condition); ok {
+			clauses = append(clauses, clause)
+		} else {
+			exprs = append(exprs, condition)
+		}
+	}
+	return
+}
+
+// clauseOf converts a comparison to whereClause if it's simple enough.
+func clauseOf(expr soql.Expr) (clause whereClause, ok bool) {
+	c, isComparison := expr.(*soql.Comparison)
+	if !isComparison || !comparisonOperators[c.Op] {
+		return
+	}
+	clause = whereClause{operator: c.Op}
+	if c.Aggregate != nil {
+		clause.field = c.Aggregate.Expr()
+	} else {
+		clause.field = c.Field.Name()
+	}
+
+	text := c.Value.Text
+	switch c.Value.Kind {
+	case soql.String:
+		// escaped % and _ of LIKE patterns can't be kept by formatCondition
+		if strings.Contains(text, `\`) {
+			return clause, false
+		}
+		clause.condition = text
+	case soql.Number:
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return clause, false
+		}
+		clause.condition = n
+	case soql.Boolean:
+		clause.condition = text == "true"
+	case soql.Date:
+		d, err := ParseDate(text)
+		if err != nil {
+			return clause, false
+		}
+		clause.condition = d
+	case soql.DateTime:
+		dt, err := ParseDateTime(text)
+		if err != nil {
+			return clause, false
+		}
+		clause.condition = dt
+	case soql.DateLiteral:
+		clause.condition = DateLiteral(text)
+	default:
+		return clause, false
+	}
+	return clause, true
+}
@@ -0,0 +1,216 @@
+// Package soql parses SOQL statements into an abstract syntax tree, prints
+// the tree back to SOQL and validates the referenced sobjects and fields
+// against a Schema:
+//
+//	q, err := soql.Parse("SELECT Id, Owner.Name FROM Account WHERE Name LIKE 'A%'")
+//	if err != nil {
+//		// syntax error
+//	}
+//	err = soql.Validate(q, schema)
+//	fmt.Println(q) // SELECT Id, Owner.Name FROM Account WHERE Name LIKE 'A%'
+package soql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Error is a syntax error at rune offset Pos of the query.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("soql: %s at position %d", e.Msg, e.Pos)
+}
+
+// Query is a parsed SELECT statement.
+type Query struct {
+	Select []SelectItem
+	From   string
+	// Scope is the filter scope of USING SCOPE.
+	Scope string
+	Where Expr
+	// With is SECURITY_ENFORCED, USER_MODE or SYSTEM_MODE.
+	With    string
+	GroupBy []*Field
+	// GroupByFunc is "ROLLUP", "CUBE" or "" for plain GROUP BY.
+	GroupByFunc string
+	Having      Expr
+	OrderBy     []OrderItem
+	Limit       *int
+	Offset      *int
+	// For is VIEW, REFERENCE or UPDATE.
+	For string
+}
+
+// SelectItem is an item of the SELECT list.
+type SelectItem interface {
+	fmt.Stringer
+	selectItem()
+}
+
+// Field is a field path like Name or Account.Owner.Name.
+type Field struct {
+	Path []string
+}
+
+func (*Field) selectItem() {}
+
+// Aggregate is an aggregate function like COUNT(), SUM(Amount) or
+// MAX(CreatedDate) latest. Field is nil for COUNT().
+type Aggregate struct {
+	Func  string
+	Field *Field
+	Alias string
+}
+
+func (*Aggregate) selectItem() {}
+
+// Expr returns the aggregate without alias, like SUM(Amount).
+func (a *Aggregate) Expr() string {
+	if a.Field == nil {
+		return a.Func + "()"
+	}
+	return a.Func + "(" + a.Field.Name() + ")"
+}
+
+// TypeOf selects fields of the polymorphic relationship Field by the type
+// of the referenced sobject. Else is used if no When matches.
+type TypeOf struct {
+	Field *Field
+	Whens []TypeOfWhen
+	Else  []*Field
+}
+
+// TypeOfWhen is a WHEN clause of TypeOf.
+type TypeOfWhen struct {
+	Sobject string
+	Fields  []*Field
+}
+
+func (*TypeOf) selectItem() {}
+
+// Subquery is a child relationship query like (SELECT Id FROM Contacts),
+// the From of Query is the relationship name.
+type Subquery struct {
+	Query *Query
+}
+
+func (*Subquery) selectItem() {}
+
+// Name returns the dotted path of the field.
+func (f *Field) Name() string {
+	return strings.Join(f.Path, ".")
+}
+
+// Expr is a condition of a WHERE or HAVING clause.
+type Expr interface {
+	fmt.Stringer
+	expr()
+}
+
+type (
+	// And is a conjunction of conditions.
+	And struct {
+		Exprs []Expr
+	}
+
+	// Or is a disjunction of conditions.
+	Or struct {
+		Exprs []Expr
+	}
+
+	// Not negates a condition.
+	Not struct {
+		Expr Expr
+	}
+
+	// Comparison compares a field, or an aggregate in HAVING, with a value
+	// by Op, which is one of = != < <= > >= LIKE IN and NOT IN. For IN and
+	// NOT IN, Values holds the list, Value is used otherwise.
+	Comparison struct {
+		Field     *Field
+		Aggregate *Aggregate
+		Op        string
+		Value     Literal
+		Values    []Literal
+	}
+)
+
+func (*And) expr()        {}
+func (*Or) expr()         {}
+func (*Not) expr()        {}
+func (*Comparison) expr() {}
+
+// LiteralKind is the kind of a Literal.
+type LiteralKind int
+
+// Kinds of literals.
+const (
+	String LiteralKind = iota
+	Number
+	Boolean
+	Null
+	Date
+	DateTime
+	DateLiteral // TODAY, LAST_N_DAYS:30...
+)
+
+// Literal is a value in a condition. Text holds the unquoted string, the
+// number, "true"/"false", the date or the date literal as written.
+type Literal struct {
+	Kind LiteralKind
+	Text string
+}
+
+// dateLiterals are the date literals without a number.
+var dateLiterals = map[string]bool{
+	"YESTERDAY": true, "TODAY": true, "TOMORROW": true,
+	"LAST_WEEK": true, "THIS_WEEK": true, "NEXT_WEEK": true,
+	"LAST_MONTH": true, "THIS_MONTH": true, "NEXT_MONTH": true,
+	"LAST_90_DAYS": true, "NEXT_90_DAYS": true,
+	"LAST_QUARTER": true, "THIS_QUARTER": true, "NEXT_QUARTER": true,
+	"LAST_YEAR": true, "THIS_YEAR": true, "NEXT_YEAR": true,
+	"LAST_FISCAL_QUARTER": true, "THIS_FISCAL_QUARTER": true, "NEXT_FISCAL_QUARTER": true,
+	"LAST_FISCAL_YEAR": true, "THIS_FISCAL_YEAR": true, "NEXT_FISCAL_YEAR": true,
+}
+
+// nDateLiterals are the date literals taking a number, like LAST_N_DAYS:n.
+var nDateLiterals = map[string]bool{
+	"LAST_N_DAYS": true, "NEXT_N_DAYS": true, "N_DAYS_AGO": true,
+	"LAST_N_WEEKS": true, "NEXT_N_WEEKS": true, "N_WEEKS_AGO": true,
+	"LAST_N_MONTHS": true, "NEXT_N_MONTHS": true, "N_MONTHS_AGO": true,
+	"LAST_N_QUARTERS": true, "NEXT_N_QUARTERS": true, "N_QUARTERS_AGO": true,
+	"LAST_N_YEARS": true, "NEXT_N_YEARS": true, "N_YEARS_AGO": true,
+	"LAST_N_FISCAL_QUARTERS": true, "NEXT_N_FISCAL_QUARTERS": true, "N_FISCAL_QUARTERS_AGO": true,
+	"LAST_N_FISCAL_YEARS": true, "NEXT_N_FISCAL_YEARS": true, "N_FISCAL_YEARS_AGO": true,
+}
+
+// IsDateLiteral reports whether text is a date literal of salesforce, like
+// TODAY or LAST_N_DAYS:30. Case is ignored, n must be an integer.
+func IsDateLiteral(text string) bool {
+	name, n, found := strings.Cut(strings.ToUpper(text), ":")
+	if !found {
+		return dateLiterals[name]
+	}
+	return nDateLiterals[name] && isDigits(n)
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// OrderItem is an item of the ORDER BY list.
+type OrderItem struct {
+	Field *Field
+	Desc  bool
+	// Nulls is "FIRST", "LAST" or "" for the default.
+	Nulls string
+}
@@ -0,0 +1,174 @@
+package soql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tokenKind is the kind of a lexical token.
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokDate
+	tokDateTime
+	tokOperator // = != <> < <= > >=
+	tokPunct    // ( ) , . :
+)
+
+type token struct {
+	kind tokenKind
+	text string // unquoted for strings
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return fmt.Sprintf("'%s'", t.text)
+	default:
+		return t.text
+	}
+}
+
+// is reports whether t is the keyword or punctuation s, case insensitively.
+func (t token) is(s string) bool {
+	return (t.kind == tokIdent || t.kind == tokPunct || t.kind == tokOperator) && strings.EqualFold(t.text, s)
+}
+
+// lex splits the query into tokens.
+func lex(q string) ([]token, error) {
+	var tokens []token
+	runes := []rune(q)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '\'':
+			text, n, err := lexString(runes[i:])
+			if err != nil {
+				return nil, &Error{Pos: i, Msg: err.Error()}
+			}
+			tokens = append(tokens, token{kind: tokString, text: text, pos: i})
+			i += n
+
+		case unicode.IsDigit(r) || (r == '-' || r == '+') && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			kind, n := lexNumberOrDate(runes[i:])
+			tokens = append(tokens, token{kind: kind, text: string(runes[i : i+n]), pos: i})
+			i += n
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: string(runes[start:i]), pos: start})
+
+		case r == '!' || r == '<' || r == '>' || r == '=':
+			op := string(r)
+			if i+1 < len(runes) {
+				if two := string(runes[i : i+2]); two == "!=" || two == "<>" || two == "<=" || two == ">=" {
+					op = two
+				}
+			}
+			if op == "!" {
+				return nil, &Error{Pos: i, Msg: "unexpected character '!'"}
+			}
+			tokens = append(tokens, token{kind: tokOperator, text: op, pos: i})
+			i += len(op)
+
+		case strings.ContainsRune("(),.:", r):
+			tokens = append(tokens, token{kind: tokPunct, text: string(r), pos: i})
+			i++
+
+		default:
+			return nil, &Error{Pos: i, Msg: fmt.Sprintf("unexpected character '%c'", r)}
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(runes)}), nil
+}
+
+// lexString reads a single quoted string, returns the unescaped text and
+// the number of runes consumed.
+func lexString(runes []rune) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(runes); i++ {
+		switch runes[i] {
+		case '\'':
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 >= len(runes) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			i++
+			switch runes[i] {
+			case 'n':
+				b.WriteRune('\n')
+			case 'r':
+				b.WriteRune('\r')
+			case 't':
+				b.WriteRune('\t')
+			case 'b':
+				b.WriteRune('\b')
+			case 'f':
+				b.WriteRune('\f')
+			case '\'', '"', '\\', '%', '_':
+				if runes[i] == '%' || runes[i] == '_' {
+					// kept escaped for LIKE patterns
+					b.WriteRune('\\')
+				}
+				b.WriteRune(runes[i])
+			default:
+				return "", 0, fmt.Errorf("invalid escape sequence '\\%c'", runes[i])
+			}
+		default:
+			b.WriteRune(runes[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// lexNumberOrDate reads a number, a date (2006-01-02) or a datetime
+// (2006-01-02T15:04:05Z, 2006-01-02T15:04:05.000+07:00).
+func lexNumberOrDate(runes []rune) (tokenKind, int) {
+	digits := func(from, n int) bool {
+		if from+n > len(runes) {
+			return false
+		}
+		for _, r := range runes[from : from+n] {
+			if !unicode.IsDigit(r) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if digits(0, 4) && len(runes) > 4 && runes[4] == '-' && digits(5, 2) && len(runes) > 7 && runes[7] == '-' && digits(8, 2) {
+		i := 10
+		if i >= len(runes) || runes[i] != 'T' {
+			return tokDate, i
+		}
+		i++
+		for i < len(runes) && (unicode.IsDigit(runes[i]) || strings.ContainsRune(":.+-Z", runes[i])) {
+			i++
+		}
+		return tokDateTime, i
+	}
+
+	i := 0
+	if runes[0] == '-' || runes[0] == '+' {
+		i++
+	}
+	for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+		i++
+	}
+	return tokNumber, i
+}
@@ -0,0 +1,531 @@
+package soql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parse parses a SOQL SELECT statement.
+func Parse(q string) (*Query, error) {
+	tokens, err := lex(q)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	query, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, p.errorf("unexpected token: %s", p.peek())
+	}
+	return query, nil
+}
+
+// ParseExpr parses a condition of WHERE or HAVING clause, like
+// Name LIKE 'A%' OR Rating = 'Hot'.
+func ParseExpr(s string) (Expr, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, p.errorf("unexpected token: %s", p.peek())
+	}
+	return expr, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	// subquery is true while parsing a subquery, which can't be nested.
+	subquery bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// peekAt returns the token n after the next one.
+func (p *parser) peekAt(n int) token {
+	if p.pos+n >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+n]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is s.
+func (p *parser) accept(s string) bool {
+	if p.peek().is(s) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.accept(s) {
+		return p.errorf("expecting '%s', unexpected token: %s", s, p.peek())
+	}
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &Error{Pos: p.peek().pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// keywords can't be used as field names or sobject names.
+var keywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "ORDER": true, "BY": true,
+	"LIMIT": true, "OFFSET": true, "AND": true, "OR": true, "NOT": true,
+	"IN": true, "LIKE": true, "ASC": true, "DESC": true, "NULLS": true,
+	"GROUP": true, "HAVING": true, "USING": true, "WITH": true, "FOR": true,
+	"TYPEOF": true,
+}
+
+// aggregateFuncs are the aggregate functions can be selected.
+var aggregateFuncs = map[string]bool{
+	"COUNT": true, "COUNT_DISTINCT": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true,
+}
+
+func (p *parser) ident() (string, error) {
+	t := p.peek()
+	if t.kind != tokIdent || keywords[strings.ToUpper(t.text)] {
+		return "", p.errorf("unexpected token: %s", t)
+	}
+	p.pos++
+	return t.text, nil
+}
+
+func (p *parser) parseQuery() (q *Query, err error) {
+	q = &Query{}
+	if err = p.expect("SELECT"); err != nil {
+		return
+	}
+	if q.Select, err = p.parseSelect(); err != nil {
+		return
+	}
+	if err = p.expect("FROM"); err != nil {
+		return
+	}
+	if q.From, err = p.ident(); err != nil {
+		return
+	}
+	if p.accept("USING") {
+		if err = p.expect("SCOPE"); err != nil {
+			return
+		}
+		if q.Scope, err = p.ident(); err != nil {
+			return
+		}
+	}
+
+	if p.accept("WHERE") {
+		if q.Where, err = p.parseOr(); err != nil {
+			return
+		}
+	}
+	if p.accept("WITH") {
+		if q.With, err = p.oneOf("SECURITY_ENFORCED", "USER_MODE", "SYSTEM_MODE"); err != nil {
+			return
+		}
+	}
+	if p.accept("GROUP") {
+		if err = p.expect("BY"); err != nil {
+			return
+		}
+		if err = p.parseGroupBy(q); err != nil {
+			return
+		}
+	}
+	if p.accept("HAVING") {
+		if len(q.GroupBy) == 0 {
+			return nil, p.errorf("HAVING without GROUP BY")
+		}
+		if q.Having, err = p.parseOr(); err != nil {
+			return
+		}
+	}
+	if p.accept("ORDER") {
+		if err = p.expect("BY"); err != nil {
+			return
+		}
+		if q.OrderBy, err = p.parseOrderBy(); err != nil {
+			return
+		}
+	}
+	if p.accept("LIMIT") {
+		if q.Limit, err = p.parseInt(); err != nil {
+			return
+		}
+	}
+	if p.accept("OFFSET") {
+		if q.Offset, err = p.parseInt(); err != nil {
+			return
+		}
+	}
+	if p.accept("FOR") {
+		if q.For, err = p.oneOf("VIEW", "REFERENCE", "UPDATE"); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// oneOf consumes the next token if it is one of words, returns it in upper case.
+func (p *parser) oneOf(words ...string) (string, error) {
+	for _, word := range words {
+		if p.accept(word) {
+			return word, nil
+		}
+	}
+	return "", p.errorf("expecting %s, unexpected token: %s", strings.Join(words, " or "), p.peek())
+}
+
+func (p *parser) parseSelect() (items []SelectItem, err error) {
+	for {
+		var item SelectItem
+		switch {
+		case p.peek().is("("):
+			item, err = p.parseSubquery()
+		case p.peek().is("TYPEOF"):
+			item, err = p.parseTypeOf()
+		case p.isAggregate():
+			item, err = p.parseAggregate(true)
+		default:
+			item, err = p.parseField()
+		}
+		if err != nil {
+			return
+		}
+		items = append(items, item)
+		if !p.accept(",") {
+			return
+		}
+	}
+}
+
+// isAggregate reports whether the next tokens are an aggregate function.
+func (p *parser) isAggregate() bool {
+	return p.peek().kind == tokIdent && aggregateFuncs[strings.ToUpper(p.peek().text)] && p.peekAt(1).is("(")
+}
+
+// parseAggregate parses an aggregate function, followed by an alias if
+// withAlias is true.
+func (p *parser) parseAggregate(withAlias bool) (a *Aggregate, err error) {
+	a = &Aggregate{Func: strings.ToUpper(p.next().text)}
+	if err = p.expect("("); err != nil {
+		return
+	}
+	if a.Func == "COUNT" && p.accept(")") {
+		return
+	}
+	if a.Field, err = p.parseField(); err != nil {
+		return
+	}
+	if err = p.expect(")"); err != nil {
+		return
+	}
+	if t := p.peek(); withAlias && t.kind == tokIdent && !keywords[strings.ToUpper(t.text)] {
+		a.Alias = p.next().text
+	}
+	return
+}
+
+func (p *parser) parseGroupBy(q *Query) (err error) {
+	for _, fn := range []string{"ROLLUP", "CUBE"} {
+		if p.peek().is(fn) && p.peekAt(1).is("(") {
+			p.pos += 2
+			q.GroupByFunc = fn
+		}
+	}
+	for {
+		var f *Field
+		if f, err = p.parseField(); err != nil {
+			return
+		}
+		q.GroupBy = append(q.GroupBy, f)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if q.GroupByFunc != "" {
+		return p.expect(")")
+	}
+	return
+}
+
+func (p *parser) parseTypeOf() (t *TypeOf, err error) {
+	p.next()
+	t = &TypeOf{}
+	if t.Field, err = p.parseField(); err != nil {
+		return
+	}
+	for p.accept("WHEN") {
+		when := TypeOfWhen{}
+		if when.Sobject, err = p.ident(); err != nil {
+			return
+		}
+		if err = p.expect("THEN"); err != nil {
+			return
+		}
+		if when.Fields, err = p.parseFields(); err != nil {
+			return
+		}
+		t.Whens = append(t.Whens, when)
+	}
+	if len(t.Whens) == 0 {
+		return nil, p.errorf("expecting WHEN, unexpected token: %s", p.peek())
+	}
+	if p.accept("ELSE") {
+		if t.Else, err = p.parseFields(); err != nil {
+			return
+		}
+	}
+	return t, p.expect("END")
+}
+
+// parseFields parses a comma separated field list.
+func (p *parser) parseFields() (fields []*Field, err error) {
+	for {
+		var f *Field
+		if f, err = p.parseField(); err != nil {
+			return
+		}
+		fields = append(fields, f)
+		if !p.accept(",") {
+			return
+		}
+	}
+}
+
+func (p *parser) parseSubquery() (*Subquery, error) {
+	if p.subquery {
+		return nil, p.errorf("nested subqueries are not supported")
+	}
+	p.next()
+	p.subquery = true
+	defer func() { p.subquery = false }()
+
+	q, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	return &Subquery{Query: q}, p.expect(")")
+}
+
+func (p *parser) parseField() (*Field, error) {
+	f := &Field{}
+	for {
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		f.Path = append(f.Path, name)
+		if !p.accept(".") {
+			return f, nil
+		}
+	}
+}
+
+// parseOr parses conditions joined by OR, or by AND. SOQL doesn't allow
+// mixing them without parentheses.
+func (p *parser) parseOr() (Expr, error) {
+	first, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	var op string
+	exprs := []Expr{first}
+	for p.peek().is("AND") || p.peek().is("OR") {
+		t := p.next()
+		if op != "" && !strings.EqualFold(op, t.text) {
+			return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("unexpected token: %s, use parentheses when mixing AND and OR", t.text)}
+		}
+		op = t.text
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+
+	switch strings.ToUpper(op) {
+	case "AND":
+		return &And{Exprs: exprs}, nil
+	case "OR":
+		return &Or{Exprs: exprs}, nil
+	default:
+		return first, nil
+	}
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	if p.accept("NOT") {
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: expr}, nil
+	}
+	if p.accept("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expect(")")
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	var err error
+	c := &Comparison{}
+	if p.isAggregate() {
+		c.Aggregate, err = p.parseAggregate(false)
+	} else {
+		c.Field, err = p.parseField()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := p.peek(); {
+	case t.kind == tokOperator:
+		p.next()
+		c.Op = t.text
+		if c.Op == "<>" {
+			c.Op = "!="
+		}
+	case t.is("LIKE"):
+		p.next()
+		c.Op = "LIKE"
+	case t.is("IN"):
+		p.next()
+		c.Op = "IN"
+	case t.is("NOT"):
+		p.next()
+		if err = p.expect("IN"); err != nil {
+			return nil, err
+		}
+		c.Op = "NOT IN"
+	default:
+		return nil, p.errorf("expecting an operator, unexpected token: %s", t)
+	}
+
+	if c.Op == "IN" || c.Op == "NOT IN" {
+		if err = p.expect("("); err != nil {
+			return nil, err
+		}
+		for {
+			v, err := p.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			c.Values = append(c.Values, v)
+			if !p.accept(",") {
+				break
+			}
+		}
+		return c, p.expect(")")
+	}
+
+	if c.Value, err = p.parseLiteral(); err != nil {
+		return nil, err
+	}
+	if c.Op == "LIKE" && c.Value.Kind != String {
+		return nil, p.errorf("LIKE expects a string")
+	}
+	return c, nil
+}
+
+func (p *parser) parseLiteral() (Literal, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return Literal{Kind: String, Text: t.text}, nil
+	case tokNumber:
+		if _, err := strconv.ParseFloat(t.text, 64); err != nil {
+			return Literal{}, &Error{Pos: t.pos, Msg: "invalid number " + t.text}
+		}
+		return Literal{Kind: Number, Text: t.text}, nil
+	case tokDate:
+		return Literal{Kind: Date, Text: t.text}, nil
+	case tokDateTime:
+		return Literal{Kind: DateTime, Text: t.text}, nil
+	case tokIdent:
+		switch strings.ToUpper(t.text) {
+		case "TRUE", "FALSE":
+			return Literal{Kind: Boolean, Text: strings.ToLower(t.text)}, nil
+		case "NULL":
+			return Literal{Kind: Null, Text: "null"}, nil
+		}
+		if keywords[strings.ToUpper(t.text)] {
+			break
+		}
+		// date literals like TODAY or LAST_N_DAYS:30
+		text := strings.ToUpper(t.text)
+		if p.accept(":") {
+			n := p.next()
+			if n.kind != tokNumber || !isDigits(n.text) {
+				return Literal{}, &Error{Pos: n.pos, Msg: "expecting an integer after " + t.text}
+			}
+			text += ":" + n.text
+		}
+		if !IsDateLiteral(text) {
+			return Literal{}, &Error{Pos: t.pos, Msg: "unknown date literal " + text}
+		}
+		return Literal{Kind: DateLiteral, Text: text}, nil
+	}
+	return Literal{}, &Error{Pos: t.pos, Msg: fmt.Sprintf("expecting a value, unexpected token: %s", t)}
+}
+
+func (p *parser) parseOrderBy() (items []OrderItem, err error) {
+	for {
+		item := OrderItem{}
+		if item.Field, err = p.parseField(); err != nil {
+			return
+		}
+		if p.accept("DESC") {
+			item.Desc = true
+		} else {
+			p.accept("ASC")
+		}
+		if p.accept("NULLS") {
+			switch t := p.next(); {
+			case t.is("FIRST"), t.is("LAST"):
+				item.Nulls = strings.ToUpper(t.text)
+			default:
+				return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("expecting FIRST or LAST, unexpected token: %s", t)}
+			}
+		}
+		items = append(items, item)
+		if !p.accept(",") {
+			return
+		}
+	}
+}
+
+func (p *parser) parseInt() (*int, error) {
+	t := p.next()
+	n, err := strconv.Atoi(t.text)
+	if t.kind != tokNumber || err != nil || n < 0 {
+		return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("expecting a non-negative integer, unexpected token: %s", t)}
+	}
+	return &n, nil
+}
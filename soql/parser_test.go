@@ -0,0 +1,88 @@
+package soql_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sidebiequ/gosf/soql"
+)
+
+func TestParseDateLiteral(t *testing.T) {
+	tests := []struct {
+		name string
+		q    string
+		want string
+		err  string
+	}{
+		{"fixed", "SELECT Id FROM Account WHERE CreatedDate = TODAY", "TODAY", ""},
+		{"fiscal", "SELECT Id FROM Account WHERE CloseDate = this_fiscal_quarter", "THIS_FISCAL_QUARTER", ""},
+		{"n form", "SELECT Id FROM Account WHERE CreatedDate > LAST_N_DAYS:30", "LAST_N_DAYS:30", ""},
+		{"ago", "SELECT Id FROM Account WHERE CreatedDate < N_FISCAL_YEARS_AGO:2", "N_FISCAL_YEARS_AGO:2", ""},
+		{"unknown", "SELECT Id FROM Account WHERE Name = Acme", "", "unknown date literal ACME"},
+		{"n form without n", "SELECT Id FROM Account WHERE CreatedDate > LAST_N_DAYS", "", "unknown date literal LAST_N_DAYS"},
+		{"fixed with n", "SELECT Id FROM Account WHERE CreatedDate > TODAY:3", "", "unknown date literal TODAY:3"},
+		{"decimal n", "SELECT Id FROM Account WHERE CreatedDate > LAST_N_DAYS:1.5", "", "expecting an integer"},
+		{"negative n", "SELECT Id FROM Account WHERE CreatedDate > LAST_N_DAYS:-1", "", "expecting an integer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := soql.Parse(tt.q)
+			if tt.err != "" {
+				var serr *soql.Error
+				if !errors.As(err, &serr) || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got %v, want *soql.Error of %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := q.Where.(*soql.Comparison)
+			if c.Value.Kind != soql.DateLiteral || c.Value.Text != tt.want {
+				t.Errorf("got %+v, want date literal %s", c.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintRoundTrip(t *testing.T) {
+	for _, q := range []string{
+		"SELECT Id FROM Account WHERE CreatedDate = TODAY",
+		"SELECT Id FROM Account WHERE CreatedDate > LAST_N_DAYS:30 AND CloseDate < NEXT_FISCAL_YEAR",
+		"SELECT Id FROM Opportunity WHERE CloseDate IN (THIS_QUARTER, N_QUARTERS_AGO:1)",
+		"SELECT Id FROM Account WHERE CreatedDate >= 2024-01-01 AND LastModifiedDate < 2024-01-01T00:00:00Z",
+	} {
+		parsed, err := soql.Parse(q)
+		if err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+		if got := parsed.String(); got != q {
+			t.Errorf("got  %s\nwant %s", got, q)
+		}
+		if _, err = soql.Parse(parsed.String()); err != nil {
+			t.Errorf("reparse %s: %v", parsed, err)
+		}
+	}
+}
+
+func TestValidateDateLiteral(t *testing.T) {
+	schema := soql.StaticSchema{"Account": {Fields: []string{"Id", "CreatedDate"}}}
+	q, err := soql.Parse("SELECT Id FROM Account WHERE CreatedDate > LAST_N_WEEKS:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = soql.Validate(q, schema); err != nil {
+		t.Errorf("got %v, want valid", err)
+	}
+
+	q.Where = &soql.Comparison{
+		Field: &soql.Field{Path: []string{"CreatedDate"}},
+		Op:    "=",
+		Value: soql.Literal{Kind: soql.DateLiteral, Text: "SOMEDAY"},
+	}
+	var verr *soql.ValidationError
+	if err = soql.Validate(q, schema); !errors.As(err, &verr) || verr.ErrorCode != "MALFORMED_QUERY" {
+		t.Errorf("got %v, want MALFORMED_QUERY", err)
+	}
+}
@@ -0,0 +1,171 @@
+package soql
+
+import (
+	"strconv"
+	"strings"
+)
+
+// String prints the query as SOQL.
+func (q *Query) String() string {
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	for i, item := range q.Select {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(item.String())
+	}
+	b.WriteString(" FROM " + q.From)
+	if q.Scope != "" {
+		b.WriteString(" USING SCOPE " + q.Scope)
+	}
+	if q.Where != nil {
+		b.WriteString(" WHERE " + q.Where.String())
+	}
+	if q.With != "" {
+		b.WriteString(" WITH " + q.With)
+	}
+	if len(q.GroupBy) > 0 {
+		b.WriteString(" GROUP BY ")
+		if q.GroupByFunc != "" {
+			b.WriteString(q.GroupByFunc + "(" + joinFields(q.GroupBy) + ")")
+		} else {
+			b.WriteString(joinFields(q.GroupBy))
+		}
+	}
+	if q.Having != nil {
+		b.WriteString(" HAVING " + q.Having.String())
+	}
+	if len(q.OrderBy) > 0 {
+		b.WriteString(" ORDER BY ")
+		for i, item := range q.OrderBy {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(item.String())
+		}
+	}
+	if q.Limit != nil {
+		b.WriteString(" LIMIT " + strconv.Itoa(*q.Limit))
+	}
+	if q.Offset != nil {
+		b.WriteString(" OFFSET " + strconv.Itoa(*q.Offset))
+	}
+	if q.For != "" {
+		b.WriteString(" FOR " + q.For)
+	}
+	return b.String()
+}
+
+func joinFields(fields []*Field) string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.Name()
+	}
+	return strings.Join(names, ", ")
+}
+
+func (f *Field) String() string {
+	return f.Name()
+}
+
+func (a *Aggregate) String() string {
+	if a.Alias != "" {
+		return a.Expr() + " " + a.Alias
+	}
+	return a.Expr()
+}
+
+func (t *TypeOf) String() string {
+	var b strings.Builder
+	b.WriteString("TYPEOF " + t.Field.Name())
+	for _, when := range t.Whens {
+		b.WriteString(" WHEN " + when.Sobject + " THEN " + joinFields(when.Fields))
+	}
+	if len(t.Else) > 0 {
+		b.WriteString(" ELSE " + joinFields(t.Else))
+	}
+	b.WriteString(" END")
+	return b.String()
+}
+
+func (s *Subquery) String() string {
+	return "(" + s.Query.String() + ")"
+}
+
+func (a *And) String() string {
+	return joinExprs(a.Exprs, " AND ")
+}
+
+func (o *Or) String() string {
+	return joinExprs(o.Exprs, " OR ")
+}
+
+func (n *Not) String() string {
+	return "NOT " + operand(n.Expr)
+}
+
+func (c *Comparison) String() string {
+	var b strings.Builder
+	if c.Aggregate != nil {
+		b.WriteString(c.Aggregate.Expr())
+	} else {
+		b.WriteString(c.Field.Name())
+	}
+	b.WriteString(" " + c.Op + " ")
+	if c.Op == "IN" || c.Op == "NOT IN" {
+		values := make([]string, len(c.Values))
+		for i, v := range c.Values {
+			values[i] = v.String()
+		}
+		b.WriteString("(" + strings.Join(values, ", ") + ")")
+	} else {
+		b.WriteString(c.Value.String())
+	}
+	return b.String()
+}
+
+func joinExprs(exprs []Expr, sep string) string {
+	operands := make([]string, len(exprs))
+	for i, expr := range exprs {
+		operands[i] = operand(expr)
+	}
+	return strings.Join(operands, sep)
+}
+
+// operand prints expr as an operand of AND, OR or NOT, conjunctions and
+// disjunctions are parenthesized.
+func operand(expr Expr) string {
+	switch expr.(type) {
+	case *And, *Or:
+		return "(" + expr.String() + ")"
+	default:
+		return expr.String()
+	}
+}
+
+// stringEscaper escapes string literals, \% and \_ are kept as Parse
+// keeps them escaped for LIKE patterns.
+var stringEscaper = strings.NewReplacer(
+	`\%`, `\%`, `\_`, `\_`,
+	`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "\b", `\b`, "\f", `\f`,
+)
+
+// String prints the literal as SOQL, strings are quoted and escaped.
+func (l Literal) String() string {
+	if l.Kind == String {
+		return "'" + stringEscaper.Replace(l.Text) + "'"
+	}
+	return l.Text
+}
+
+func (o OrderItem) String() string {
+	s := o.Field.Name()
+	if o.Desc {
+		s += " DESC"
+	}
+	if o.Nulls != "" {
+		s += " NULLS " + o.Nulls
+	}
+	return s
+}
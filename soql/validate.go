@@ -0,0 +1,273 @@
+package soql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Schema describes sobjects for Validate. Lookups are case insensitive.
+type Schema interface {
+	// HasSobject reports whether the sobject exists.
+	HasSobject(sobject string) (bool, error)
+	// HasField reports whether sobject has the field.
+	HasField(sobject, field string) (bool, error)
+	// Parent returns the sobject the parent relationship of sobject refers
+	// to, the first one if it's polymorphic.
+	Parent(sobject, relationship string) (string, bool, error)
+	// Child returns the sobject of the child relationship of sobject.
+	Child(sobject, relationship string) (string, bool, error)
+}
+
+// ValidationError is an invalid reference of a query, the messages are
+// like salesforce's.
+type ValidationError struct {
+	ErrorCode string
+	Msg       string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("soql: %s: %s", e.ErrorCode, e.Msg)
+}
+
+// Validate checks the sobjects, fields and relationships q refers to
+// against schema. All invalid references are reported, joined by
+// errors.Join; errors of schema are returned as they are.
+func Validate(q *Query, schema Schema) error {
+	v := &validator{schema: schema}
+	v.query(q, q.From, false)
+	return errors.Join(v.errs...)
+}
+
+type validator struct {
+	schema Schema
+	errs   []error
+	// failed is set when schema fails, the validation stops.
+	failed bool
+}
+
+func (v *validator) invalid(code, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ValidationError{ErrorCode: code, Msg: fmt.Sprintf(format, args...)})
+}
+
+// check records err of schema, returns false if the validation should stop.
+func (v *validator) check(err error) bool {
+	if err != nil && !v.failed {
+		v.errs = append(v.errs, err)
+		v.failed = true
+	}
+	return !v.failed
+}
+
+// query validates q on sobject, which is the child sobject for a subquery.
+func (v *validator) query(q *Query, sobject string, subquery bool) {
+	if !subquery {
+		ok, err := v.schema.HasSobject(sobject)
+		if !v.check(err) {
+			return
+		}
+		if !ok {
+			v.invalid("INVALID_TYPE", "sObject type '%s' is not supported.", sobject)
+			return
+		}
+	}
+
+	for _, item := range q.Select {
+		switch item := item.(type) {
+		case *Field:
+			v.field(sobject, q.From, item)
+		case *Aggregate:
+			if item.Field != nil {
+				v.field(sobject, q.From, item.Field)
+			}
+		case *TypeOf:
+			v.typeOf(sobject, q.From, item)
+		case *Subquery:
+			child, ok, err := v.schema.Child(sobject, item.Query.From)
+			if !v.check(err) {
+				return
+			}
+			if !ok {
+				v.invalid("INVALID_TYPE", "Didn't understand relationship '%s' in FROM part of query call.", item.Query.From)
+				continue
+			}
+			v.query(item.Query, child, true)
+		}
+	}
+	v.expr(sobject, q.From, q.Where)
+	for _, f := range q.GroupBy {
+		v.field(sobject, q.From, f)
+	}
+	v.expr(sobject, q.From, q.Having)
+	for _, item := range q.OrderBy {
+		v.field(sobject, q.From, item.Field)
+	}
+}
+
+func (v *validator) expr(sobject, from string, expr Expr) {
+	switch expr := expr.(type) {
+	case *And:
+		for _, sub := range expr.Exprs {
+			v.expr(sobject, from, sub)
+		}
+	case *Or:
+		for _, sub := range expr.Exprs {
+			v.expr(sobject, from, sub)
+		}
+	case *Not:
+		v.expr(sobject, from, expr.Expr)
+	case *Comparison:
+		if expr.Aggregate != nil && expr.Aggregate.Field != nil {
+			v.field(sobject, from, expr.Aggregate.Field)
+		} else if expr.Field != nil {
+			v.field(sobject, from, expr.Field)
+		}
+		v.literal(expr.Value)
+		for _, value := range expr.Values {
+			v.literal(value)
+		}
+	}
+}
+
+// literal checks the date literals of trees not built by Parse.
+func (v *validator) literal(l Literal) {
+	if l.Kind == DateLiteral && !IsDateLiteral(l.Text) {
+		v.invalid("MALFORMED_QUERY", "unknown date literal %s", l.Text)
+	}
+}
+
+// field validates the field path on sobject, following parent
+// relationships. from is the name in FROM, which can prefix the path.
+func (v *validator) field(sobject, from string, f *Field) {
+	path := f.Path
+	if len(path) > 1 && strings.EqualFold(path[0], from) {
+		path = path[1:]
+	}
+	for ; len(path) > 1; path = path[1:] {
+		parent, ok, err := v.schema.Parent(sobject, path[0])
+		if !v.check(err) {
+			return
+		}
+		if !ok {
+			v.invalid("INVALID_FIELD", "Didn't understand relationship '%s' in field path. If you are attempting to use a custom relationship, be sure to append the '__r' after the custom relationship name.", path[0])
+			return
+		}
+		sobject = parent
+	}
+
+	ok, err := v.schema.HasField(sobject, path[0])
+	if !v.check(err) {
+		return
+	}
+	if !ok {
+		v.invalid("INVALID_FIELD", "No such column '%s' on entity '%s'.", path[0], sobject)
+	}
+}
+
+// typeOf validates the fields of each WHEN on its sobject, the fields of
+// ELSE on the sobject the relationship refers to.
+func (v *validator) typeOf(sobject, from string, t *TypeOf) {
+	path := t.Field.Path
+	if len(path) > 1 && strings.EqualFold(path[0], from) {
+		path = path[1:]
+	}
+	parent := sobject
+	for _, relationship := range path {
+		var ok bool
+		var err error
+		parent, ok, err = v.schema.Parent(parent, relationship)
+		if !v.check(err) {
+			return
+		}
+		if !ok {
+			v.invalid("INVALID_FIELD", "Didn't understand relationship '%s' in field path.", relationship)
+			return
+		}
+	}
+
+	for _, when := range t.Whens {
+		ok, err := v.schema.HasSobject(when.Sobject)
+		if !v.check(err) {
+			return
+		}
+		if !ok {
+			v.invalid("INVALID_TYPE", "sObject type '%s' is not supported.", when.Sobject)
+			continue
+		}
+		for _, f := range when.Fields {
+			v.field(when.Sobject, "", f)
+		}
+	}
+	for _, f := range t.Else {
+		v.field(parent, "", f)
+	}
+}
+
+// StaticSchema is a Schema of fixed sobjects keyed by their names, for
+// validating queries offline.
+type StaticSchema map[string]*SobjectSchema
+
+// SobjectSchema describes a sobject of StaticSchema.
+type SobjectSchema struct {
+	Fields []string
+	// Parents maps parent relationship names to the sobjects they refer to.
+	Parents map[string]string
+	// Children maps child relationship names to the child sobjects.
+	Children map[string]string
+}
+
+func (s StaticSchema) sobject(name string) *SobjectSchema {
+	if sobject, ok := s[name]; ok {
+		return sobject
+	}
+	for key, sobject := range s {
+		if strings.EqualFold(key, name) {
+			return sobject
+		}
+	}
+	return nil
+}
+
+// HasSobject implements Schema.
+func (s StaticSchema) HasSobject(sobject string) (bool, error) {
+	return s.sobject(sobject) != nil, nil
+}
+
+// HasField implements Schema.
+func (s StaticSchema) HasField(sobject, field string) (bool, error) {
+	if o := s.sobject(sobject); o != nil {
+		for _, f := range o.Fields {
+			if strings.EqualFold(f, field) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// Parent implements Schema.
+func (s StaticSchema) Parent(sobject, relationship string) (string, bool, error) {
+	if o := s.sobject(sobject); o != nil {
+		parent, ok := lookupFold(o.Parents, relationship)
+		return parent, ok, nil
+	}
+	return "", false, nil
+}
+
+// Child implements Schema.
+func (s StaticSchema) Child(sobject, relationship string) (string, bool, error) {
+	if o := s.sobject(sobject); o != nil {
+		child, ok := lookupFold(o.Children, relationship)
+		return child, ok, nil
+	}
+	return "", false, nil
+}
+
+func lookupFold(m map[string]string, key string) (string, bool) {
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,199 @@
+package gosf
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+/************************************/
+/************ STRUCT TAGS ***********/
+/************************************/
+
+// maxRelationshipDepth is the max levels of parent relationships SOQL allows.
+const maxRelationshipDepth = 5
+
+// SobjectNamer is implemented by structs know their sobject name.
+type SobjectNamer interface {
+	SobjectName() string
+}
+
+var (
+	sobjectNamerType    = reflect.TypeOf((*SobjectNamer)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// SelectStruct defines the select fields by the `sf` tags of v's struct
+// type, v can be a struct, a pointer to struct or a nil pointer of it:
+//
+//	type Account struct {
+//		_        struct{}  `sf:"Account"`
+//		ID       string    `sf:"Id"`
+//		Name     string    `sf:"Name"`
+//		Owner    *User     `sf:"Owner"`    // Owner.Name, ... by User's tags
+//		Contacts []Contact `sf:"Contacts"` // (SELECT ... FROM Contacts)
+//		Internal string    `sf:"-"`
+//	}
+//
+// Fields without tag are not selected, except embedded structs whose
+// fields are selected as their own. Nested structs select parent
+// relationship fields and slices of structs select child relationship
+// subqueries. A relationship to a struct already on the way, like
+// User.Manager *User, selects the fields of it without its relationships,
+// so self-referential structs end there. If the sobject name is not set by
+// From(), it is inferred from the tag of a blank field like above or a
+// SobjectName() method.
+//
+// Query, QueryIter and the Parse methods decode records into the fields by
+// their `sf` tags, fields without them are decoded by encoding/json rules.
+func (op *OpQuery) SelectStruct(v interface{}) *OpQuery {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		op.err = fmt.Errorf("SelectStruct expects a struct, got %T", v)
+		return op
+	}
+
+	fields, subqueries, err := structFields(t, "", 0, true, map[reflect.Type]bool{t: true})
+	if err != nil {
+		op.err = err
+		return op
+	}
+	op.Select(fields...)
+	op.subqueries = append(op.subqueries, subqueries...)
+	if op.sobjectName == "" {
+		op.sobjectName = sobjectNameOf(t)
+	}
+	return op
+}
+
+// sobjectNameOf returns the sobject name of struct type t by SobjectName()
+// method or the `sf` tag of the blank field.
+func sobjectNameOf(t reflect.Type) string {
+	if t.Implements(sobjectNamerType) {
+		return reflect.Zero(t).Interface().(SobjectNamer).SobjectName()
+	}
+	if reflect.PointerTo(t).Implements(sobjectNamerType) {
+		return reflect.New(t).Interface().(SobjectNamer).SobjectName()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.Name == "_" {
+			if name := tagName(f); name != "" && name != "-" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// structFields returns the select fields with prefix and the child
+// relationship subqueries of struct type t. path holds the relationship
+// types from the query to t, the relationships of t are skipped if it's
+// nil.
+func structFields(t reflect.Type, prefix string, depth int, allowSubquery bool, path map[reflect.Type]bool) (fields []string, subqueries []*OpQuery, err error) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := tagName(f)
+		if f.Name == "_" || name == "-" {
+			continue
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if name == "" {
+			if f.Anonymous && ft != t && isRelationship(ft) {
+				embedded, subs, err := structFields(ft, prefix, depth, allowSubquery, path)
+				if err != nil {
+					return nil, nil, err
+				}
+				fields = append(fields, embedded...)
+				subqueries = append(subqueries, subs...)
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		switch {
+		case isRelationship(ft):
+			if path == nil {
+				continue
+			}
+			if depth+1 > maxRelationshipDepth {
+				return nil, nil, fmt.Errorf("relationship %s%s is deeper than %d levels", prefix, name, maxRelationshipDepth)
+			}
+			parentPath := path
+			if path[ft] {
+				// stop at a type already on the way, like User.Manager
+				parentPath = nil
+			} else {
+				path[ft] = true
+			}
+			parent, subs, err := structFields(ft, prefix+name+".", depth+1, false, parentPath)
+			if parentPath != nil {
+				delete(path, ft)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(subs) > 0 {
+				return nil, nil, fmt.Errorf("relationship %s%s can't have child relationships", prefix, name)
+			}
+			fields = append(fields, parent...)
+
+		case ft.Kind() == reflect.Slice && isRelationship(elemType(ft)):
+			if path == nil {
+				continue
+			}
+			if !allowSubquery {
+				return nil, nil, fmt.Errorf("child relationship %s%s is not allowed here, subqueries can only be in the top level query", prefix, name)
+			}
+			child := elemType(ft)
+			childFields, _, err := structFields(child, "", 0, false, map[reflect.Type]bool{child: true})
+			if err != nil {
+				return nil, nil, err
+			}
+			subqueries = append(subqueries, NewOpQuery(name).Select(childFields...))
+
+		default:
+			fields = append(fields, prefix+name)
+		}
+	}
+	return
+}
+
+// tagName returns the name in the `sf` tag of f.
+func tagName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("sf"), ",")
+	return name
+}
+
+// elemType returns the element type of slice t, pointers are dereferenced.
+func elemType(t reflect.Type) reflect.Type {
+	t = t.Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// isRelationship returns true if t is a struct of relationship, rather than
+// a value type like time.Time or one decodes itself.
+func isRelationship(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+	pt := reflect.PointerTo(t)
+	return !pt.Implements(jsonUnmarshalerType) && !pt.Implements(textUnmarshalerType)
+}
@@ -0,0 +1,127 @@
+package gosf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type tagUser struct {
+	_       struct{} `sf:"User"`
+	Name    string   `sf:"Name"`
+	Manager *tagUser `sf:"Manager"`
+}
+
+type tagContact struct {
+	Email string `sf:"Email"`
+}
+
+type tagAccount struct {
+	Name     string       `sf:"Name"`
+	Custom   string       `sf:"Custom__c" json:"custom"`
+	Owner    *tagUser     `sf:"Owner"`
+	Parent   *tagAccount  `sf:"Parent"`
+	Contacts []tagContact `sf:"Contacts"`
+	Note     string       `json:"Note__c"`
+	Skipped  string       `sf:"-"`
+}
+
+func (tagAccount) SobjectName() string { return "Account" }
+
+type tagDeep struct {
+	Name string    `sf:"Name"`
+	Next *tagDeep1 `sf:"Next__r"`
+}
+type tagDeep1 struct {
+	Next *tagDeep2 `sf:"Next__r"`
+}
+type tagDeep2 struct {
+	Next *tagDeep3 `sf:"Next__r"`
+}
+type tagDeep3 struct {
+	Next *tagDeep4 `sf:"Next__r"`
+}
+type tagDeep4 struct {
+	Next *tagDeep5 `sf:"Next__r"`
+}
+type tagDeep5 struct {
+	Next *tagDeep6 `sf:"Next__r"`
+}
+type tagDeep6 struct {
+	Name string `sf:"Name"`
+}
+
+func TestSelectStruct(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+		err  string
+	}{
+		{
+			"self reference",
+			&tagUser{},
+			"SELECT Name,Manager.Name FROM User",
+			"",
+		},
+		{
+			"relationships",
+			tagAccount{},
+			"SELECT Name,Custom__c,Owner.Name,Owner.Manager.Name,Parent.Name,Parent.Custom__c," +
+				"(SELECT Email FROM Contacts) FROM Account",
+			"",
+		},
+		{
+			"too deep",
+			tagDeep{},
+			"",
+			"deeper than 5 levels",
+		},
+		{
+			"not a struct",
+			"Account",
+			"",
+			"expects a struct",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := NewOpQuery("").SelectStruct(tt.v)
+			if tt.err != "" {
+				if op.err == nil || !strings.Contains(op.err.Error(), tt.err) {
+					t.Fatalf("got %v, want error of %s", op.err, tt.err)
+				}
+				return
+			}
+			if op.err != nil {
+				t.Fatal(op.err)
+			}
+			if got := op.makeQueryStatment(&recordLogger{}); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeRecordBySfTags(t *testing.T) {
+	raw := `{"attributes":{"type":"Account"},"Name":"Acme","Custom__c":"c","custom":"json","Note__c":"n","Skipped":"s",
+		"Owner":{"Name":"Boss","Manager":{"Name":"Big Boss"}},
+		"Parent":{"Name":"Holding"},
+		"Contacts":{"totalSize":1,"done":true,"records":[{"Email":"a@acme.com"}]}}`
+	var got tagAccount
+	if err := decodeRecord([]byte(raw), &got, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := tagAccount{
+		Name:     "Acme",
+		Custom:   "c",
+		Owner:    &tagUser{Name: "Boss", Manager: &tagUser{Name: "Big Boss"}},
+		Parent:   &tagAccount{Name: "Holding"},
+		Contacts: []tagContact{{Email: "a@acme.com"}},
+		Note:     "n",
+		Skipped:  "s",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+}